@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// apiPrefix — префикс версионированного REST API
+const apiPrefix = "/api/v1"
+
+// registerAPI регистрирует REST API событий /api/v1/users/{user}/events.
+// Старые маршруты /create_event и т.д. работают поверх того же Calendar для совместимости.
+func registerAPI(r *mux.Router, calendar *Calendar) {
+	api := r.PathPrefix(apiPrefix).Subrouter()
+	// Маршруты без {id} регистрируются первыми: иначе gorilla/mux теряет 405 для путей событий
+	api.HandleFunc("/freebusy", freeBusyHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/holidays/{holidays}/days/{date}", workingDayHandler).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/search", searchHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/changes", changesHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/trash", trashHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/batch", batchHandler(calendar)).Methods(http.MethodPost)
+	api.HandleFunc("/users/{user}/settings", getSettingsHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/settings", updateSettingsHandler(calendar)).Methods(http.MethodPut)
+	api.HandleFunc("/users/{user}/views/{view:day|week|month|year}", viewHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/agenda", agendaHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/events", listEventsHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/events", postEventHandler(calendar)).Methods(http.MethodPost)
+	api.HandleFunc("/users/{user}/calendars", listCalendarsHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/calendars", createCalendarHandler(calendar)).Methods(http.MethodPost)
+	api.HandleFunc("/users/{user}/calendars/{cid:[0-9]+}", getCalendarHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/calendars/{cid:[0-9]+}", updateCalendarHandler(calendar)).Methods(http.MethodPut)
+	api.HandleFunc("/users/{user}/calendars/{cid:[0-9]+}", deleteCalendarHandler(calendar)).Methods(http.MethodDelete)
+	api.HandleFunc("/users/{user}/calendars/{cid:[0-9]+}/shares/{grantee}", shareCalendarHandler(calendar)).Methods(http.MethodPut)
+	api.HandleFunc("/users/{user}/calendars/{cid:[0-9]+}/shares/{grantee}", unshareCalendarHandler(calendar)).Methods(http.MethodDelete)
+	api.HandleFunc("/users/{user}/events/{id:[0-9]+}/rsvp", rsvpHandler(calendar)).Methods(http.MethodPut)
+	api.HandleFunc("/users/{user}/events/{id:[0-9]+}/restore", restoreEventHandler(calendar)).Methods(http.MethodPost)
+	api.HandleFunc("/users/{user}/events/{id:[0-9]+}", getEventHandler(calendar)).Methods(http.MethodGet)
+	api.HandleFunc("/users/{user}/events/{id:[0-9]+}", putEventHandler(calendar)).Methods(http.MethodPut)
+	api.HandleFunc("/users/{user}/events/{id:[0-9]+}", patchEventHandler(calendar)).Methods(http.MethodPatch)
+	api.HandleFunc("/users/{user}/events/{id:[0-9]+}", deleteAPIEventHandler(calendar)).Methods(http.MethodDelete)
+}
+
+// writeAPIError переводит ошибку календаря в HTTP-статус:
+// 404 — события или календаря нет или он чужой, 403 — чужой пользователь в пути, нет права записи
+// или исчерпан лимит событий, 409 — конфликт UID или пересечение по времени, 412 — версия не совпала с If-Match,
+// 422 — перенос к другому владельцу, неверный доступ, неверные настройки или слишком длинное описание
+func writeAPIError(w http.ResponseWriter, err error) {
+	var overlap *OverlapError
+	if errors.As(err, &overlap) {
+		writeOverlapError(w, overlap)
+		return
+	}
+	status, err := apiErrorStatus(err)
+	writeErrorResponse(w, status, err.Error())
+}
+
+// apiErrorStatus возвращает HTTP-статус ошибки календаря и ошибку, которую можно показать клиенту
+func apiErrorStatus(err error) (int, error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrEventNotFound), errors.Is(err, ErrForbidden):
+		// Чужие события неотличимы от несуществующих
+		status, err = http.StatusNotFound, ErrEventNotFound
+	case errors.Is(err, ErrCalendarNotFound), errors.Is(err, ErrCalendarNotShared):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrUserMismatch), errors.Is(err, ErrReadOnly), errors.Is(err, ErrNotCalendarOwner), errors.Is(err, ErrNotAttendee), errors.Is(err, ErrEventLimit):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrCrossOwnerMove), errors.Is(err, ErrInvalidShare), errors.Is(err, ErrInvalidSettings), errors.Is(err, ErrDescriptionTooLong):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, ErrDuplicateUID):
+		status = http.StatusConflict
+	case errors.Is(err, ErrVersionMismatch):
+		status = http.StatusPreconditionFailed
+	case errors.As(err, new(*OverlapError)):
+		status = http.StatusConflict
+	}
+	return status, err
+}
+
+// writeOverlapError отвечает 409 со списком событий, с которыми пересекается сохраняемое
+func writeOverlapError(w http.ResponseWriter, err *OverlapError) {
+	writeResponse(w, http.StatusConflict, Response{Error: err.Error(), Data: err.Conflicts})
+}
+
+// requestWriteOptions читает параметр reject_overlap=true, запрещающий пересечения по времени,
+// и ожидаемые версии события из заголовка If-Match
+func requestWriteOptions(w http.ResponseWriter, r *http.Request) (WriteOptions, bool) {
+	opts := WriteOptions{IfVersion: parseIfMatch(r.Header.Get("If-Match"))}
+	if value := r.URL.Query().Get("reject_overlap"); value != "" {
+		reject, err := strconv.ParseBool(value)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "reject_overlap must be true or false")
+			return opts, false
+		}
+		opts.RejectOverlap = reject
+	}
+	return opts, true
+}
+
+// apiUser возвращает пользователя из пути, сверяя его с токеном
+func apiUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, err := requestUser(r, mux.Vars(r)["user"])
+	if err != nil {
+		writeAPIError(w, err)
+		return "", false
+	}
+	return userID, true
+}
+
+// apiEventID возвращает ID события из пути
+func apiEventID(r *http.Request) int {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	return id
+}
+
+func eventLocation(userID string, id int) string {
+	return fmt.Sprintf("%s/users/%s/events/%d", apiPrefix, userID, id)
+}
+
+// decodeEventBody разбирает событие из тела запроса; при ошибке отвечает 400
+func decodeEventBody(w http.ResponseWriter, r *http.Request) (Event, bool) {
+	var event Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		writeBodyError(w, err, http.StatusBadRequest, "Invalid JSON format")
+		return event, false
+	}
+	return event, true
+}
+
+// listEventsHandler возвращает события пользователя страницами (limit, cursor) с фильтрами q и tags.
+// С параметрами from и to (YYYY-MM-DD включительно или моменты RFC 3339) возвращаются экземпляры этого периода
+// по возрастанию начала, повторяющиеся события раскрываются.
+func listEventsHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+
+		opts, err := parseListOptions(r, defaultPageLimit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		fromParam, toParam := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		if fromParam == "" && toParam == "" {
+			writeEventPage(w, r, "Events", calendar.GetUserEvents(userID, time.Time{}, time.Time{}), opts, idKey)
+			return
+		}
+		if fromParam == "" || toParam == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "from and to must be set together")
+			return
+		}
+
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		from, errFrom := parseRangeBound(fromParam, loc, false)
+		to, errTo := parseRangeBound(toParam, loc, true)
+		if errFrom != nil || errTo != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid range. Use YYYY-MM-DD or RFC 3339")
+			return
+		}
+		if !to.After(from) {
+			writeErrorResponse(w, http.StatusBadRequest, "to must not be before from")
+			return
+		}
+
+		events, err := calendar.GetEventsInRange(userID, from, to)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeEventPage(w, r, "Events", events, opts, startKey(loc))
+	}
+}
+
+func getEventHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		event, err := calendar.GetEvent(userID, apiEventID(r))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		etag := versionETag(event.Version)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Event", Data: event})
+	}
+}
+
+func postEventHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		opts, ok := requestWriteOptions(w, r)
+		if !ok {
+			return
+		}
+		event, ok := decodeEventBody(w, r)
+		if !ok {
+			return
+		}
+		if event.UserID != "" && event.UserID != userID {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "user_id must match the path")
+			return
+		}
+		event.UserID = userID
+		// ID назначает сервер
+		event.ID = 0
+
+		if err := ValidateEvent(event); err != nil {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		id, err := calendar.CreateEventWithOptions(event, opts)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		created, _ := calendar.GetEvent(userID, id)
+		w.Header().Set("Location", eventLocation(userID, id))
+		w.Header().Set("ETag", versionETag(created.Version))
+		writeResponse(w, http.StatusCreated, Response{Message: "Event created", Data: created})
+	}
+}
+
+// putEventHandler полностью заменяет событие
+func putEventHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		opts, ok := requestWriteOptions(w, r)
+		if !ok {
+			return
+		}
+		event, ok := decodeEventBody(w, r)
+		if !ok {
+			return
+		}
+		replaceEvent(w, calendar, userID, apiEventID(r), event, opts)
+	}
+}
+
+// patchEventHandler частично изменяет событие по правилам JSON Merge Patch (RFC 7396):
+// переданные поля заменяются, null удаляет поле. Патч применяется к прочитанной версии события,
+// поэтому сохраняется, только если событие с тех пор не изменилось.
+func patchEventHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		opts, ok := requestWriteOptions(w, r)
+		if !ok {
+			return
+		}
+		id := apiEventID(r)
+		existing, err := calendar.GetEvent(userID, id)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		if !opts.versionMatches(existing.Version) {
+			writeAPIError(w, ErrVersionMismatch)
+			return
+		}
+		opts.IfVersion = []int{existing.Version}
+
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, err, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		var patchDoc map[string]interface{}
+		if err := json.Unmarshal(patch, &patchDoc); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+
+		original, _ := json.Marshal(existing)
+		var doc map[string]interface{}
+		json.Unmarshal(original, &doc)
+		mergePatch(doc, patchDoc)
+		// Новое время начала превращает событие на весь день в событие со временем
+		if _, hasStart := patchDoc["start"]; hasStart {
+			if _, hasAllDay := patchDoc["all_day"]; !hasAllDay {
+				delete(doc, "all_day")
+			}
+		}
+		merged, _ := json.Marshal(doc)
+
+		var event Event
+		if err := json.Unmarshal(merged, &event); err != nil {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "Invalid field value: "+err.Error())
+			return
+		}
+		replaceEvent(w, calendar, userID, id, event, opts)
+	}
+}
+
+// mergePatch применяет JSON Merge Patch к документу doc
+func mergePatch(doc, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		if patchObj, ok := value.(map[string]interface{}); ok {
+			if docObj, ok := doc[key].(map[string]interface{}); ok {
+				mergePatch(docObj, patchObj)
+				continue
+			}
+		}
+		doc[key] = value
+	}
+}
+
+// replaceEvent сохраняет новое содержимое события id пользователя userID
+func replaceEvent(w http.ResponseWriter, calendar *Calendar, userID string, id int, event Event, opts WriteOptions) {
+	if event.ID != 0 && event.ID != id {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, "id must match the path")
+		return
+	}
+	// Сначала проверяем существование, чтобы отвечать 404, а не 422, на несуществующее событие
+	existing, err := calendar.GetEvent(userID, id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	// В событии из общего календаря user_id — его владелец
+	if event.UserID != "" && event.UserID != userID && event.UserID != existing.UserID {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, "user_id must match the path")
+		return
+	}
+	event.ID = id
+	event.UserID = userID
+
+	if err := ValidateEvent(event); err != nil {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err := calendar.UpdateEventWithOptions(event, opts); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	updated, _ := calendar.GetEvent(userID, id)
+	w.Header().Set("ETag", versionETag(updated.Version))
+	writeResponse(w, http.StatusOK, Response{Message: "Event updated", Data: updated})
+}
+
+func deleteAPIEventHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		opts := WriteOptions{IfVersion: parseIfMatch(r.Header.Get("If-Match"))}
+		if err := calendar.DeleteUserEventWithOptions(userID, apiEventID(r), opts); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
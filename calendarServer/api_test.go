@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// apiEvent разбирает событие из ответа REST API
+func apiEvent(t *testing.T, body string) Event {
+	t.Helper()
+	var resp struct {
+		Data Event `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", body, err)
+	}
+	return resp.Data
+}
+
+func TestRESTEventLifecycle(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	base := "/api/v1/users/user1/events"
+
+	rec := serve(router, http.MethodPost, base, `{"title":"Review","description":"Q1","date":"2024-03-10"}`, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	created := apiEvent(t, rec.Body.String())
+	location := rec.Header().Get("Location")
+	if created.UserID != "user1" || location != base+"/1" {
+		t.Fatalf("Expected event of user1 at %s/1, got %+v at %s", base, created, location)
+	}
+
+	rec = serve(router, http.MethodGet, location, "", "")
+	if rec.Code != http.StatusOK || apiEvent(t, rec.Body.String()).Title != "Review" {
+		t.Fatalf("Expected event on GET, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(router, http.MethodPatch, location, `{"title":"Quarterly review","description":null}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on PATCH, got %d: %s", rec.Code, rec.Body)
+	}
+	patched := apiEvent(t, rec.Body.String())
+	if patched.Title != "Quarterly review" || patched.Description != "" || patched.Date != "2024-03-10" {
+		t.Errorf("Expected merged event, got %+v", patched)
+	}
+
+	rec = serve(router, http.MethodPatch, location, `{"start":"2024-03-11T10:00:00Z"}`, "")
+	if rec.Code != http.StatusOK || apiEvent(t, rec.Body.String()).AllDay {
+		t.Errorf("Expected PATCH with start to make a timed event, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(router, http.MethodPut, location, `{"title":"Replaced","date":"2024-03-12"}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on PUT, got %d: %s", rec.Code, rec.Body)
+	}
+	if replaced := apiEvent(t, rec.Body.String()); replaced.Title != "Replaced" || !replaced.AllDay {
+		t.Errorf("Expected replaced all-day event, got %+v", replaced)
+	}
+
+	rec = serve(router, http.MethodGet, base+"?from=2024-03-01&to=2024-03-31", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Replaced") {
+		t.Errorf("Expected event in range listing, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(router, http.MethodDelete, location, "", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on DELETE, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, location, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after DELETE, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodDelete, location, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 on repeated DELETE, got %d", rec.Code)
+	}
+}
+
+func TestRESTStatusCodes(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	calendar.CreateEvent(Event{UserID: "user1", UID: "meeting@example.com", Title: "Meeting", Date: "2024-03-10"})
+	foreignID, _ := calendar.CreateEvent(Event{UserID: "user2", Title: "Foreign", Date: "2024-03-10"})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"malformed JSON", http.MethodPost, "/api/v1/users/user1/events", `{"title":`, http.StatusBadRequest},
+		{"missing title", http.MethodPost, "/api/v1/users/user1/events", `{"date":"2024-03-10"}`, http.StatusUnprocessableEntity},
+		{"invalid date", http.MethodPost, "/api/v1/users/user1/events", `{"title":"x","date":"10.03.2024"}`, http.StatusUnprocessableEntity},
+		{"user mismatch in body", http.MethodPost, "/api/v1/users/user1/events", `{"user_id":"user2","title":"x","date":"2024-03-10"}`, http.StatusUnprocessableEntity},
+		{"duplicate UID", http.MethodPost, "/api/v1/users/user1/events", `{"uid":"meeting@example.com","title":"x","date":"2024-03-10"}`, http.StatusConflict},
+		{"foreign event", http.MethodGet, "/api/v1/users/user1/events/" + strconv.Itoa(foreignID), "", http.StatusNotFound},
+		{"foreign delete", http.MethodDelete, "/api/v1/users/user1/events/" + strconv.Itoa(foreignID), "", http.StatusNotFound},
+		{"missing event PUT", http.MethodPut, "/api/v1/users/user1/events/999", `{"title":"x","date":"2024-03-10"}`, http.StatusNotFound},
+		{"id mismatch", http.MethodPut, "/api/v1/users/user1/events/1", `{"id":2,"title":"x","date":"2024-03-10"}`, http.StatusUnprocessableEntity},
+		{"invalid PATCH", http.MethodPatch, "/api/v1/users/user1/events/1", `{"title":""}`, http.StatusUnprocessableEntity},
+		{"half range", http.MethodGet, "/api/v1/users/user1/events?from=2024-03-01", "", http.StatusBadRequest},
+		{"method not allowed", http.MethodPost, "/api/v1/users/user1/events/1", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(router, tt.method, tt.target, tt.body, ""); rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// Пользователь в пути должен совпадать с пользователем токена
+	authRouter := newRouter(calendar, NewAuthenticator(map[string]string{"key": "user1"}, nil), nil)
+	if rec := serve(authRouter, http.MethodGet, "/api/v1/users/user2/events", "", "key"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for another user's path, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnauthorized — запрос без действительного токена
+	ErrUnauthorized = errors.New("authentication required")
+	// ErrUserMismatch — user_id в запросе не совпадает с пользователем из токена
+	ErrUserMismatch = errors.New("user_id does not match the authenticated user")
+)
+
+// jwtLeeway — допустимое расхождение часов при проверке exp и nbf
+const jwtLeeway = 30 * time.Second
+
+// Authenticator определяет пользователя запроса по API-ключу или JWT (HS256), подписанному локальным ключом.
+// Без ключей и секрета аутентификация выключена, и пользователь берется из параметров запроса, как раньше.
+type Authenticator struct {
+	apiKeys   map[string]string // ключ -> пользователь
+	jwtSecret []byte
+	now       func() time.Time
+}
+
+// NewAuthenticator создает аутентификатор с API-ключами и секретом для JWT
+func NewAuthenticator(apiKeys map[string]string, jwtSecret []byte) *Authenticator {
+	return &Authenticator{apiKeys: apiKeys, jwtSecret: jwtSecret, now: time.Now}
+}
+
+// ParseAPIKeys разбирает список API-ключей вида "ключ1:user1,ключ2:user2"
+func ParseAPIKeys(s string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, user, ok := strings.Cut(item, ":")
+		if !ok || key == "" || user == "" {
+			return nil, fmt.Errorf("invalid API key entry %q, expected key:user", item)
+		}
+		keys[key] = user
+	}
+	return keys, nil
+}
+
+// Enabled сообщает, включена ли аутентификация
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.apiKeys) > 0 || len(a.jwtSecret) > 0)
+}
+
+// Authenticate возвращает пользователя по заголовку Authorization (Bearer или Basic с токеном вместо пароля)
+// или X-API-Key. Basic нужен клиентам CalDAV, которые не умеют передавать Bearer.
+func (a *Authenticator) Authenticate(r *http.Request) (string, error) {
+	token := r.Header.Get("X-API-Key")
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, value, _ := strings.Cut(header, " ")
+		switch {
+		case strings.EqualFold(scheme, "Bearer"):
+			token = strings.TrimSpace(value)
+		case strings.EqualFold(scheme, "Basic"):
+			_, password, ok := r.BasicAuth()
+			if !ok {
+				return "", ErrUnauthorized
+			}
+			token = password
+		default:
+			return "", ErrUnauthorized
+		}
+	}
+	return a.authenticateToken(token)
+}
+
+// authenticateToken возвращает пользователя по API-ключу или JWT
+func (a *Authenticator) authenticateToken(token string) (string, error) {
+	if token == "" {
+		return "", ErrUnauthorized
+	}
+	if strings.Count(token, ".") == 2 && len(a.jwtSecret) > 0 {
+		return a.verifyJWT(token)
+	}
+	for key, user := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return user, nil
+		}
+	}
+	return "", ErrUnauthorized
+}
+
+// jwtHeader — заголовок JWT; поддерживается только HS256
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// jwtClaims — используемые поля JWT: пользователь в sub и срок действия
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// verifyJWT проверяет подпись и срок действия JWT и возвращает sub
+func (a *Authenticator) verifyJWT(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrUnauthorized
+	}
+	var header jwtHeader
+	// Алгоритм задан сервером: токены с alg=none или асимметричными алгоритмами отвергаются
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "HS256" {
+		return "", ErrUnauthorized
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrUnauthorized
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", ErrUnauthorized
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrUnauthorized
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return "", ErrUnauthorized
+	}
+	now := a.now()
+	if claims.ExpiresAt != 0 && now.After(time.Unix(claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return "", fmt.Errorf("%w: token expired", ErrUnauthorized)
+	}
+	if claims.NotBefore != 0 && now.Add(jwtLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return "", fmt.Errorf("%w: token not valid yet", ErrUnauthorized)
+	}
+	return claims.Subject, nil
+}
+
+// SignJWT выпускает JWT (HS256) для пользователя userID со сроком действия ttl (0 — бессрочный)
+func SignJWT(secret []byte, userID string, ttl time.Duration, now time.Time) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims := jwtClaims{Subject: userID, IssuedAt: now.Unix()}
+	if ttl > 0 {
+		claims.ExpiresAt = now.Add(ttl).Unix()
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	requestInfoContextKey
+)
+
+// withUser сохраняет аутентифицированного пользователя в контексте запроса
+func withUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userContextKey, userID)
+}
+
+// userFromContext возвращает аутентифицированного пользователя, если аутентификация включена
+func userFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userContextKey).(string)
+	return userID, ok
+}
+
+// publicPaths — служебные маршруты, доступные без токена: пробы оркестратора, сбор метрик и описание API
+var publicPaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true, "/openapi.json": true}
+
+// authMiddleware отклоняет запросы без действительного токена и кладет пользователя в контекст.
+// При выключенной аутентификации запросы проходят без изменений.
+func authMiddleware(auth *Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !auth.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userID, err := auth.Authenticate(r)
+			if err != nil {
+				w.Header().Add("WWW-Authenticate", `Bearer realm="calendarServer"`)
+				w.Header().Add("WWW-Authenticate", `Basic realm="calendarServer"`)
+				writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			setLogUser(r.Context(), userID)
+			next.ServeHTTP(w, r.WithContext(withUser(r.Context(), userID)))
+		})
+	}
+}
+
+// requestUser возвращает пользователя, от имени которого выполняется запрос.
+// При включенной аутентификации это пользователь из токена, а claimed (user_id из запроса)
+// допускается только пустым или совпадающим с ним. Иначе используется claimed.
+func requestUser(r *http.Request, claimed string) (string, error) {
+	userID, ok := userFromContext(r.Context())
+	if !ok {
+		return claimed, nil
+	}
+	if claimed != "" && claimed != userID {
+		return "", ErrUserMismatch
+	}
+	return userID, nil
+}
+
+// errorStatus возвращает HTTP-статус для ошибок доступа, ограничений и несовпадения версии, а для остальных — fallback
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrUserMismatch), errors.Is(err, ErrReadOnly), errors.Is(err, ErrEventLimit):
+		return http.StatusForbidden
+	case errors.Is(err, ErrDescriptionTooLong):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	}
+	return fallback
+}
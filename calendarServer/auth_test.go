@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret")
+
+func TestAuthenticateJWT(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	auth := NewAuthenticator(nil, testSecret)
+	auth.now = func() time.Time { return now }
+
+	valid, _ := SignJWT(testSecret, "user1", time.Hour, now)
+	expired, _ := SignJWT(testSecret, "user1", time.Hour, now.Add(-2*time.Hour))
+	foreign, _ := SignJWT([]byte("other-secret"), "user1", time.Hour, now)
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`)) + "." + parts[2]
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+
+	tests := []struct {
+		name     string
+		token    string
+		wantUser string
+	}{
+		{name: "valid", token: valid, wantUser: "user1"},
+		{name: "expired", token: expired},
+		{name: "wrong key", token: foreign},
+		{name: "tampered payload", token: tampered},
+		{name: "alg none", token: none},
+		{name: "garbage", token: "a.b.c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			user, err := auth.Authenticate(req)
+			if tt.wantUser == "" {
+				if !errors.Is(err, ErrUnauthorized) {
+					t.Errorf("Expected ErrUnauthorized, got user %q, err %v", user, err)
+				}
+				return
+			}
+			if err != nil || user != tt.wantUser {
+				t.Errorf("Expected user %q, got %q, err %v", tt.wantUser, user, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	keys, err := ParseAPIKeys("key-1:user1, key-2:user2")
+	if err != nil {
+		t.Fatalf("Failed to parse keys: %v", err)
+	}
+	auth := NewAuthenticator(keys, nil)
+
+	check := func(setup func(*http.Request), wantUser string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		setup(req)
+		user, err := auth.Authenticate(req)
+		if wantUser == "" && err == nil {
+			t.Errorf("Expected error, got user %q", user)
+		}
+		if wantUser != "" && (err != nil || user != wantUser) {
+			t.Errorf("Expected user %q, got %q, err %v", wantUser, user, err)
+		}
+	}
+	check(func(r *http.Request) { r.Header.Set("Authorization", "Bearer key-2") }, "user2")
+	check(func(r *http.Request) { r.Header.Set("X-API-Key", "key-1") }, "user1")
+	// Клиенты CalDAV передают ключ паролем Basic
+	check(func(r *http.Request) { r.SetBasicAuth("anyone", "key-1") }, "user1")
+	check(func(r *http.Request) { r.Header.Set("Authorization", "Bearer key-3") }, "")
+	check(func(r *http.Request) {}, "")
+
+	if _, err := ParseAPIKeys("no-user"); err == nil {
+		t.Error("Expected error for entry without user")
+	}
+	if NewAuthenticator(nil, nil).Enabled() {
+		t.Error("Expected authentication to be disabled without keys and secret")
+	}
+}
+
+func TestCalendarOwnership(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Private", Date: "2024-03-10"})
+
+	if err := calendar.UpdateEvent(Event{ID: id, UserID: "user2", Title: "Stolen", Date: "2024-03-10"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden on foreign update, got %v", err)
+	}
+	if err := calendar.DeleteUserEvent("user2", id); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden on foreign delete, got %v", err)
+	}
+	if err := calendar.DeleteUserEvent("user1", 999); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+	if err := calendar.DeleteUserEvent("user1", id); err != nil {
+		t.Errorf("Expected owner to delete the event, got %v", err)
+	}
+}
+
+// serve выполняет запрос к роутеру с токеном token (если он не пуст)
+func serve(h http.Handler, method, target, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthenticatedHandlers(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(map[string]string{"alice-key": "alice", "bob-key": "bob"}, nil), nil)
+
+	if rec := serve(router, http.MethodGet, "/events_for_day?date=2024-03-10&user_id=alice", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+
+	// user_id берется из токена, даже если в теле его нет
+	rec := serve(router, http.MethodPost, "/create_event", `{"title":"Alice event","date":"2024-03-10"}`, "alice-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on create, got %d: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		Data struct {
+			ID int `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if calendar.events[created.Data.ID].UserID != "alice" {
+		t.Fatalf("Expected event owned by alice, got %q", calendar.events[created.Data.ID].UserID)
+	}
+
+	if rec := serve(router, http.MethodPost, "/create_event", `{"user_id":"alice","title":"Forged","date":"2024-03-10"}`, "bob-key"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when creating for another user, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/events_for_day?date=2024-03-10&user_id=alice", "", "bob-key"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when reading another user's events, got %d", rec.Code)
+	}
+	rec = serve(router, http.MethodGet, "/events_for_day?date=2024-03-10", "", "bob-key")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Alice event") {
+		t.Errorf("Expected bob to see only his own events, got %d: %s", rec.Code, rec.Body)
+	}
+
+	update := `{"id":` + strconv.Itoa(created.Data.ID) + `,"title":"Hijacked","date":"2024-03-10"}`
+	if rec := serve(router, http.MethodPost, "/update_event", update, "bob-key"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 on foreign update, got %d", rec.Code)
+	}
+	deleteBody := `{"event_id":` + strconv.Itoa(created.Data.ID) + `}`
+	if rec := serve(router, http.MethodPost, "/delete_event", deleteBody, "bob-key"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 on foreign delete, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodPost, "/delete_event", deleteBody, "alice-key"); rec.Code != http.StatusOK {
+		t.Errorf("Expected owner delete to succeed, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(router, "PROPFIND", "/caldav/alice/calendar/", "", "bob-key"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 on another user's CalDAV collection, got %d", rec.Code)
+	}
+	rec = serve(router, "PROPFIND", "/caldav/", "", "bob-key")
+	if !strings.Contains(rec.Body.String(), "<d:current-user-principal><d:href>/caldav/bob/</d:href>") {
+		t.Errorf("Expected current-user-principal for bob, got:\n%s", rec.Body)
+	}
+}
+
+func TestUnauthenticatedModeKeepsUserParameter(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+
+	if rec := serve(router, http.MethodPost, "/create_event", `{"user_id":"user1","title":"Legacy","date":"2024-03-10"}`, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 without auth, got %d: %s", rec.Code, rec.Body)
+	}
+	rec := serve(router, http.MethodGet, "/events_for_day?date=2024-03-10&user_id=user1", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Legacy") {
+		t.Errorf("Expected legacy event, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Без user_id владельца не проверить, поэтому событие не удаляется
+	if rec := serve(router, http.MethodPost, "/delete_event", `{"event_id":1}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 on delete without user_id, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodPost, "/delete_event", `{"event_id":1,"user_id":"user2"}`, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 on foreign delete, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodPost, "/delete_event", `{"event_id":1,"user_id":"user1"}`, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected owner delete to succeed, got %d: %s", rec.Code, rec.Body)
+	}
+}
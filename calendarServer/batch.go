@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Операции пакетного изменения событий
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// maxBatchOperations — сколько операций можно передать в одном пакете
+const maxBatchOperations = 500
+
+// BatchOperation — одна операция пакета
+type BatchOperation struct {
+	Op            string `json:"op"`                       // create, update или delete
+	ID            int    `json:"id,omitempty"`             // событие для update и delete
+	Event         *Event `json:"event,omitempty"`          // новое содержимое события для create и update
+	Version       int    `json:"version,omitempty"`        // ожидаемая версия для update и delete; 0 — любая
+	RejectOverlap bool   `json:"reject_overlap,omitempty"` // отклонить create или update при пересечении по времени
+}
+
+// BatchError — ошибка операции пакета с номером Index (с нуля); пакет при этом не применен
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// batchWrite — примененная в памяти операция пакета: old заменено на event
+type batchWrite struct {
+	old, event *Event
+}
+
+// ApplyBatch выполняет операции от имени userID по порядку и атомарно: если одна из них не проходит
+// проверки или хранилище не сохранило результат, не применяется ни одна (ошибка *BatchError
+// указывает на операцию). Каждая операция видит результат предыдущих. Возвращает состояние события
+// после каждой операции; удаленное событие возвращается из корзины.
+func (c *Calendar) ApplyBatch(userID string, ops []BatchOperation) ([]Event, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	if len(ops) > maxBatchOperations {
+		return nil, fmt.Errorf("at most %d operations per batch", maxBatchOperations)
+	}
+
+	events := make([]Event, len(ops))
+	for i, op := range ops {
+		event, err := batchEvent(userID, op)
+		if err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+		events[i] = event
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	nextID := c.nextID
+	writes := make([]batchWrite, 0, len(ops))
+	rollback := func() {
+		for i := len(writes) - 1; i >= 0; i-- {
+			c.putEvent(writes[i].event, writes[i].old)
+		}
+		c.nextID = nextID
+	}
+
+	deleted := false
+	for i, op := range ops {
+		opts := WriteOptions{RejectOverlap: op.RejectOverlap}
+		if op.Version != 0 {
+			opts.IfVersion = []int{op.Version}
+		}
+		var old *Event
+		var event Event
+		var err error
+		switch op.Op {
+		case BatchCreate:
+			event, err = c.prepareCreate(events[i], opts)
+			if err == nil {
+				c.nextID++
+			}
+		case BatchUpdate:
+			old, event, err = c.prepareUpdate(events[i], opts)
+		case BatchDelete:
+			old, event, err = c.prepareDelete(userID, op.ID, opts, now)
+			deleted = true
+		}
+		if err != nil {
+			rollback()
+			return nil, &BatchError{Index: i, Err: err}
+		}
+		// Следующие операции должны видеть результат этой: применяем ее в памяти сразу
+		c.putEvent(old, &event)
+		writes = append(writes, batchWrite{old: old, event: &event})
+	}
+
+	if c.repo != nil {
+		stored := make([]Event, len(writes))
+		for i, write := range writes {
+			stored[i] = *write.event
+		}
+		if err := c.repo.SaveEvents(stored, c.nextID); err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to store events: %w", err)
+		}
+	}
+
+	results := make([]Event, len(writes))
+	for i, write := range writes {
+		c.publishChange(liveEvent(write.old), liveEvent(write.event))
+		results[i] = *write.event
+	}
+	if deleted {
+		if err := c.purgeTrash(now); err != nil {
+			slog.Warn("failed to purge deleted events", slog.Any("error", err))
+		}
+	}
+	return results, nil
+}
+
+// batchEvent возвращает нормализованное событие операции create или update; для delete — пустое
+func batchEvent(userID string, op BatchOperation) (Event, error) {
+	switch op.Op {
+	case BatchCreate, BatchUpdate:
+	case BatchDelete:
+		if op.ID == 0 {
+			return Event{}, errors.New("id is required")
+		}
+		return Event{}, nil
+	default:
+		return Event{}, fmt.Errorf("unknown operation %q", op.Op)
+	}
+	if op.Event == nil {
+		return Event{}, errors.New("event is required")
+	}
+	event := *op.Event
+	event.UserID = userID
+	event.ID = 0
+	if op.Op == BatchUpdate {
+		if op.ID == 0 {
+			return Event{}, errors.New("id is required")
+		}
+		if op.Event.ID != 0 && op.Event.ID != op.ID {
+			return Event{}, errors.New("event id must match the operation id")
+		}
+		event.ID = op.ID
+	}
+	if event.Title == "" {
+		return Event{}, errors.New("title is required")
+	}
+	return normalizeEvent(event)
+}
+
+// liveEvent возвращает event, если оно не в корзине, иначе nil
+func liveEvent(event *Event) *Event {
+	if event == nil || !event.DeletedAt.IsZero() {
+		return nil
+	}
+	return event
+}
+
+// BatchResult — результат операции пакета
+type BatchResult struct {
+	Op     string `json:"op"`
+	ID     int    `json:"id,omitempty"`
+	Status int    `json:"status"`          // HTTP-статус, который вернул бы отдельный запрос
+	Event  *Event `json:"event,omitempty"` // сохраненное событие create и update
+	Error  string `json:"error,omitempty"`
+}
+
+// batchHandler применяет пакет операций {"operations": [...]} атомарно. Если пакет применен, отвечает 200
+// с результатом каждой операции. Иначе отвечает статусом неудавшейся операции: у нее в результате
+// ошибка, остальные операции не применены и получают 424.
+func batchHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		var body struct {
+			Operations []BatchOperation `json:"operations"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeBodyError(w, err, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		ops := body.Operations
+		if len(ops) == 0 || len(ops) > maxBatchOperations {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, fmt.Sprintf("operations must contain 1 to %d items", maxBatchOperations))
+			return
+		}
+
+		// Неверные операции отклоняем до обращения к календарю, чтобы ответить 422, а не 500
+		for i, op := range ops {
+			if _, err := batchEvent(userID, op); err != nil {
+				writeBatchFailure(w, ops, i, http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+
+		events, err := calendar.ApplyBatch(userID, ops)
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) {
+			status, opErr := apiErrorStatus(batchErr.Err)
+			writeBatchFailure(w, ops, batchErr.Index, status, opErr)
+			return
+		}
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+		results := make([]BatchResult, len(ops))
+		for i, op := range ops {
+			event := events[i]
+			results[i] = BatchResult{Op: op.Op, ID: event.ID, Status: http.StatusOK, Event: &event}
+			switch op.Op {
+			case BatchCreate:
+				results[i].Status = http.StatusCreated
+			case BatchDelete:
+				results[i].Status = http.StatusNoContent
+				results[i].Event = nil
+			}
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Batch applied", Data: results})
+	}
+}
+
+// writeBatchFailure отвечает статусом status неудавшейся операции index; остальные операции не применены
+func writeBatchFailure(w http.ResponseWriter, ops []BatchOperation, index, status int, err error) {
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = BatchResult{Op: op.Op, ID: op.ID, Status: http.StatusFailedDependency, Error: "not applied"}
+	}
+	results[index].Status = status
+	results[index].Error = err.Error()
+	writeResponse(w, status, Response{Error: fmt.Sprintf("operation %d: %v", index, err), Data: results})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"WBTechL2/calendarServer/client"
+)
+
+func TestApplyBatch(t *testing.T) {
+	for kind, open := range openTestRepositories(t) {
+		t.Run(kind, func(t *testing.T) {
+			calendar, _ := NewCalendar(open())
+			keep, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Keep", Date: "2024-03-10"})
+			drop, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Drop", Date: "2024-03-11"})
+			start := calendar.changes.last()
+
+			// Последняя операция не проходит: не применяется ни одна
+			_, err := calendar.ApplyBatch("user1", []BatchOperation{
+				{Op: BatchCreate, Event: &Event{Title: "New", Date: "2024-03-12"}},
+				{Op: BatchUpdate, ID: keep, Event: &Event{Title: "Changed", Date: "2024-03-10"}},
+				{Op: BatchDelete, ID: drop},
+				{Op: BatchUpdate, ID: keep, Version: 1, Event: &Event{Title: "Stale", Date: "2024-03-10"}},
+			})
+			var batchErr *BatchError
+			if !errors.As(err, &batchErr) || batchErr.Index != 3 || !errors.Is(err, ErrVersionMismatch) {
+				t.Fatalf("Expected version mismatch in operation 3, got %v", err)
+			}
+			events := calendar.GetUserEvents("user1", time.Time{}, time.Time{})
+			if !equalStrings(orderedTitles(events), []string{"Keep", "Drop"}) || events[0].Version != 1 {
+				t.Errorf("Failed batch changed the calendar: %+v", events)
+			}
+			if calendar.nextID != drop+1 || len(calendar.DeletedEvents("user1")) != 0 {
+				t.Errorf("Failed batch left state behind: next ID %d, trash %v", calendar.nextID, calendar.DeletedEvents("user1"))
+			}
+			if changes, _, _, _, _ := calendar.changes.since("user1", start); len(changes) != 0 {
+				t.Errorf("Failed batch published changes: %v", changeTypes(changes))
+			}
+
+			results, err := calendar.ApplyBatch("user1", []BatchOperation{
+				{Op: BatchCreate, Event: &Event{Title: "New", Date: "2024-03-12"}},
+				{Op: BatchUpdate, ID: keep, Version: 1, Event: &Event{Title: "Changed", Date: "2024-03-10"}},
+				{Op: BatchUpdate, ID: keep, Version: 2, Event: &Event{Title: "Changed twice", Date: "2024-03-10"}},
+				{Op: BatchDelete, ID: drop},
+			})
+			if err != nil || len(results) != 4 || results[0].ID != drop+1 || results[2].Version != 3 || results[3].DeletedAt.IsZero() {
+				t.Fatalf("ApplyBatch: %+v, %v", results, err)
+			}
+			changes, _, _, _, _ := calendar.changes.since("user1", start)
+			if !equalStrings(changeTypes(changes), []string{ChangeCreated, ChangeUpdated, ChangeUpdated, ChangeDeleted}) {
+				t.Errorf("Unexpected changes of the batch: %v", changeTypes(changes))
+			}
+			calendar.Close()
+
+			reopened, _ := NewCalendar(open())
+			defer reopened.Close()
+			if titles := orderedTitles(reopened.GetUserEvents("user1", time.Time{}, time.Time{})); !equalStrings(titles, []string{"Changed twice", "New"}) {
+				t.Errorf("Unexpected events after reopening: %v", titles)
+			}
+			if deleted := reopened.DeletedEvents("user1"); len(deleted) != 1 || deleted[0].ID != drop {
+				t.Errorf("Expected the deleted event in the trash, got %+v", deleted)
+			}
+			if reopened.nextID != drop+2 {
+				t.Errorf("Expected next ID %d, got %d", drop+2, reopened.nextID)
+			}
+		})
+	}
+}
+
+func TestBatchAPI(t *testing.T) {
+	ctx := context.Background()
+	c, calendar := newTestClient(t)
+	other, _ := calendar.CreateEvent(Event{UserID: "user2", Title: "Private", Date: "2024-03-10"})
+
+	results, err := c.ApplyBatch(ctx, "user1", []client.BatchOperation{
+		{Op: client.BatchCreate, Event: &client.Event{Title: "Standup", Date: "2024-03-11"}},
+		{Op: client.BatchCreate, Event: &client.Event{Title: "Retro", Date: "2024-03-12"}},
+	})
+	if err != nil || len(results) != 2 || results[0].Status != http.StatusCreated || results[1].Event.Title != "Retro" {
+		t.Fatalf("ApplyBatch: %+v, %v", results, err)
+	}
+	standup, retro := results[0].ID, results[1].ID
+
+	// Чужое событие не видно: пакет отклоняется с 404, остальные операции не применяются
+	results, err = c.ApplyBatch(ctx, "user1", []client.BatchOperation{
+		{Op: client.BatchDelete, ID: standup},
+		{Op: client.BatchDelete, ID: other},
+	})
+	if !client.IsNotFound(err) || len(results) != 2 || results[0].Status != http.StatusFailedDependency || results[1].Error == "" {
+		t.Errorf("Expected 404 with per-operation results, got %+v, %v", results, err)
+	}
+	if _, err := c.GetEvent(ctx, "user1", standup); err != nil {
+		t.Errorf("Operation of a failed batch was applied: %v", err)
+	}
+
+	results, err = c.ApplyBatch(ctx, "user1", []client.BatchOperation{
+		{Op: client.BatchUpdate, ID: retro, Version: 1, Event: &client.Event{Title: "Retro", Date: "2024-03-13"}},
+		{Op: client.BatchUpdate, ID: standup, Event: &client.Event{Title: "Standup", Date: "2024-02-30"}},
+	})
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || len(results) != 2 || results[1].Status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid date, got %+v, %v", results, err)
+	}
+
+	results, err = c.ApplyBatch(ctx, "user1", []client.BatchOperation{
+		{Op: client.BatchUpdate, ID: retro, Version: 1, Event: &client.Event{Title: "Retro", Date: "2024-03-13"}},
+		{Op: client.BatchDelete, ID: standup, Version: 1},
+	})
+	if err != nil || results[0].Event.Version != 2 || results[1].Status != http.StatusNoContent || results[1].Event != nil {
+		t.Errorf("ApplyBatch: %+v, %v", results, err)
+	}
+}
@@ -0,0 +1,680 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Пространства имен XML, используемые CalDAV
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// davPrefixes — префиксы пространств имен в ответах multistatus
+var davPrefixes = map[string]string{
+	nsDAV:    "d",
+	nsCalDAV: "c",
+	nsCS:     "cs",
+}
+
+const (
+	// caldavCalendarName — имя коллекции с событиями пользователя
+	caldavCalendarName = "calendar"
+	// caldavMaxBody — максимальный размер тела запроса CalDAV
+	caldavMaxBody = 1 << 20
+	// caldavContentType — тип содержимого ресурса события
+	caldavContentType = "text/calendar; charset=utf-8; component=VEVENT"
+)
+
+var (
+	propResourceType     = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName      = xml.Name{Space: nsDAV, Local: "displayname"}
+	propGetETag          = xml.Name{Space: nsDAV, Local: "getetag"}
+	propGetContentType   = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propCurrentPrincipal = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL     = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propOwner            = xml.Name{Space: nsDAV, Local: "owner"}
+	propSupportedReports = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propCalendarHomeSet  = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propCalendarData     = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propSupportedComps   = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCTag             = xml.Name{Space: nsCS, Local: "getctag"}
+)
+
+// Границы диапазона для calendar-query без начала или конца time-range
+var (
+	caldavMinTime = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	caldavMaxTime = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// CalDAVHandler — минимальный сервер CalDAV (RFC 4791) поверх Calendar.
+// При включенной аутентификации доступны только коллекции пользователя из токена.
+//
+// Ресурсы:
+//
+//	{prefix}/                       — корень
+//	{prefix}/{user}/                — принципал и домашняя коллекция пользователя
+//	{prefix}/{user}/calendar/       — календарь пользователя (GET отдает его целиком в .ics)
+//	{prefix}/{user}/calendar/{uid}.ics — отдельное событие
+//
+// Поддерживаются PROPFIND, REPORT (calendar-query и calendar-multiget), GET, PUT и DELETE с ETag.
+type CalDAVHandler struct {
+	calendar *Calendar
+	prefix   string
+}
+
+// NewCalDAVHandler создает обработчик CalDAV, смонтированный по пути prefix (например, /caldav)
+func NewCalDAVHandler(calendar *Calendar, prefix string) *CalDAVHandler {
+	return &CalDAVHandler{calendar: calendar, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+func (h *CalDAVHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.Header().Set("DAV", "1, 3, calendar-access")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var parts []string
+	for _, part := range strings.Split(strings.TrimPrefix(r.URL.Path, h.prefix), "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	// При включенной аутентификации пользователь видит только свои коллекции
+	if authUser, ok := userFromContext(r.Context()); ok && len(parts) > 0 && parts[0] != authUser {
+		http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case len(parts) == 0:
+		h.serveRoot(w, r)
+	case len(parts) == 1:
+		h.servePrincipal(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == caldavCalendarName:
+		h.serveCollection(w, r, parts[0])
+	case len(parts) == 3 && parts[1] == caldavCalendarName && strings.HasSuffix(parts[2], ".ics"):
+		h.serveResource(w, r, parts[0], strings.TrimSuffix(parts[2], ".ics"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *CalDAVHandler) rootHref() string {
+	return h.prefix + "/"
+}
+
+func (h *CalDAVHandler) principalHref(userID string) string {
+	return h.prefix + "/" + url.PathEscape(userID) + "/"
+}
+
+func (h *CalDAVHandler) collectionHref(userID string) string {
+	return h.principalHref(userID) + caldavCalendarName + "/"
+}
+
+func (h *CalDAVHandler) resourceHref(userID string, event *Event) string {
+	return h.collectionHref(userID) + url.PathEscape(event.uid()) + ".ics"
+}
+
+func (h *CalDAVHandler) serveRoot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PROPFIND" {
+		w.Header().Set("Allow", "OPTIONS, PROPFIND")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := readPropfind(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	props := davProps{propResourceType: "<d:collection/>"}
+	// Клиенты находят свой календарь через current-user-principal корня
+	if authUser, ok := userFromContext(r.Context()); ok {
+		props[propCurrentPrincipal] = davHref(h.principalHref(authUser))
+	}
+	writeMultistatus(w, []davResponse{req.response(h.rootHref(), props)})
+}
+
+func (h *CalDAVHandler) servePrincipal(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != "PROPFIND" {
+		w.Header().Set("Allow", "OPTIONS, PROPFIND")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := readPropfind(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	self := davHref(h.principalHref(userID))
+	props := davProps{
+		propResourceType:     "<d:collection/><d:principal/>",
+		propDisplayName:      xmlText(userID),
+		propCurrentPrincipal: self,
+		propPrincipalURL:     self,
+		propCalendarHomeSet:  self,
+	}
+	responses := []davResponse{req.response(h.principalHref(userID), props)}
+	if davDepth(r) > 0 {
+		responses = append(responses, req.response(h.collectionHref(userID), h.collectionProps(userID)))
+	}
+	writeMultistatus(w, responses)
+}
+
+func (h *CalDAVHandler) collectionProps(userID string) davProps {
+	principal := davHref(h.principalHref(userID))
+	return davProps{
+		propResourceType:     "<d:collection/><c:calendar/>",
+		propDisplayName:      xmlText(userID),
+		propCurrentPrincipal: principal,
+		propOwner:            principal,
+		propSupportedComps:   `<c:comp name="VEVENT"/>`,
+		propSupportedReports: "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>",
+		propCTag: xmlText(h.ctag(userID)),
+	}
+}
+
+// ctag меняется при любом изменении событий пользователя (расширение CalendarServer getctag)
+func (h *CalDAVHandler) ctag(userID string) string {
+	hash := sha256.New()
+	for _, event := range h.calendar.GetUserEvents(userID, time.Time{}, time.Time{}) {
+		fmt.Fprintf(hash, "%d:%s;", event.ID, eventETag(&event))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+func (h *CalDAVHandler) resourceProps(event *Event, withData bool) davProps {
+	props := davProps{
+		propResourceType:   "",
+		propGetETag:        xmlText(eventETag(event)),
+		propGetContentType: xmlText(caldavContentType),
+	}
+	if withData {
+		var buf bytes.Buffer
+		WriteICalendar(&buf, []Event{*event}, time.Now())
+		props[propCalendarData] = xmlText(buf.String())
+	}
+	return props
+}
+
+func (h *CalDAVHandler) serveCollection(w http.ResponseWriter, r *http.Request, userID string) {
+	switch r.Method {
+	case "PROPFIND":
+		req, err := readPropfind(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		responses := []davResponse{req.response(h.collectionHref(userID), h.collectionProps(userID))}
+		if davDepth(r) > 0 {
+			for _, event := range h.calendar.GetUserEvents(userID, time.Time{}, time.Time{}) {
+				responses = append(responses, req.response(h.resourceHref(userID, &event), h.resourceProps(&event, req.wants(propCalendarData))))
+			}
+		}
+		writeMultistatus(w, responses)
+
+	case "REPORT":
+		h.serveReport(w, r, userID)
+
+	case http.MethodGet, http.MethodHead:
+		// Подписка на календарь целиком (webcal)
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("ETag", `"`+h.ctag(userID)+`"`)
+		if r.Method == http.MethodHead {
+			return
+		}
+		if err := WriteICalendar(w, h.calendar.GetUserEvents(userID, time.Time{}, time.Time{}), time.Now()); err != nil {
+			log.Printf("Failed to write iCalendar: %v", err)
+		}
+
+	default:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// caldavReport — тело REPORT calendar-query или calendar-multiget
+type caldavReport struct {
+	XMLName xml.Name
+	Prop    davPropNames  `xml:"DAV: prop"`
+	Hrefs   []string      `xml:"DAV: href"`
+	Filter  *caldavFilter `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type caldavFilter struct {
+	CompFilter caldavCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type caldavCompFilter struct {
+	Name        string             `xml:"name,attr"`
+	TimeRange   *caldavTimeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	CompFilters []caldavCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type caldavTimeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+func (h *CalDAVHandler) serveReport(w http.ResponseWriter, r *http.Request, userID string) {
+	var report caldavReport
+	if err := xml.NewDecoder(io.LimitReader(r.Body, caldavMaxBody)).Decode(&report); err != nil {
+		http.Error(w, "Invalid XML body", http.StatusBadRequest)
+		return
+	}
+	req := propfindRequest{names: report.Prop}
+	if len(req.names) == 0 {
+		req.all = true
+	}
+
+	var responses []davResponse
+	switch report.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		events, err := h.queryEvents(userID, report.Filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, event := range events {
+			responses = append(responses, req.response(h.resourceHref(userID, &event), h.resourceProps(&event, req.wants(propCalendarData))))
+		}
+
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		// Пути в href сравниваются в раскодированном виде
+		prefix := h.prefix + "/" + userID + "/" + caldavCalendarName + "/"
+		for _, href := range report.Hrefs {
+			u, err := url.Parse(strings.TrimSpace(href))
+			if err != nil || !strings.HasPrefix(u.Path, prefix) || !strings.HasSuffix(u.Path, ".ics") {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+			uid := strings.TrimSuffix(strings.TrimPrefix(u.Path, prefix), ".ics")
+			event, ok := h.calendar.getUserEventByUID(userID, uid)
+			if !ok {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+			responses = append(responses, req.response(h.resourceHref(userID, &event), h.resourceProps(&event, req.wants(propCalendarData))))
+		}
+
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported report %s", report.XMLName.Local), http.StatusForbidden)
+		return
+	}
+	writeMultistatus(w, responses)
+}
+
+// queryEvents отбирает события по фильтру calendar-query: VCALENDAR > VEVENT с необязательным time-range
+func (h *CalDAVHandler) queryEvents(userID string, filter *caldavFilter) ([]Event, error) {
+	if filter == nil || len(filter.CompFilter.CompFilters) == 0 {
+		return h.calendar.GetUserEvents(userID, time.Time{}, time.Time{}), nil
+	}
+	if filter.CompFilter.Name != "VCALENDAR" {
+		return nil, fmt.Errorf("unsupported comp-filter %q", filter.CompFilter.Name)
+	}
+
+	var events []Event
+	for _, comp := range filter.CompFilter.CompFilters {
+		if comp.Name != "VEVENT" {
+			continue
+		}
+		if comp.TimeRange == nil {
+			return h.calendar.GetUserEvents(userID, time.Time{}, time.Time{}), nil
+		}
+		from, to := caldavMinTime, caldavMaxTime
+		var err error
+		if comp.TimeRange.Start != "" {
+			if from, err = time.Parse(icalUTCLayout, comp.TimeRange.Start); err != nil {
+				return nil, fmt.Errorf("invalid time-range start %q", comp.TimeRange.Start)
+			}
+		}
+		if comp.TimeRange.End != "" {
+			if to, err = time.Parse(icalUTCLayout, comp.TimeRange.End); err != nil {
+				return nil, fmt.Errorf("invalid time-range end %q", comp.TimeRange.End)
+			}
+		}
+		events = append(events, h.calendar.GetUserEvents(userID, from, to)...)
+	}
+	return events, nil
+}
+
+func (h *CalDAVHandler) serveResource(w http.ResponseWriter, r *http.Request, userID, uid string) {
+	event, exists := h.calendar.getUserEventByUID(userID, uid)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		etag := eventETag(&event)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", caldavContentType)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		if err := WriteICalendar(w, []Event{event}, time.Now()); err != nil {
+			log.Printf("Failed to write iCalendar: %v", err)
+		}
+
+	case http.MethodPut:
+		h.putResource(w, r, userID, uid, event, exists)
+
+	case http.MethodDelete:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		var opts WriteOptions
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+			if !etagMatches(ifMatch, eventETag(&event)) {
+				http.Error(w, "ETag does not match", http.StatusPreconditionFailed)
+				return
+			}
+			// Удаляем именно проверенную версию, даже если событие успели изменить после проверки
+			opts.IfVersion = []int{event.Version}
+		}
+		if err := h.calendar.DeleteUserEventWithOptions(userID, event.ID, opts); err != nil {
+			http.Error(w, err.Error(), errorStatus(err, http.StatusServiceUnavailable))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case "PROPFIND":
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		req, err := readPropfind(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeMultistatus(w, []davResponse{req.response(h.resourceHref(userID, &event), h.resourceProps(&event, req.wants(propCalendarData)))})
+
+	default:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// putResource создает или заменяет событие из тела PUT.
+// Ресурс должен содержать ровно одно событие (с переопределениями экземпляров), UID которого совпадает с именем ресурса.
+func (h *CalDAVHandler) putResource(w http.ResponseWriter, r *http.Request, userID, uid string, existing Event, exists bool) {
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		http.Error(w, "Resource already exists", http.StatusPreconditionFailed)
+		return
+	}
+	var opts WriteOptions
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !exists || !etagMatches(ifMatch, eventETag(&existing)) {
+			http.Error(w, "ETag does not match", http.StatusPreconditionFailed)
+			return
+		}
+		// Заменяем именно проверенную версию, даже если событие успели изменить после проверки
+		opts.IfVersion = []int{existing.Version}
+	}
+
+	root, err := parseICalendar(io.LimitReader(r.Body, caldavMaxBody))
+	if err != nil {
+		http.Error(w, "Invalid iCalendar: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	decoded := decodeICalendar(root)
+	if len(decoded.skipped) > 0 {
+		http.Error(w, "Unsupported calendar data: "+decoded.skipped[0].Reason, http.StatusBadRequest)
+		return
+	}
+	if len(decoded.events) != 1 {
+		http.Error(w, "Exactly one event per resource is required", http.StatusBadRequest)
+		return
+	}
+
+	event := decoded.events[0]
+	if event.UID == "" {
+		event.UID = uid
+	}
+	if event.UID != uid {
+		http.Error(w, "Resource name must match the event UID", http.StatusBadRequest)
+		return
+	}
+	event.UserID = userID
+	if err := ValidateEvent(event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusCreated
+	if exists {
+		event.ID = existing.ID
+		err = h.calendar.UpdateEventWithOptions(event, opts)
+		status = http.StatusNoContent
+	} else {
+		event.ID, err = h.calendar.CreateEvent(event)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusServiceUnavailable))
+		return
+	}
+
+	if stored, ok := h.calendar.getUserEventByUID(userID, uid); ok {
+		w.Header().Set("ETag", eventETag(&stored))
+	}
+	w.WriteHeader(status)
+}
+
+// eventETag возвращает ETag события — тот же, что у REST API (versionETag), поэтому значение
+// If-Match, полученное через один интерфейс, подходит и для другого. Отметка доставленных
+// напоминаний не меняет версию, и клиенты не перечитывают событие после каждого напоминания.
+func eventETag(event *Event) string {
+	return versionETag(event.Version)
+}
+
+// etagMatches проверяет, совпадает ли etag с одним из значений заголовка If-Match или If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// davDepth возвращает значение заголовка Depth; infinity обрабатывается как 1
+func davDepth(r *http.Request) int {
+	if r.Header.Get("Depth") == "0" {
+		return 0
+	}
+	return 1
+}
+
+// davPropNames — список имен свойств из элемента DAV:prop
+type davPropNames []xml.Name
+
+func (p *davPropNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// propfindRequest — запрошенные свойства: все (allprop) или перечисленные
+type propfindRequest struct {
+	all   bool
+	names []xml.Name
+}
+
+func readPropfind(r *http.Request) (propfindRequest, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, caldavMaxBody))
+	if err != nil {
+		return propfindRequest{}, fmt.Errorf("failed to read request body")
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return propfindRequest{all: true}, nil
+	}
+
+	var doc struct {
+		XMLName  xml.Name     `xml:"DAV: propfind"`
+		AllProp  *struct{}    `xml:"DAV: allprop"`
+		PropName *struct{}    `xml:"DAV: propname"`
+		Prop     davPropNames `xml:"DAV: prop"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return propfindRequest{}, fmt.Errorf("invalid XML body")
+	}
+	if doc.AllProp != nil || doc.PropName != nil || len(doc.Prop) == 0 {
+		return propfindRequest{all: true}, nil
+	}
+	return propfindRequest{names: doc.Prop}, nil
+}
+
+func (req propfindRequest) wants(name xml.Name) bool {
+	for _, n := range req.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// response отбирает из props запрошенные свойства; отсутствующие попадают в propstat 404
+func (req propfindRequest) response(href string, props davProps) davResponse {
+	resp := davResponse{href: href}
+	if req.all {
+		for _, name := range props.names() {
+			// calendar-data отдается только по явному запросу
+			if name != propCalendarData {
+				resp.found = append(resp.found, davProp{name, props[name]})
+			}
+		}
+		return resp
+	}
+	for _, name := range req.names {
+		if value, ok := props[name]; ok {
+			resp.found = append(resp.found, davProp{name, value})
+		} else {
+			resp.missing = append(resp.missing, name)
+		}
+	}
+	return resp
+}
+
+// davProps — свойства ресурса; значение — готовое XML-содержимое элемента
+type davProps map[xml.Name]string
+
+func (p davProps) names() []xml.Name {
+	names := make([]xml.Name, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Space != names[j].Space {
+			return names[i].Space < names[j].Space
+		}
+		return names[i].Local < names[j].Local
+	})
+	return names
+}
+
+type davProp struct {
+	name  xml.Name
+	value string
+}
+
+// davResponse — элемент response в multistatus; status != 0 означает ответ без свойств (например, 404)
+type davResponse struct {
+	href    string
+	found   []davProp
+	missing []xml.Name
+	status  int
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []davResponse) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCS + `">`)
+	for _, resp := range responses {
+		b.WriteString("<d:response>" + davHref(resp.href))
+		if resp.status != 0 {
+			b.WriteString(davStatus(resp.status))
+		}
+		if len(resp.found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range resp.found {
+				open, close := davElement(p.name)
+				b.WriteString(open + p.value + close)
+			}
+			b.WriteString("</d:prop>" + davStatus(http.StatusOK) + "</d:propstat>")
+		}
+		if len(resp.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range resp.missing {
+				open, close := davElement(name)
+				b.WriteString(open + close)
+			}
+			b.WriteString("</d:prop>" + davStatus(http.StatusNotFound) + "</d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+// davElement возвращает открывающий и закрывающий теги свойства с учетом пространства имен
+func davElement(name xml.Name) (string, string) {
+	if prefix, ok := davPrefixes[name.Space]; ok {
+		return "<" + prefix + ":" + name.Local + ">", "</" + prefix + ":" + name.Local + ">"
+	}
+	return `<x:` + name.Local + ` xmlns:x="` + xmlText(name.Space) + `">`, "</x:" + name.Local + ">"
+}
+
+func davHref(href string) string {
+	return "<d:href>" + xmlText(href) + "</d:href>"
+}
+
+func davStatus(code int) string {
+	return fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status>", code, http.StatusText(code))
+}
+
+// xmlText экранирует текст для вставки в XML
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
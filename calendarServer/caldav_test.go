@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// davRequest выполняет запрос к обработчику CalDAV и возвращает ответ с прочитанным телом
+func davRequest(t *testing.T, h http.Handler, method, path, body string, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	resp := rec.Result()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+const caldavTestEvent = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:phone-1\r\n" +
+	"DTSTAMP:20240301T000000Z\r\n" +
+	"DTSTART:20240310T090000Z\r\n" +
+	"DTEND:20240310T100000Z\r\n" +
+	"SUMMARY:From phone\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalDAVResourceLifecycle(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	h := NewCalDAVHandler(calendar, "/caldav")
+	href := "/caldav/user1/calendar/phone-1.ics"
+
+	resp, _ := davRequest(t, h, http.MethodPut, href, caldavTestEvent, map[string]string{"If-None-Match": "*"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201 on create, got %d", resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag on create")
+	}
+
+	resp, _ = davRequest(t, h, http.MethodPut, href, caldavTestEvent, map[string]string{"If-None-Match": "*"})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 when creating an existing resource, got %d", resp.StatusCode)
+	}
+
+	resp, body := davRequest(t, h, http.MethodGet, href, "", nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != etag || !strings.Contains(body, "SUMMARY:From phone") {
+		t.Fatalf("Unexpected GET response %d %s:\n%s", resp.StatusCode, resp.Header.Get("ETag"), body)
+	}
+	resp, _ = davRequest(t, h, http.MethodGet, href, "", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for matching If-None-Match, got %d", resp.StatusCode)
+	}
+
+	updated := strings.Replace(caldavTestEvent, "From phone", "Moved", 1)
+	resp, _ = davRequest(t, h, http.MethodPut, href, updated, map[string]string{"If-Match": `"stale"`})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for stale If-Match, got %d", resp.StatusCode)
+	}
+	resp, _ = davRequest(t, h, http.MethodPut, href, updated, map[string]string{"If-Match": etag})
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204 on update, got %d", resp.StatusCode)
+	}
+	newETag := resp.Header.Get("ETag")
+	if newETag == etag {
+		t.Error("Expected ETag to change after update")
+	}
+
+	events, _ := calendar.GetEventsForDay("2024-03-10", "user1")
+	if len(events) != 1 || events[0].Title != "Moved" {
+		t.Fatalf("Expected updated event in calendar, got %+v", events)
+	}
+	// ETag общий с REST API: If-Match можно переносить между интерфейсами
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	if rec := serve(router, http.MethodGet, "/api/v1/users/user1/events/"+strconv.Itoa(events[0].ID), "", ""); rec.Header().Get("ETag") != newETag {
+		t.Errorf("Expected the REST ETag %s to match CalDAV, got %s", newETag, rec.Header().Get("ETag"))
+	}
+
+	resp, _ = davRequest(t, h, http.MethodPut, "/caldav/user1/calendar/other.ics", caldavTestEvent, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 when UID does not match resource name, got %d", resp.StatusCode)
+	}
+
+	resp, _ = davRequest(t, h, http.MethodDelete, href, "", map[string]string{"If-Match": etag})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for stale delete, got %d", resp.StatusCode)
+	}
+	resp, _ = davRequest(t, h, http.MethodDelete, href, "", map[string]string{"If-Match": newETag})
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204 on delete, got %d", resp.StatusCode)
+	}
+	resp, _ = davRequest(t, h, http.MethodGet, href, "", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestCalDAVPropfind(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Existing", Date: "2024-03-10"})
+	h := NewCalDAVHandler(calendar, "/caldav")
+
+	body := `<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop><d:resourcetype/><d:getetag/><cs:getctag/><c:calendar-home-set/><d:quota-used-bytes/></d:prop>
+</d:propfind>`
+
+	resp, out := davRequest(t, h, "PROPFIND", "/caldav/user1/calendar/", body, map[string]string{"Depth": "1"})
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("Expected 207, got %d", resp.StatusCode)
+	}
+	for _, want := range []string{
+		"<d:href>/caldav/user1/calendar/</d:href>",
+		"<c:calendar/>",
+		"<cs:getctag>",
+		"<d:href>/caldav/user1/calendar/event-1@calendarServer.ics</d:href>",
+		"<d:getetag>&#34;",
+		"<d:quota-used-bytes></d:quota-used-bytes></d:prop><d:status>HTTP/1.1 404 Not Found</d:status>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected PROPFIND response to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	resp, out = davRequest(t, h, "PROPFIND", "/caldav/user1/", "", map[string]string{"Depth": "0"})
+	if resp.StatusCode != http.StatusMultiStatus || !strings.Contains(out, "<c:calendar-home-set><d:href>/caldav/user1/</d:href>") {
+		t.Errorf("Expected principal with calendar-home-set, got %d:\n%s", resp.StatusCode, out)
+	}
+
+	// Подписка на календарь целиком
+	resp, out = davRequest(t, h, http.MethodGet, "/caldav/user1/calendar/", "", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(out, "SUMMARY:Existing") {
+		t.Errorf("Expected full calendar on GET, got %d:\n%s", resp.StatusCode, out)
+	}
+}
+
+func TestCalDAVReports(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Weekly", Start: start, End: start.Add(time.Hour), RRule: "FREQ=WEEKLY"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Once", Date: "2024-01-15"})
+	calendar.CreateEvent(Event{UserID: "user2", Title: "Foreign", Date: "2024-04-01"})
+	h := NewCalDAVHandler(calendar, "/caldav")
+
+	query := `<?xml version="1.0"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">
+    <c:time-range start="20240401T000000Z" end="20240501T000000Z"/>
+  </c:comp-filter></c:comp-filter></c:filter>
+</c:calendar-query>`
+	resp, out := davRequest(t, h, "REPORT", "/caldav/user1/calendar/", query, map[string]string{"Depth": "1"})
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("Expected 207, got %d", resp.StatusCode)
+	}
+	if strings.Count(out, "<d:response>") != 1 || !strings.Contains(out, "SUMMARY:Weekly") || !strings.Contains(out, "RRULE:FREQ=WEEKLY") {
+		t.Errorf("Expected only the recurring event in April, got:\n%s", out)
+	}
+
+	multiget := `<?xml version="1.0"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <d:href>/caldav/user1/calendar/event-2@calendarServer.ics</d:href>
+  <d:href>/caldav/user1/calendar/event-3@calendarServer.ics</d:href>
+</c:calendar-multiget>`
+	resp, out = davRequest(t, h, "REPORT", "/caldav/user1/calendar/", multiget, nil)
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("Expected 207, got %d", resp.StatusCode)
+	}
+	// Событие другого пользователя не видно по чужому пути
+	if strings.Count(out, "<d:getetag>") != 1 || !strings.Contains(out, "<d:status>HTTP/1.1 404 Not Found</d:status>") {
+		t.Errorf("Expected one found and one missing resource, got:\n%s", out)
+	}
+
+	resp, _ = davRequest(t, h, "REPORT", "/caldav/user1/calendar/", `<d:sync-collection xmlns:d="DAV:"/>`, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for unsupported report, got %d", resp.StatusCode)
+	}
+}
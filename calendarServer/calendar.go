@@ -2,69 +2,397 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 )
 
 type Event struct {
-	ID          int    `json:"id"`
-	UserID      string `json:"user_id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Date        string `json:"date"`
+	ID          int       `json:"id"`
+	UID         string    `json:"uid,omitempty"` // UID из импортированного iCalendar
+	UserID      string    `json:"user_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags,omitempty"`      // метки для фильтрации, хранятся в нижнем регистре
+	Date        string    `json:"date"`                // дата начала, YYYY-MM-DD
+	EndDate     string    `json:"end_date,omitempty"`  // последний день события на весь день (включительно)
+	Start       time.Time `json:"start,omitzero"`      // начало события со временем
+	End         time.Time `json:"end,omitzero"`        // окончание события со временем (не включительно)
+	AllDay      bool      `json:"all_day"`             // событие на весь день, задается только датами
+	TimeZone    string    `json:"time_zone,omitempty"` // часовой пояс IANA, в котором заданы start и end
+
+	RRule        string     `json:"rrule,omitempty"`         // правило повторения RFC 5545, например FREQ=WEEKLY;BYDAY=MO,WE
+	ExDates      []string   `json:"exdates,omitempty"`       // исключенные экземпляры (их recurrence_id)
+	Overrides    []Override `json:"overrides,omitempty"`     // измененные и отмененные экземпляры
+	RecurrenceID string     `json:"recurrence_id,omitempty"` // у раскрытого экземпляра — его исходное начало
+	Holidays     string     `json:"holidays,omitempty"`      // производственный календарь для on_holiday
+	OnHoliday    string     `json:"on_holiday,omitempty"`    // экземпляр на нерабочем дне: skip, next или previous
+
+	Reminders     []Duration `json:"reminders,omitempty"`     // за сколько до начала напомнить, например ["15m", "24h"]
+	RemindedUntil time.Time  `json:"reminded_until,omitzero"` // напоминания до этого момента уже доставлены; ведет сервер
+
+	CalendarID int        `json:"calendar_id,omitempty"` // календарь владельца; 0 — календарь по умолчанию
+	Attendees  []Attendee `json:"attendees,omitempty"`   // приглашенные пользователи и их ответы
+
+	Version   int       `json:"version"`             // растет при каждом изменении; ведет сервер, основа ETag
+	DeletedAt time.Time `json:"deleted_at,omitzero"` // момент удаления события, лежащего в корзине
 }
 
+var (
+	// ErrEventNotFound — событие с таким ID не существует
+	ErrEventNotFound = errors.New("event not found")
+	// ErrForbidden — событие принадлежит другому пользователю
+	ErrForbidden = errors.New("event belongs to another user")
+	// ErrDuplicateUID — у пользователя уже есть событие с таким iCalendar UID
+	ErrDuplicateUID = errors.New("event with this UID already exists")
+	// ErrOverlap — событие пересекается с другими событиями пользователя
+	ErrOverlap = errors.New("event overlaps with existing events")
+)
+
 type Calendar struct {
 	events map[int]*Event
 	mu     sync.RWMutex
 	nextID int
+	repo   Repository            // постоянное хранилище, nil — события живут только в памяти
+	index  map[string]*userIndex // события по пользователям, упорядоченные по времени; создается при первой записи
+
+	calendars      map[int]*UserCalendar     // именованные календари пользователей
+	nextCalendarID int                       // следующий свободный ID календаря; 0 — календарей еще нет
+	attending      map[string]map[int]*Event // события, на которые приглашен пользователь, по ID
+	trash          map[int]*Event            // удаленные события, которые еще можно восстановить
+	userTrash      map[string]map[int]*Event // те же события по владельцам, для ограничения корзины
+	settings       map[string]UserSettings   // настройки пользователей; нет записи — настройки по умолчанию
+	quota          Quota                     // ограничения на данные пользователя
+
+	changes changeFeed // лента изменений для потоков /changes
+}
+
+// NewCalendar создает календарь и загружает в него события из хранилища (если оно задано)
+func NewCalendar(repo Repository) (*Calendar, error) {
+	c := &Calendar{
+		events: make(map[int]*Event),
+		nextID: 1,
+		repo:   repo,
+	}
+	if repo == nil {
+		return c, nil
+	}
+
+	events, nextID, err := repo.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+	for i := range events {
+		event := events[i]
+		event.localize()
+		// События, сохраненные до появления версий
+		if event.Version == 0 {
+			event.Version = 1
+		}
+		if event.DeletedAt.IsZero() {
+			c.events[event.ID] = &event
+			c.indexPut(nil, &event)
+		} else {
+			c.trashPut(&event)
+		}
+		// nextID не должен указывать на уже занятый ID, даже если хранилище отстало
+		if event.ID >= nextID {
+			nextID = event.ID + 1
+		}
+	}
+	if nextID > c.nextID {
+		c.nextID = nextID
+	}
+
+	calendars, err := repo.LoadCalendars()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendars: %w", err)
+	}
+	for i := range calendars {
+		c.putCalendar(&calendars[i])
+	}
+
+	settings, err := repo.LoadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	for _, s := range settings {
+		c.putSettings(s)
+	}
+	if err := c.purgeTrash(time.Now()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Ping проверяет, что постоянное хранилище доступно; календарь в памяти доступен всегда
+func (c *Calendar) Ping() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.repo == nil {
+		return nil
+	}
+	return c.repo.Ping()
+}
+
+// Count возвращает число хранимых событий
+func (c *Calendar) Count() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.events)
+}
+
+// Close закрывает постоянное хранилище календаря
+func (c *Calendar) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.repo == nil {
+		return nil
+	}
+	return c.repo.Close()
+}
+
+// WriteOptions — дополнительные проверки при сохранении события
+type WriteOptions struct {
+	RejectOverlap bool // отклонять событие, пересекающееся по времени с другими событиями пользователя
+	// IfVersion — изменять и удалять событие, только если его текущая версия в списке (If-Match);
+	// nil — без проверки
+	IfVersion []int
+}
+
+// versionMatches проверяет ожидаемую версию события
+func (o WriteOptions) versionMatches(version int) bool {
+	return o.IfVersion == nil || slices.Contains(o.IfVersion, version)
 }
 
 func (c *Calendar) CreateEvent(event Event) (int, error) {
+	return c.CreateEventWithOptions(event, WriteOptions{})
+}
+
+// CreateEventWithOptions создает событие с дополнительными проверками opts
+func (c *Calendar) CreateEventWithOptions(event Event, opts WriteOptions) (int, error) {
 	if event.UserID == "" {
 		return 0, errors.New("user ID is required")
 	}
 
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return 0, err
+	}
+
 	// ID всегда будет пустой, поэтому он будет генерироваться сервером
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	id := c.nextID
+	created, err := c.prepareCreate(event, opts)
+	if err != nil {
+		return 0, err
+	}
+	if c.repo != nil {
+		if err := c.repo.CreateEvent(created, created.ID+1); err != nil {
+			return 0, fmt.Errorf("failed to store event: %w", err)
+		}
+	}
 	c.nextID++
-	event.ID = id
-	c.events[id] = &event
-	return id, nil
+	c.putEvent(nil, &created)
+	c.publishChange(nil, &created)
+	return created.ID, nil
+}
+
+// prepareCreate проверяет новое событие и возвращает его в том виде, в каком оно будет сохранено,
+// с ID c.nextID. Календарь не меняется. Вызывается под блокировкой записи.
+func (c *Calendar) prepareCreate(event Event, opts WriteOptions) (Event, error) {
+	// В чужой календарь событие попадает от имени его владельца
+	owner, err := c.calendarOwner(event.UserID, event.CalendarID)
+	if err != nil {
+		return Event{}, err
+	}
+	event.UserID = owner
+	keepResponses(event.Attendees, nil)
+	if c.uidTaken(event.UserID, event.UID, 0) {
+		return Event{}, ErrDuplicateUID
+	}
+	if err := c.checkQuota(&event, true); err != nil {
+		return Event{}, err
+	}
+	if opts.RejectOverlap {
+		if conflicts := c.conflicts(&event); len(conflicts) > 0 {
+			return Event{}, &OverlapError{Conflicts: conflicts}
+		}
+	}
+	event.ID = c.nextID
+	event.Version = 1
+	event.RemindedUntil = time.Time{}
+	event.DeletedAt = time.Time{}
+	return event, nil
 }
 
 func (c *Calendar) UpdateEvent(event Event) error {
+	return c.UpdateEventWithOptions(event, WriteOptions{})
+}
+
+// UpdateEventWithOptions изменяет событие с дополнительными проверками opts.
+// event.UserID — пользователь, который меняет событие: владелец или пользователь с правом записи в календарь события.
+// Нулевой calendar_id оставляет событие в текущем календаре; переносить можно только между календарями владельца.
+func (c *Calendar) UpdateEventWithOptions(event Event, opts WriteOptions) error {
 	if event.ID == 0 {
 		return errors.New("event ID is required")
 	}
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return err
+	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	existing, updated, err := c.prepareUpdate(event, opts)
+	if err != nil {
+		return err
+	}
+	if c.repo != nil {
+		if err := c.repo.UpdateEvent(updated); err != nil {
+			return fmt.Errorf("failed to store event: %w", err)
+		}
+	}
+	c.putEvent(existing, &updated)
+	c.publishChange(existing, &updated)
+	return nil
+}
+
+// prepareUpdate проверяет изменение события и возвращает текущее событие и его новое состояние.
+// Календарь не меняется. Вызывается под блокировкой записи.
+func (c *Calendar) prepareUpdate(event Event, opts WriteOptions) (*Event, Event, error) {
+	// Проверяем существование события и его владельца
+	existing, exists := c.events[event.ID]
+	if !exists {
+		return nil, Event{}, ErrEventNotFound
+	}
+	if err := c.checkWrite(event.UserID, existing); err != nil {
+		return nil, Event{}, err
+	}
+	if !opts.versionMatches(existing.Version) {
+		return nil, Event{}, ErrVersionMismatch
+	}
+	actor := event.UserID
+	event.UserID = existing.UserID
+	if event.CalendarID == 0 {
+		event.CalendarID = existing.CalendarID
+	} else if event.CalendarID != existing.CalendarID {
+		owner, err := c.calendarOwner(actor, event.CalendarID)
+		if err != nil {
+			return nil, Event{}, err
+		}
+		if owner != existing.UserID {
+			return nil, Event{}, ErrCrossOwnerMove
+		}
+	}
+	// Отвечать на приглашение может только сам участник
+	keepResponses(event.Attendees, existing.Attendees)
+	if c.uidTaken(event.UserID, event.UID, event.ID) {
+		return nil, Event{}, ErrDuplicateUID
+	}
+	if err := c.checkQuota(&event, false); err != nil {
+		return nil, Event{}, err
+	}
+	if opts.RejectOverlap {
+		if conflicts := c.conflicts(&event); len(conflicts) > 0 {
+			return nil, Event{}, &OverlapError{Conflicts: conflicts}
+		}
+	}
+	// Состояние напоминаний ведет планировщик, клиент не может его сбросить
+	event.RemindedUntil = existing.RemindedUntil
+	event.Version = existing.Version + 1
+	event.DeletedAt = time.Time{}
+	return existing, event, nil
+}
 
-	// Проверяем существование события
-	if _, exists := c.events[event.ID]; !exists {
-		return errors.New("event not found")
+// DeleteUserEvent удаляет событие, только если оно принадлежит userID или userID может писать в его календарь
+func (c *Calendar) DeleteUserEvent(userID string, id int) error {
+	if userID == "" {
+		return errors.New("user ID is required")
 	}
+	return c.deleteEvent(userID, id, WriteOptions{})
+}
 
-	c.events[event.ID] = &event
-	return nil
+// DeleteUserEventWithOptions удаляет событие с проверкой версии opts.IfVersion
+func (c *Calendar) DeleteUserEventWithOptions(userID string, id int, opts WriteOptions) error {
+	if userID == "" {
+		return errors.New("user ID is required")
+	}
+	return c.deleteEvent(userID, id, opts)
 }
 
-func (c *Calendar) DeleteEvent(id int) error {
+// deleteEvent переносит событие в корзину, откуда его можно восстановить в течение trashRetention;
+// непустой userID должен иметь право записи
+func (c *Calendar) deleteEvent(userID string, id int, opts WriteOptions) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Проверяем существование события
-	if _, exists := c.events[id]; !exists {
-		return errors.New("event not found")
+	now := time.Now()
+	existing, deleted, err := c.prepareDelete(userID, id, opts, now)
+	if err != nil {
+		return err
+	}
+	if c.repo != nil {
+		if err := c.repo.UpdateEvent(deleted); err != nil {
+			return fmt.Errorf("failed to delete stored event: %w", err)
+		}
+	}
+	c.putEvent(existing, &deleted)
+	c.publishChange(existing, nil)
+	// Событие уже удалено, ошибка очистки корзины только откладывает ее до следующего раза
+	if err := c.purgeTrash(now); err != nil {
+		slog.Warn("failed to purge deleted events", slog.Any("error", err))
 	}
-
-	delete(c.events, id)
 	return nil
 }
 
+// prepareDelete проверяет удаление события и возвращает текущее событие и его состояние в корзине.
+// Календарь не меняется. Вызывается под блокировкой записи.
+func (c *Calendar) prepareDelete(userID string, id int, opts WriteOptions, now time.Time) (*Event, Event, error) {
+	// Проверяем существование события и его владельца
+	existing, exists := c.events[id]
+	if !exists {
+		return nil, Event{}, ErrEventNotFound
+	}
+	if userID != "" {
+		if err := c.checkWrite(userID, existing); err != nil {
+			return nil, Event{}, err
+		}
+	}
+	if !opts.versionMatches(existing.Version) {
+		return nil, Event{}, ErrVersionMismatch
+	}
+	deleted := *existing
+	deleted.Version++
+	deleted.DeletedAt = now.UTC()
+	return existing, deleted, nil
+}
+
+// putEvent заменяет в памяти old на event (любой из них может быть nil): живые события попадают
+// в events и индекс, удаленные — в корзину. Вызывается под блокировкой записи.
+func (c *Calendar) putEvent(old, event *Event) {
+	if old != nil {
+		if old.DeletedAt.IsZero() {
+			delete(c.events, old.ID)
+			c.indexPut(old, nil)
+		} else {
+			c.trashRemove(old)
+		}
+	}
+	if event == nil {
+		return
+	}
+	if event.DeletedAt.IsZero() {
+		c.events[event.ID] = event
+		c.indexPut(nil, event)
+	} else {
+		c.trashPut(event)
+	}
+}
+
 func ValidateEvent(event Event) error {
 	if event.UserID == "" {
 		return errors.New("user ID is required")
@@ -72,78 +400,166 @@ func ValidateEvent(event Event) error {
 	if event.Title == "" {
 		return errors.New("title is required")
 	}
-	// Date must be in format YYYY-MM-DD
-	if _, err := time.Parse("2006-01-02", event.Date); err != nil {
-		return errors.New("date must be in format YYYY-MM-DD")
+	_, err := normalizeEvent(event)
+	return err
+}
+
+// GetEventsInRange возвращает события пользователя, пересекающиеся с полуинтервалом [from, to), по возрастанию начала.
+// Вместе с собственными возвращаются события календарей, которыми с пользователем поделились,
+// и события, на которые он приглашен и не отказался.
+// События на весь день рассматриваются в поясе from.
+// Повторяющиеся события раскрываются в отдельные экземпляры с заполненным recurrence_id.
+func (c *Calendar) GetEventsInRange(userID string, from, to time.Time) ([]Event, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	events := c.eventsInRange(userID, from, to)
+	if shared := c.sharedEventsInRange(userID, from, to); len(shared) > 0 {
+		events = append(events, shared...)
+		sortByStart(events, from.Location())
 	}
-	return nil
+	return events, nil
 }
 
-func (c *Calendar) GetEventsForDay(date string, userID string) ([]Event, error) {
+// GetUserEvents возвращает события пользователя без раскрытия повторений, по возрастанию ID.
+// Если from и to не нулевые, возвращаются только события, хотя бы один экземпляр которых попадает в [from, to).
+func (c *Calendar) GetUserEvents(userID string, from, to time.Time) []Event {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+
 	events := make([]Event, 0)
-	for _, event := range c.events {
-		if event.Date == date && event.UserID == userID {
-			events = append(events, *event)
+	for _, event := range c.userEvents(userID) {
+		if !from.IsZero() && !to.IsZero() && !event.occursIn(from, to) {
+			continue
 		}
+		events = append(events, *event)
 	}
-	return events, nil
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
 }
 
-// addDays adds days to a date
-func addDays(date string, days int) string {
-	dateTime, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		return ""
+// uidTaken проверяет, занят ли явный UID другим событием пользователя (кроме exceptID).
+// Вызывается под блокировкой.
+func (c *Calendar) uidTaken(userID, uid string, exceptID int) bool {
+	if uid == "" {
+		return false
 	}
-	return dateTime.AddDate(0, 0, days).Format("2006-01-02")
+	for _, event := range c.userEvents(userID) {
+		if event.ID != exceptID && event.uid() == uid {
+			return true
+		}
+	}
+	return false
 }
 
-func (c *Calendar) GetEventsForWeek(date string, userID string) ([]Event, error) {
+// eventsWithReminders возвращает копии всех событий, у которых есть напоминания
+func (c *Calendar) eventsWithReminders() []Event {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-
-	startDate, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		return nil, errors.New("invalid date format")
+	var events []Event
+	for _, event := range c.events {
+		if len(event.Reminders) > 0 {
+			events = append(events, *event)
+		}
 	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
 
-	// Неделя начинается с указанной даты и длится 7 дней
-	endDate := startDate.AddDate(0, 0, 6)
-	endDateStr := endDate.Format("2006-01-02")
+// markReminded сдвигает отметку о доставленных до until напоминаниях события id. Отметка
+// записывается в хранилище, только если persist: если ни одно напоминание не наступило,
+// после перезапуска повторять нечего, и журнал не растет на каждой проверке.
+func (c *Calendar) markReminded(id int, until time.Time, persist bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing, exists := c.events[id]
+	if !exists {
+		return ErrEventNotFound
+	}
+	if !until.After(existing.RemindedUntil) {
+		return nil
+	}
 
-	events := make([]Event, 0)
-	for _, event := range c.events {
-		if event.UserID == userID && event.Date >= date && event.Date <= endDateStr {
-			events = append(events, *event)
+	event := *existing
+	event.RemindedUntil = until
+	if persist && c.repo != nil {
+		if err := c.repo.UpdateEvent(event); err != nil {
+			return fmt.Errorf("failed to store event: %w", err)
 		}
 	}
-	return events, nil
+	c.events[id] = &event
+	c.indexPut(existing, &event)
+	return nil
 }
 
-func (c *Calendar) GetEventsForMonth(date string, userID string) ([]Event, error) {
+// GetEvent возвращает событие по ID, если оно видно пользователю: свое, из открытого ему календаря
+// или событие, на которое он приглашен. Остальные события не видны
+func (c *Calendar) GetEvent(userID string, id int) (Event, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	event, exists := c.events[id]
+	if !exists || !c.canRead(userID, event) {
+		return Event{}, ErrEventNotFound
+	}
+	return *event, nil
+}
 
-	startDate, err := time.Parse("2006-01-02", date)
+// getUserEventByUID ищет событие пользователя по iCalendar UID
+func (c *Calendar) getUserEventByUID(userID, uid string) (Event, bool) {
+	if uid == "" {
+		return Event{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, event := range c.userEvents(userID) {
+		if event.uid() == uid {
+			return *event, true
+		}
+	}
+	return Event{}, false
+}
+
+// GetEventsForDay возвращает события за сутки date (UTC)
+func (c *Calendar) GetEventsForDay(date string, userID string) ([]Event, error) {
+	from, to, err := DayRange(date, time.UTC)
 	if err != nil {
-		return nil, errors.New("invalid date format")
+		return nil, err
 	}
+	return c.GetEventsInRange(userID, from, to)
+}
 
-	// Получаем первый день месяца
-	firstOfMonth := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-	// Получаем последний день месяца
-	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+// addDays adds days to a date
+func addDays(date string, days int) string {
+	dateTime, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	return dateTime.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// GetEventsForWeek возвращает события за календарную неделю, в которую попадает date (UTC).
+// Неделя начинается с первого дня недели из настроек пользователя.
+func (c *Calendar) GetEventsForWeek(date string, userID string) ([]Event, error) {
+	from, to, err := WeekRange(date, time.UTC, c.WeekStart(userID))
+	if err != nil {
+		return nil, err
+	}
+	return c.GetEventsInRange(userID, from, to)
+}
 
-	startDateStr := firstOfMonth.Format("2006-01-02")
-	endDateStr := lastOfMonth.Format("2006-01-02")
+// GetEventsForMonth возвращает события за календарный месяц, в который попадает date (UTC)
+func (c *Calendar) GetEventsForMonth(date string, userID string) ([]Event, error) {
+	from, to, err := MonthRange(date, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetEventsInRange(userID, from, to)
+}
 
-	events := make([]Event, 0)
-	for _, event := range c.events {
-		if event.UserID == userID && event.Date >= startDateStr && event.Date <= endDateStr {
-			events = append(events, *event)
-		}
+// GetEventsForYear возвращает события за календарный год, в который попадает date (UTC)
+func (c *Calendar) GetEventsForYear(date string, userID string) ([]Event, error) {
+	from, to, err := YearRange(date, time.UTC)
+	if err != nil {
+		return nil, err
 	}
-	return events, nil
+	return c.GetEventsInRange(userID, from, to)
 }
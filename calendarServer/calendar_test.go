@@ -150,7 +150,7 @@ func TestDeleteEvent(t *testing.T) {
 	}
 
 	// Удаляем событие
-	err = calendar.DeleteEvent(id)
+	err = calendar.DeleteUserEvent("user1", id)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -171,7 +171,7 @@ func TestDeleteNonExistentEvent(t *testing.T) {
 		nextID: 1,
 	}
 
-	err := calendar.DeleteEvent(999)
+	err := calendar.DeleteUserEvent("user1", 999)
 	if err == nil {
 		t.Fatal("Expected error for non-existent event, got nil")
 	}
@@ -221,10 +221,20 @@ func TestGetEventsForWeek(t *testing.T) {
 	calendar.CreateEvent(Event{UserID: "user1", Title: "Day 8", Date: "2024-01-07"}) // Вне недели
 	calendar.CreateEvent(Event{UserID: "user2", Title: "Other User", Date: "2024-01-02"})
 
+	// По умолчанию неделя начинается с понедельника: воскресенье 31 декабря — ее последний день
 	events, err := calendar.GetEventsForWeek("2023-12-31", "user1")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
+	if got := orderedTitles(events); !equalStrings(got, []string{"Day 1"}) {
+		t.Errorf("Expected only Day 1 in the week from Monday, got %v", got)
+	}
+
+	calendar.UpdateSettings(UserSettings{UserID: "user1", WeekStart: "sunday"})
+	events, err = calendar.GetEventsForWeek("2024-01-04", "user1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
 
 	if len(events) != 3 {
 		t.Errorf("Expected 3 events, got %d", len(events))
@@ -0,0 +1,5 @@
+// Package calendarpb — protobuf-описание gRPC API сервера календаря (calendar.proto) и сгенерированный по нему код.
+// Код перегенерируется командой go generate при установленных protoc, protoc-gen-go и protoc-gen-go-grpc.
+package calendarpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative calendar.proto
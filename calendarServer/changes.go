@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Типы изменений в ленте
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// defaultChangeHistory — сколько последних изменений лента хранит для переподключившихся клиентов
+const defaultChangeHistory = 10000
+
+// changeHeartbeat — как часто поток изменений шлет комментарий, чтобы прокси не закрыли соединение
+var changeHeartbeat = 15 * time.Second
+
+// ErrChangesExpired — изменения после запрошенного номера уже вытеснены из ленты или номер из
+// другого запуска сервера; клиенту нужно перечитать календарь целиком
+var ErrChangesExpired = errors.New("changes since this sequence number are no longer available")
+
+// Change — изменение события в ленте. Type и Event зависят от получателя: пользователь,
+// потерявший доступ к событию (например, исключенный участник), получает deleted без события.
+type Change struct {
+	Seq        int64     `json:"seq"`
+	Type       string    `json:"type"`
+	EventID    int       `json:"event_id"`
+	UserID     string    `json:"user_id"` // владелец события
+	CalendarID int       `json:"calendar_id,omitempty"`
+	Event      *Event    `json:"event,omitempty"` // новое состояние события; нет у deleted
+	Time       time.Time `json:"time"`
+
+	audience map[string]string // получатель -> тип изменения для него
+}
+
+// changeFeed — лента изменений событий с монотонными номерами. Хранит последние limit изменений,
+// чтобы переподключившийся клиент получил пропущенное. Нулевое значение готово к работе.
+type changeFeed struct {
+	mu      sync.Mutex
+	seq     int64 // номер последнего изменения
+	trimmed int64 // номер последнего вытесненного изменения: с меньших номеров продолжить нельзя
+	history []Change
+	limit   int           // 0 — defaultChangeHistory
+	notify  chan struct{} // закрывается и заменяется при каждом изменении
+	done    chan struct{} // закрывается при остановке сервера
+}
+
+// init задает начальный номер. Номера начинаются с текущего времени в микросекундах, поэтому
+// после перезапуска они больше всех выданных раньше, и старый номер клиента распознается как устаревший.
+// Вызывается под блокировкой ленты.
+func (f *changeFeed) init() {
+	if f.notify != nil {
+		return
+	}
+	f.seq = time.Now().UnixMicro()
+	f.trimmed = f.seq
+	f.notify = make(chan struct{})
+	f.done = make(chan struct{})
+}
+
+// publish добавляет изменение и будит ожидающих подписчиков
+func (f *changeFeed) publish(change Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	f.seq++
+	change.Seq = f.seq
+	f.history = append(f.history, change)
+	limit := f.limit
+	if limit <= 0 {
+		limit = defaultChangeHistory
+	}
+	if drop := len(f.history) - limit; drop > 0 {
+		f.trimmed = f.history[drop-1].Seq
+		f.history = append(f.history[:0:0], f.history[drop:]...)
+	}
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+// since возвращает изменения после номера after, видимые userID, номер последнего изменения ленты
+// и каналы: notify закроется при следующем изменении, done — при остановке ленты
+func (f *changeFeed) since(userID string, after int64) ([]Change, int64, <-chan struct{}, <-chan struct{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	if after < f.trimmed || after > f.seq {
+		return nil, f.seq, f.notify, f.done, ErrChangesExpired
+	}
+	first := sort.Search(len(f.history), func(i int) bool { return f.history[i].Seq > after })
+	var changes []Change
+	for _, change := range f.history[first:] {
+		kind := change.audience[userID]
+		if kind == "" {
+			continue
+		}
+		change.Type = kind
+		if kind == ChangeDeleted {
+			change.Event = nil
+		}
+		change.audience = nil
+		changes = append(changes, change)
+	}
+	return changes, f.seq, f.notify, f.done, nil
+}
+
+// last возвращает номер последнего изменения
+func (f *changeFeed) last() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	return f.seq
+}
+
+// stop завершает все потоки изменений
+func (f *changeFeed) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	select {
+	case <-f.done:
+	default:
+		close(f.done)
+	}
+}
+
+// StopChangeFeed завершает открытые потоки изменений, чтобы остановка сервера не ждала их
+func (c *Calendar) StopChangeFeed() {
+	c.changes.stop()
+}
+
+// eventAudience возвращает пользователей, которые могут видеть событие: владельца, участников
+// и тех, с кем поделились календарем события. Вызывается под блокировкой.
+func (c *Calendar) eventAudience(event *Event) []string {
+	users := []string{event.UserID}
+	for _, attendee := range event.Attendees {
+		users = append(users, attendee.UserID)
+	}
+	if cal := c.calendars[event.CalendarID]; event.CalendarID != 0 && cal != nil {
+		for _, share := range cal.Shares {
+			users = append(users, share.UserID)
+		}
+	}
+	return users
+}
+
+// publishChange записывает в ленту замену old на event (любой из них может быть nil).
+// Для каждого, кто видел событие до или видит после, определяется, как изменение выглядит для него.
+// Вызывается под блокировкой записи.
+func (c *Calendar) publishChange(old, event *Event) {
+	audience := make(map[string]string)
+	var candidates []string
+	if old != nil {
+		candidates = append(candidates, c.eventAudience(old)...)
+	}
+	if event != nil {
+		candidates = append(candidates, c.eventAudience(event)...)
+	}
+	for _, user := range candidates {
+		if _, seen := audience[user]; seen {
+			continue
+		}
+		before := old != nil && c.canRead(user, old)
+		after := event != nil && c.canRead(user, event)
+		switch {
+		case !before && after:
+			audience[user] = ChangeCreated
+		case before && after:
+			audience[user] = ChangeUpdated
+		case before && !after:
+			audience[user] = ChangeDeleted
+		}
+	}
+	if len(audience) == 0 {
+		return
+	}
+
+	current := event
+	if current == nil {
+		current = old
+	}
+	change := Change{EventID: current.ID, UserID: current.UserID, CalendarID: current.CalendarID, Time: time.Now().UTC(), audience: audience}
+	if event != nil {
+		copied := *event
+		change.Event = &copied
+	}
+	c.changes.publish(change)
+}
+
+// publishAccess сообщает пользователю userID о событиях календаря cal, которые стали ему видны
+// (kind = created) или перестали (kind = deleted) после изменения доступа. Вызывается под блокировкой записи.
+func (c *Calendar) publishAccess(cal *UserCalendar, userID, kind string) {
+	for _, event := range c.userEvents(cal.OwnerID) {
+		if event.CalendarID != cal.ID || event.attendeeStatus(userID) != "" {
+			continue
+		}
+		change := Change{
+			EventID:    event.ID,
+			UserID:     event.UserID,
+			CalendarID: event.CalendarID,
+			Time:       time.Now().UTC(),
+			audience:   map[string]string{userID: kind},
+		}
+		if kind != ChangeDeleted {
+			copied := *event
+			change.Event = &copied
+		}
+		c.changes.publish(change)
+	}
+}
+
+// requestChangeSeq возвращает номер, с которого продолжить поток: параметр since или заголовок
+// Last-Event-ID, который EventSource отправляет при переподключении. Без них поток начинается с текущего момента.
+func requestChangeSeq(r *http.Request, calendar *Calendar) (int64, error) {
+	value := r.URL.Query().Get("since")
+	if value == "" {
+		value = r.Header.Get("Last-Event-ID")
+	}
+	if value == "" {
+		return calendar.changes.last(), nil
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("since must be a change sequence number")
+	}
+	return seq, nil
+}
+
+// changesHandler отдает поток изменений событий пользователя в формате Server-Sent Events.
+// Каждое изменение — событие SSE с id (номер изменения), event (тип) и data (Change в JSON).
+// Если продолжить с запрошенного номера нельзя, первым приходит событие reset: клиент перечитывает
+// календарь и продолжает с номера из него. Комментарии-пинги тоже несут id, чтобы номер
+// клиента не устаревал, пока у него нет изменений.
+func changesHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		since, err := requestChangeSeq(r, calendar)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		// Поток живет дольше -write-timeout сервера
+		rc.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Просим nginx не буферизовать поток
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		heartbeat := time.NewTicker(changeHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			changes, last, notify, done, err := calendar.changes.since(userID, since)
+			if errors.Is(err, ErrChangesExpired) {
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {\"seq\":%d}\n\n", last, last); err != nil {
+					return
+				}
+			}
+			for _, change := range changes {
+				data, _ := json.Marshal(change)
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", change.Seq, change.Type, data); err != nil {
+					return
+				}
+			}
+			since = last
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case <-notify:
+			case <-heartbeat.C:
+				if _, err := fmt.Fprintf(w, ": ping\nid: %d\n\n", since); err != nil {
+					return
+				}
+			case <-done:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"WBTechL2/calendarServer/client"
+)
+
+func changeTypes(changes []Change) []string {
+	res := make([]string, len(changes))
+	for i, change := range changes {
+		res[i] = change.Type
+	}
+	return res
+}
+
+func TestChangeFeed(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := calendar.changes.last()
+
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Planning", Date: "2024-03-10", Attendees: []Attendee{{UserID: "user2"}}})
+	calendar.RespondToEvent("user2", id, RSVPAccepted)
+	// user2 исключен из участников: для него событие удалено
+	calendar.UpdateEvent(Event{ID: id, UserID: "user1", Title: "Planning", Date: "2024-03-10"})
+	calendar.DeleteUserEvent("user1", id)
+
+	changes, _, _, _, err := calendar.changes.since("user1", start)
+	if err != nil || !equalStrings(changeTypes(changes), []string{ChangeCreated, ChangeUpdated, ChangeUpdated, ChangeDeleted}) {
+		t.Errorf("Unexpected changes of the owner: %v, %v", changeTypes(changes), err)
+	}
+	changes, _, _, _, _ = calendar.changes.since("user2", start)
+	if !equalStrings(changeTypes(changes), []string{ChangeCreated, ChangeUpdated, ChangeDeleted}) {
+		t.Errorf("Unexpected changes of the attendee: %v", changeTypes(changes))
+	}
+	if changes[2].Event != nil || changes[2].EventID != id {
+		t.Errorf("Expected deleted change without the event, got %+v", changes[2])
+	}
+	for i := 1; i < len(changes); i++ {
+		if changes[i].Seq <= changes[i-1].Seq {
+			t.Errorf("Sequence numbers are not increasing: %d, %d", changes[i-1].Seq, changes[i].Seq)
+		}
+	}
+
+	// Открытие и закрытие календаря показываются как появление и удаление его событий
+	work, _ := calendar.CreateCalendar(UserCalendar{OwnerID: "user1", Name: "Work"})
+	calendar.CreateEvent(Event{UserID: "user1", CalendarID: work.ID, Title: "Standup", Date: "2024-03-11"})
+	mark := calendar.changes.last()
+	calendar.ShareCalendar("user1", work.ID, "user3", PermissionRead)
+	calendar.ShareCalendar("user1", work.ID, "user3", PermissionWrite)
+	calendar.UnshareCalendar("user1", work.ID, "user3")
+	changes, _, _, _, _ = calendar.changes.since("user3", mark)
+	if !equalStrings(changeTypes(changes), []string{ChangeCreated, ChangeDeleted}) {
+		t.Errorf("Unexpected changes on sharing: %v", changeTypes(changes))
+	}
+	if changes, _, _, _, _ := calendar.changes.since("user1", mark); len(changes) != 0 {
+		t.Errorf("Sharing should not produce changes for the owner, got %v", changeTypes(changes))
+	}
+
+	// Номера вытесненных изменений и номера из будущего (другого запуска) не принимаются
+	calendar.changes.limit = 2
+	for i := 0; i < 3; i++ {
+		calendar.CreateEvent(Event{UserID: "user1", Title: "Filler", Date: "2024-03-12"})
+	}
+	last := calendar.changes.last()
+	if _, _, _, _, err := calendar.changes.since("user1", last-3); !errors.Is(err, ErrChangesExpired) {
+		t.Errorf("Expected ErrChangesExpired for a trimmed change, got %v", err)
+	}
+	if changes, _, _, _, err := calendar.changes.since("user1", last-2); err != nil || len(changes) != 2 {
+		t.Errorf("Expected the two kept changes, got %d, %v", len(changes), err)
+	}
+	if _, _, _, _, err := calendar.changes.since("user1", last+1); !errors.Is(err, ErrChangesExpired) {
+		t.Errorf("Expected ErrChangesExpired for a future number, got %v", err)
+	}
+}
+
+func TestChangesStream(t *testing.T) {
+	ctx := context.Background()
+	calendar, _ := NewCalendar(nil)
+	server := httptest.NewServer(newRouter(calendar, NewAuthenticator(map[string]string{"user1-key": "user1"}, nil), nil))
+	defer server.Close()
+	c := client.New(server.URL, "user1-key", server.Client())
+
+	stream, err := c.WatchChanges(ctx, "user1", 0)
+	if err != nil {
+		t.Fatalf("WatchChanges: %v", err)
+	}
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Standup", Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "user2", Title: "Hidden", Date: "2024-03-10"})
+	calendar.UpdateEvent(Event{ID: id, UserID: "user1", Title: "Retro", Date: "2024-03-10"})
+
+	change, err := stream.Next()
+	if err != nil || change.Type != ChangeCreated || change.EventID != id || change.Event.Title != "Standup" {
+		t.Fatalf("Expected created change, got %+v, %v", change, err)
+	}
+	seen := stream.LastSeq
+	stream.Close()
+
+	// После переподключения приходят пропущенные изменения
+	calendar.DeleteUserEvent("user1", id)
+	stream, err = c.WatchChanges(ctx, "user1", seen)
+	if err != nil {
+		t.Fatalf("WatchChanges: %v", err)
+	}
+	for _, want := range []string{ChangeUpdated, ChangeDeleted} {
+		change, err := stream.Next()
+		if err != nil || change.Type != want || change.EventID != id {
+			t.Errorf("Expected %s change, got %+v, %v", want, change, err)
+		}
+	}
+	stream.Close()
+
+	// С устаревшего номера поток начинается с reset
+	stream, err = c.WatchChanges(ctx, "user1", 1)
+	if err != nil {
+		t.Fatalf("WatchChanges: %v", err)
+	}
+	change, err = stream.Next()
+	if err != nil || change.Type != client.ChangeReset || change.Seq != calendar.changes.last() {
+		t.Errorf("Expected reset, got %+v, %v", change, err)
+	}
+
+	// Остановка ленты завершает открытые потоки
+	calendar.StopChangeFeed()
+	if _, err := stream.Next(); err != io.EOF && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected the stream to end, got %v", err)
+	}
+	stream.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/users/user1/changes", nil)
+	req.Header.Set("Authorization", "Bearer user1-key")
+	req.Header.Set("Last-Event-ID", "abc")
+	resp, err := server.Client().Do(req)
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid Last-Event-ID, got %v, %v", resp, err)
+	}
+	resp.Body.Close()
+}
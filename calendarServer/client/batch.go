@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Операции пакета
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation — операция пакета (схема BatchOperation)
+type BatchOperation struct {
+	Op            string `json:"op"`
+	ID            int    `json:"id,omitempty"`      // событие для update и delete
+	Event         *Event `json:"event,omitempty"`   // содержимое события для create и update
+	Version       int    `json:"version,omitempty"` // ожидаемая версия для update и delete; 0 — любая
+	RejectOverlap bool   `json:"reject_overlap,omitempty"`
+}
+
+// BatchResult — результат операции пакета (схема BatchResult)
+type BatchResult struct {
+	Op     string `json:"op"`
+	ID     int    `json:"id,omitempty"`
+	Status int    `json:"status"` // 201, 200, 204; у неудавшегося пакета — ошибка операции или 424
+	Event  *Event `json:"event,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ApplyBatch атомарно выполняет операции по порядку: применяются либо все, либо ни одна.
+// Если пакет не применен, вместе с *APIError возвращаются результаты: у неудавшейся операции
+// в Error причина, остальные получают Status 424.
+func (c *Client) ApplyBatch(ctx context.Context, user string, ops []BatchOperation) ([]BatchResult, error) {
+	var results []BatchResult
+	body := map[string][]BatchOperation{"operations": ops}
+	_, err := c.call(ctx, http.MethodPost, "/api/v1/users/"+url.PathEscape(user)+"/batch", nil, nil, "application/json", body, &results)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && len(apiErr.data) > 0 {
+		// В data ошибки пакета — результаты операций, а не события-конфликты
+		apiErr.Conflicts = nil
+		json.Unmarshal(apiErr.data, &results)
+	}
+	return results, err
+}
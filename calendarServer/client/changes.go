@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeReset — тип служебного изменения: продолжить с запрошенного номера нельзя,
+// календарь нужно перечитать целиком
+const ChangeReset = "reset"
+
+// Change — изменение события из потока изменений (created, updated, deleted) или ChangeReset
+type Change struct {
+	Seq        int64     `json:"seq"`
+	Type       string    `json:"type"`
+	EventID    int       `json:"event_id"`
+	UserID     string    `json:"user_id"` // владелец события
+	CalendarID int       `json:"calendar_id,omitempty"`
+	Event      *Event    `json:"event,omitempty"` // новое состояние события; нет у deleted
+	Time       time.Time `json:"time"`
+}
+
+// ChangeStream — открытый поток изменений Server-Sent Events
+type ChangeStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	// LastSeq — номер последнего полученного изменения (или пинга); с него продолжают после обрыва
+	LastSeq int64
+}
+
+// WatchChanges открывает поток изменений событий, видимых пользователю. since — номер последнего
+// обработанного изменения; 0 — только изменения после подключения.
+// Поток закрывается через Close или отменой ctx.
+func (c *Client) WatchChanges(ctx context.Context, user string, since int64) (*ChangeStream, error) {
+	var query url.Values
+	if since > 0 {
+		query = url.Values{"since": {strconv.FormatInt(since, 10)}}
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/users/"+url.PathEscape(user)+"/changes", query, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ChangeStream{body: resp.Body, reader: bufio.NewReader(resp.Body), LastSeq: since}, nil
+}
+
+// Next ждет следующее изменение. Пинги сервера только обновляют LastSeq.
+func (s *ChangeStream) Next() (*Change, error) {
+	var kind string
+	var data strings.Builder
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			// Пустая строка завершает событие SSE
+			if data.Len() == 0 {
+				continue
+			}
+			var change Change
+			if err := json.Unmarshal([]byte(data.String()), &change); err != nil {
+				return nil, fmt.Errorf("calendar: invalid change: %w", err)
+			}
+			if kind == ChangeReset {
+				change.Type = ChangeReset
+			}
+			return &change, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			if seq, err := strconv.ParseInt(value, 10, 64); err == nil {
+				s.LastSeq = seq
+			}
+		case "event":
+			kind = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+}
+
+// Close закрывает поток
+func (s *ChangeStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,588 @@
+// Package client — типизированный Go-клиент сервера календаря.
+// Методы клиента соответствуют операциям openapi.json (operationId с заглавной буквы),
+// устаревшие RPC-маршруты не поддерживаются.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event — событие календаря (схема Event)
+type Event struct {
+	ID          int       `json:"id,omitempty"`
+	UID         string    `json:"uid,omitempty"`
+	UserID      string    `json:"user_id,omitempty"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Date        string    `json:"date,omitempty"`     // дата события на весь день, YYYY-MM-DD
+	EndDate     string    `json:"end_date,omitempty"` // последний день события на весь день (включительно)
+	Start       time.Time `json:"start,omitzero"`     // начало события со временем
+	End         time.Time `json:"end,omitzero"`       // окончание события со временем (не включительно)
+	AllDay      bool      `json:"all_day,omitempty"`
+	TimeZone    string    `json:"time_zone,omitempty"`
+
+	RRule        string     `json:"rrule,omitempty"`
+	ExDates      []string   `json:"exdates,omitempty"`
+	Overrides    []Override `json:"overrides,omitempty"`
+	RecurrenceID string     `json:"recurrence_id,omitempty"` // заполняет сервер у раскрытого экземпляра
+	Holidays     string     `json:"holidays,omitempty"`      // производственный календарь для OnHoliday
+	OnHoliday    string     `json:"on_holiday,omitempty"`    // HolidaySkip, HolidayNext или HolidayPrevious
+
+	Reminders     []string  `json:"reminders,omitempty"` // длительности Go, например "15m"
+	RemindedUntil time.Time `json:"reminded_until,omitzero"`
+
+	CalendarID int        `json:"calendar_id,omitempty"` // 0 — календарь владельца по умолчанию
+	Attendees  []Attendee `json:"attendees,omitempty"`
+
+	Version   int       `json:"version,omitempty"`   // версия, которую прочитал клиент; ведет сервер
+	DeletedAt time.Time `json:"deleted_at,omitzero"` // момент удаления события из корзины
+}
+
+// Attendee — участник события (схема Attendee)
+type Attendee struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status,omitempty"` // needs-action, accepted, declined или tentative
+}
+
+// Calendar — именованный календарь пользователя (схема Calendar)
+type Calendar struct {
+	ID         int     `json:"id,omitempty"`
+	OwnerID    string  `json:"owner_id,omitempty"`
+	Name       string  `json:"name"`
+	Color      string  `json:"color,omitempty"` // #RRGGBB
+	Shares     []Share `json:"shares,omitempty"`
+	Permission string  `json:"permission,omitempty"` // owner, write или read — права запросившего пользователя
+}
+
+// Share — доступ пользователя к календарю (схема Share)
+type Share struct {
+	UserID     string `json:"user_id"`
+	Permission string `json:"permission"` // read или write
+}
+
+// Override — измененный или отмененный экземпляр повторяющегося события (схема Override)
+type Override struct {
+	RecurrenceID string    `json:"recurrence_id"`
+	Title        string    `json:"title,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Date         string    `json:"date,omitempty"`
+	Start        time.Time `json:"start,omitzero"`
+	End          time.Time `json:"end,omitzero"`
+	Cancelled    bool      `json:"cancelled,omitempty"`
+}
+
+// Interval — полуинтервал времени [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeBusy — занятость пользователей и общие свободные слоты
+type FreeBusy struct {
+	Busy map[string][]Interval `json:"busy"`
+	Free []Interval            `json:"free"`
+}
+
+// SkippedItem — компонент iCalendar, который не удалось импортировать
+type SkippedItem struct {
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// ImportResult — итог импорта iCalendar
+type ImportResult struct {
+	Imported []int         `json:"imported"`
+	Skipped  []SkippedItem `json:"skipped"`
+}
+
+// ListOptions — параметры списка событий. Пустые поля не передаются.
+type ListOptions struct {
+	From     string // YYYY-MM-DD или RFC 3339, задается вместе с To
+	To       string // YYYY-MM-DD (включительно) или RFC 3339
+	TimeZone string // часовой пояс IANA
+	Query    string // подстрока заголовка или описания
+	Tags     []string
+	Limit    int
+	Cursor   string // курсор следующей страницы из EventPage.NextCursor
+	// WeekStart — первый день недели для GetView: monday ... sunday; пусто — из настроек пользователя
+	WeekStart string
+}
+
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("from", o.From)
+	set("to", o.To)
+	set("tz", o.TimeZone)
+	set("q", o.Query)
+	set("tags", strings.Join(o.Tags, ","))
+	set("cursor", o.Cursor)
+	set("week_start", o.WeekStart)
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return v
+}
+
+// EventPage — страница событий; NextCursor пуст на последней странице
+type EventPage struct {
+	Events     []Event
+	NextCursor string
+}
+
+// FreeBusyRequest — параметры запроса занятости
+type FreeBusyRequest struct {
+	Users    []string
+	From     string // YYYY-MM-DD или RFC 3339
+	To       string
+	TimeZone string
+	Duration time.Duration // длина свободного слота, 0 — по умолчанию сервера (30m)
+	Slots    int           // сколько свободных слотов вернуть, 0 — по умолчанию сервера (5)
+}
+
+// APIError — ответ сервера с кодом ошибки
+type APIError struct {
+	StatusCode int
+	Message    string
+	Conflicts  []Event       // события, с которыми пересекается сохраняемое (409 при reject_overlap)
+	RetryAfter time.Duration // через сколько можно повторить запрос (429 и заголовок Retry-After)
+
+	data json.RawMessage // data ответа с ошибкой
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("calendar: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound сообщает, что событие не найдено (или принадлежит другому пользователю)
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict сообщает о конфликте UID или пересечении по времени
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// IsPreconditionFailed сообщает, что событие изменилось после того, как его прочитали (412):
+// его нужно перечитать и повторить изменение
+func IsPreconditionFailed(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// IsRateLimited сообщает, что превышена частота запросов (429): запрос можно повторить через APIError.RetryAfter
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// Client — клиент сервера календаря
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// New создает клиент сервера baseURL (например http://localhost:8080).
+// token — API-ключ или JWT, пустой — без аутентификации; httpClient nil — http.DefaultClient.
+func New(baseURL, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), token: token, httpClient: httpClient}
+}
+
+// envelope — обертка ответов сервера (схема Response)
+type envelope struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+}
+
+// do выполняет запрос и возвращает ответ с кодом 2xx; остальные коды превращаются в *APIError
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, contentType string, body io.Reader) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var env envelope
+	if json.Unmarshal(data, &env) == nil && env.Error != "" {
+		apiErr.Message = env.Error
+		apiErr.data = env.Data
+		if resp.StatusCode == http.StatusConflict {
+			json.Unmarshal(env.Data, &apiErr.Conflicts)
+		}
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return nil, apiErr
+}
+
+// call выполняет JSON-запрос и разбирает data ответа в out (если out не nil)
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, header http.Header, contentType string, in, out any) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	resp, err := c.do(ctx, method, path, query, header, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return resp, nil
+	}
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return nil, fmt.Errorf("calendar: invalid response: %w", err)
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return nil, fmt.Errorf("calendar: invalid response data: %w", err)
+	}
+	return resp, nil
+}
+
+func eventsPath(user string) string {
+	return "/api/v1/users/" + url.PathEscape(user) + "/events"
+}
+
+func eventPath(user string, id int) string {
+	return eventsPath(user) + "/" + strconv.Itoa(id)
+}
+
+func calendarsPath(user string) string {
+	return "/api/v1/users/" + url.PathEscape(user) + "/calendars"
+}
+
+func calendarPath(user string, id int) string {
+	return calendarsPath(user) + "/" + strconv.Itoa(id)
+}
+
+// ifMatch возвращает заголовок If-Match для версии события; 0 — без проверки версии
+func ifMatch(version int) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+}
+
+func writeQuery(rejectOverlap bool) url.Values {
+	if !rejectOverlap {
+		return nil
+	}
+	return url.Values{"reject_overlap": {"true"}}
+}
+
+// ListEvents возвращает страницу событий пользователя
+func (c *Client) ListEvents(ctx context.Context, user string, opts ListOptions) (*EventPage, error) {
+	page := &EventPage{}
+	resp, err := c.call(ctx, http.MethodGet, eventsPath(user), opts.values(), nil, "", nil, &page.Events)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = resp.Header.Get("X-Next-Cursor")
+	return page, nil
+}
+
+// SearchEvents ищет события пользователя по словам query; Query в opts не используется
+func (c *Client) SearchEvents(ctx context.Context, user, query string, opts ListOptions) (*EventPage, error) {
+	values := opts.values()
+	values.Set("q", query)
+	page := &EventPage{}
+	resp, err := c.call(ctx, http.MethodGet, "/api/v1/users/"+url.PathEscape(user)+"/search", values, nil, "", nil, &page.Events)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = resp.Header.Get("X-Next-Cursor")
+	return page, nil
+}
+
+// GetEvent возвращает событие пользователя
+func (c *Client) GetEvent(ctx context.Context, user string, id int) (*Event, error) {
+	var event Event
+	if _, err := c.call(ctx, http.MethodGet, eventPath(user, id), nil, nil, "", nil, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// CreateEvent создает событие и возвращает его в том виде, в каком его сохранил сервер.
+// rejectOverlap запрещает пересечение с другими событиями пользователя (ошибка с кодом 409).
+func (c *Client) CreateEvent(ctx context.Context, user string, event Event, rejectOverlap bool) (*Event, error) {
+	var created Event
+	if _, err := c.call(ctx, http.MethodPost, eventsPath(user), writeQuery(rejectOverlap), nil, "application/json", event, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ReplaceEvent полностью заменяет событие. Если задана event.Version, событие заменяется, только пока
+// у него эта версия, иначе возвращается ошибка, для которой IsPreconditionFailed — true.
+func (c *Client) ReplaceEvent(ctx context.Context, user string, id int, event Event, rejectOverlap bool) (*Event, error) {
+	var updated Event
+	if _, err := c.call(ctx, http.MethodPut, eventPath(user, id), writeQuery(rejectOverlap), ifMatch(event.Version), "application/json", event, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// PatchEvent изменяет поля события по правилам JSON Merge Patch: значение nil удаляет поле.
+// version — ожидаемая версия события, 0 — любая.
+func (c *Client) PatchEvent(ctx context.Context, user string, id int, patch map[string]any, version int, rejectOverlap bool) (*Event, error) {
+	var updated Event
+	if _, err := c.call(ctx, http.MethodPatch, eventPath(user, id), writeQuery(rejectOverlap), ifMatch(version), "application/merge-patch+json", patch, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteEvent переносит событие в корзину, откуда его можно вернуть через RestoreEvent.
+// version — ожидаемая версия события, 0 — любая.
+func (c *Client) DeleteEvent(ctx context.Context, user string, id int, version int) error {
+	_, err := c.call(ctx, http.MethodDelete, eventPath(user, id), nil, ifMatch(version), "", nil, nil)
+	return err
+}
+
+// RestoreEvent возвращает событие из корзины
+func (c *Client) RestoreEvent(ctx context.Context, user string, id int) (*Event, error) {
+	var event Event
+	if _, err := c.call(ctx, http.MethodPost, eventPath(user, id)+"/restore", nil, nil, "", nil, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ListDeletedEvents возвращает страницу событий из корзины, которые пользователь может восстановить
+func (c *Client) ListDeletedEvents(ctx context.Context, user string, opts ListOptions) (*EventPage, error) {
+	page := &EventPage{}
+	resp, err := c.call(ctx, http.MethodGet, "/api/v1/users/"+url.PathEscape(user)+"/trash", opts.values(), nil, "", nil, &page.Events)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = resp.Header.Get("X-Next-Cursor")
+	return page, nil
+}
+
+// RespondToEvent сохраняет ответ пользователя на приглашение: accepted, declined, tentative или needs-action
+func (c *Client) RespondToEvent(ctx context.Context, user string, id int, status string) (*Event, error) {
+	var event Event
+	body := map[string]string{"status": status}
+	if _, err := c.call(ctx, http.MethodPut, eventPath(user, id)+"/rsvp", nil, nil, "application/json", body, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ListCalendars возвращает календари пользователя и календари, которыми с ним поделились
+func (c *Client) ListCalendars(ctx context.Context, user string) ([]Calendar, error) {
+	var calendars []Calendar
+	if _, err := c.call(ctx, http.MethodGet, calendarsPath(user), nil, nil, "", nil, &calendars); err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// GetCalendar возвращает календарь, доступный пользователю
+func (c *Client) GetCalendar(ctx context.Context, user string, id int) (*Calendar, error) {
+	var cal Calendar
+	if _, err := c.call(ctx, http.MethodGet, calendarPath(user, id), nil, nil, "", nil, &cal); err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
+
+// CreateCalendar создает календарь с именем и цветом из cal
+func (c *Client) CreateCalendar(ctx context.Context, user string, cal Calendar) (*Calendar, error) {
+	var created Calendar
+	if _, err := c.call(ctx, http.MethodPost, calendarsPath(user), nil, nil, "application/json", cal, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateCalendar меняет имя и цвет календаря
+func (c *Client) UpdateCalendar(ctx context.Context, user string, id int, cal Calendar) (*Calendar, error) {
+	var updated Calendar
+	if _, err := c.call(ctx, http.MethodPut, calendarPath(user, id), nil, nil, "application/json", cal, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteCalendar удаляет календарь вместе с его событиями
+func (c *Client) DeleteCalendar(ctx context.Context, user string, id int) error {
+	_, err := c.call(ctx, http.MethodDelete, calendarPath(user, id), nil, nil, "", nil, nil)
+	return err
+}
+
+// ShareCalendar открывает календарь пользователю grantee с правами read или write
+func (c *Client) ShareCalendar(ctx context.Context, user string, id int, grantee, permission string) (*Calendar, error) {
+	var cal Calendar
+	body := map[string]string{"permission": permission}
+	if _, err := c.call(ctx, http.MethodPut, calendarPath(user, id)+"/shares/"+url.PathEscape(grantee), nil, nil, "application/json", body, &cal); err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
+
+// UnshareCalendar закрывает доступ пользователя grantee к календарю
+func (c *Client) UnshareCalendar(ctx context.Context, user string, id int, grantee string) (*Calendar, error) {
+	var cal Calendar
+	if _, err := c.call(ctx, http.MethodDelete, calendarPath(user, id)+"/shares/"+url.PathEscape(grantee), nil, nil, "", nil, &cal); err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
+
+// GetFreeBusy возвращает занятость пользователей и общие свободные слоты
+func (c *Client) GetFreeBusy(ctx context.Context, req FreeBusyRequest) (*FreeBusy, error) {
+	values := url.Values{
+		"users": {strings.Join(req.Users, ",")},
+		"from":  {req.From},
+		"to":    {req.To},
+	}
+	if req.TimeZone != "" {
+		values.Set("tz", req.TimeZone)
+	}
+	if req.Duration > 0 {
+		values.Set("duration", req.Duration.String())
+	}
+	if req.Slots > 0 {
+		values.Set("slots", strconv.Itoa(req.Slots))
+	}
+	var fb FreeBusy
+	if _, err := c.call(ctx, http.MethodGet, "/api/v1/freebusy", values, nil, "", nil, &fb); err != nil {
+		return nil, err
+	}
+	return &fb, nil
+}
+
+// ExportICS выгружает события пользователя в iCalendar; from и to (YYYY-MM-DD) необязательны
+func (c *Client) ExportICS(ctx context.Context, user, from, to string) ([]byte, error) {
+	values := url.Values{"user_id": {user}}
+	if from != "" || to != "" {
+		values.Set("from", from)
+		values.Set("to", to)
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/export_ics", values, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// ImportICS импортирует события пользователя из iCalendar
+func (c *Client) ImportICS(ctx context.Context, user string, ics io.Reader) (*ImportResult, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "calendar.ics")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, ics); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/import_ics", url.Values{"user_id": {user}}, nil, form.FormDataContentType(), &body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var env envelope
+	var result ImportResult
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return nil, fmt.Errorf("calendar: invalid response: %w", err)
+	}
+	if err := json.Unmarshal(env.Data, &result); err != nil {
+		return nil, fmt.Errorf("calendar: invalid response data: %w", err)
+	}
+	return &result, nil
+}
+
+// Health проверяет, что сервер запущен
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.call(ctx, http.MethodGet, "/healthz", nil, nil, "", nil, nil)
+	return err
+}
+
+// Ready проверяет, что сервер готов обрабатывать запросы (хранилище доступно)
+func (c *Client) Ready(ctx context.Context) error {
+	_, err := c.call(ctx, http.MethodGet, "/readyz", nil, nil, "", nil, nil)
+	return err
+}
+
+// Metrics возвращает метрики сервера в текстовом формате Prometheus
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/metrics", nil, nil, "", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// OpenAPI возвращает OpenAPI-документ сервера
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/openapi.json", nil, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
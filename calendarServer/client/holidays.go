@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Что делать с экземпляром повторяющегося события, выпавшим на нерабочий день (Event.OnHoliday)
+const (
+	HolidaySkip     = "skip"
+	HolidayNext     = "next"
+	HolidayPrevious = "previous"
+)
+
+// WorkingDay — день производственного календаря (схема WorkingDay)
+type WorkingDay struct {
+	Calendar string `json:"calendar"`
+	Date     string `json:"date"`
+	Working  bool   `json:"working"`
+	Weekend  bool   `json:"weekend"`
+	Holiday  string `json:"holiday,omitempty"` // название праздника
+}
+
+// GetWorkingDay сообщает, рабочий ли день date (YYYY-MM-DD) в производственном календаре holidays
+func (c *Client) GetWorkingDay(ctx context.Context, holidays, date string) (*WorkingDay, error) {
+	var day WorkingDay
+	path := "/api/v1/holidays/" + url.PathEscape(holidays) + "/days/" + url.PathEscape(date)
+	if _, err := c.call(ctx, http.MethodGet, path, nil, nil, "", nil, &day); err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
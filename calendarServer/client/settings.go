@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Settings — настройки пользователя (схема Settings)
+type Settings struct {
+	UserID string `json:"user_id,omitempty"`
+	// WeekStart — первый день недели: monday ... sunday; в ответе сервера заполнен всегда
+	WeekStart string `json:"week_start,omitempty"`
+	Locale    string `json:"locale,omitempty"` // тег BCP 47, например en-US
+}
+
+func settingsPath(user string) string {
+	return "/api/v1/users/" + url.PathEscape(user) + "/settings"
+}
+
+// GetSettings возвращает настройки пользователя
+func (c *Client) GetSettings(ctx context.Context, user string) (*Settings, error) {
+	var settings Settings
+	if _, err := c.call(ctx, http.MethodGet, settingsPath(user), nil, nil, "", nil, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateSettings заменяет настройки пользователя
+func (c *Client) UpdateSettings(ctx context.Context, user string, settings Settings) (*Settings, error) {
+	var updated Settings
+	if _, err := c.call(ctx, http.MethodPut, settingsPath(user), nil, nil, "application/json", settings, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Представления календаря для GetView
+const (
+	ViewDay   = "day"
+	ViewWeek  = "week"
+	ViewMonth = "month"
+	ViewYear  = "year"
+)
+
+// GetView возвращает события дня, недели, месяца или года, в которые попадает date (YYYY-MM-DD).
+// From и To в opts не используются.
+func (c *Client) GetView(ctx context.Context, user, view, date string, opts ListOptions) (*EventPage, error) {
+	values := opts.values()
+	values.Del("from")
+	values.Del("to")
+	values.Set("date", date)
+	return c.eventPage(ctx, "/api/v1/users/"+url.PathEscape(user)+"/views/"+url.PathEscape(view), values)
+}
+
+// GetAgenda возвращает ближайшие события, которые не закончились к opts.From (по умолчанию — к текущему моменту).
+// To в opts не используется; для следующей страницы передайте тот же From вместе с курсором.
+func (c *Client) GetAgenda(ctx context.Context, user string, opts ListOptions) (*EventPage, error) {
+	values := opts.values()
+	values.Del("to")
+	return c.eventPage(ctx, "/api/v1/users/"+url.PathEscape(user)+"/agenda", values)
+}
+
+// eventPage запрашивает страницу событий по пути path
+func (c *Client) eventPage(ctx context.Context, path string, query url.Values) (*EventPage, error) {
+	page := &EventPage{}
+	resp, err := c.call(ctx, http.MethodGet, path, query, nil, "", nil, &page.Events)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = resp.Header.Get("X-Next-Cursor")
+	return page, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"WBTechL2/calendarServer/client"
+)
+
+// newTestClient запускает сервер календаря и возвращает клиент для пользователя user1.
+// Все JSON-ответы сервера сверяются с openapi.json.
+func newTestClient(t *testing.T) (*client.Client, *Calendar) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(map[string]string{"user1-key": "user1"}, nil), nil)
+	validateResponses(t, router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return client.New(server.URL, "user1-key", server.Client()), calendar
+}
+
+func TestClientCoversOpenAPI(t *testing.T) {
+	clientType := reflect.TypeOf(&client.Client{})
+	for path, methods := range apiSpecification.Paths {
+		for method, op := range methods {
+			if op.Deprecated {
+				continue
+			}
+			name := []rune(op.OperationID)
+			name[0] = unicode.ToUpper(name[0])
+			if _, ok := clientType.MethodByName(string(name)); !ok {
+				t.Errorf("client has no method %s for %s %s", string(name), strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	// Поля событий клиента совпадают с полями сервера
+	fields := func(v any) map[string]bool {
+		names := make(map[string]bool)
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			if typ.Field(i).IsExported() {
+				names[strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]] = true
+			}
+		}
+		return names
+	}
+	if server, cl := fields(Event{}), fields(client.Event{}); !reflect.DeepEqual(server, cl) {
+		t.Errorf("client.Event fields %v differ from Event fields %v", cl, server)
+	}
+	if server, cl := fields(Override{}), fields(client.Override{}); !reflect.DeepEqual(server, cl) {
+		t.Errorf("client.Override fields %v differ from Override fields %v", cl, server)
+	}
+	if server, cl := fields(UserCalendar{}), fields(client.Calendar{}); !reflect.DeepEqual(server, cl) {
+		t.Errorf("client.Calendar fields %v differ from UserCalendar fields %v", cl, server)
+	}
+	if server, cl := fields(Change{}), fields(client.Change{}); !reflect.DeepEqual(server, cl) {
+		t.Errorf("client.Change fields %v differ from Change fields %v", cl, server)
+	}
+	if server, cl := fields(BatchOperation{}), fields(client.BatchOperation{}); !reflect.DeepEqual(server, cl) {
+		t.Errorf("client.BatchOperation fields %v differ from BatchOperation fields %v", cl, server)
+	}
+	if server, cl := fields(BatchResult{}), fields(client.BatchResult{}); !reflect.DeepEqual(server, cl) {
+		t.Errorf("client.BatchResult fields %v differ from BatchResult fields %v", cl, server)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	created, err := c.CreateEvent(ctx, "user1", client.Event{
+		Title:     "Standup",
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+		Tags:      []string{"Work"},
+		Reminders: []string{"15m"},
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	if created.ID == 0 || created.UserID != "user1" || !equalStrings(created.Tags, []string{"work"}) {
+		t.Errorf("Unexpected created event %+v", created)
+	}
+
+	got, err := c.GetEvent(ctx, "user1", created.ID)
+	if err != nil || !got.Start.Equal(start) || got.Reminders[0] != "15m0s" {
+		t.Errorf("GetEvent: %+v, %v", got, err)
+	}
+
+	// Пересечение с reject_overlap возвращает конфликтующие события
+	_, err = c.CreateEvent(ctx, "user1", client.Event{Title: "Clash", Start: start.Add(10 * time.Minute), End: start.Add(time.Hour)}, true)
+	var apiErr *client.APIError
+	if !client.IsConflict(err) || !errors.As(err, &apiErr) || len(apiErr.Conflicts) != 1 || apiErr.Conflicts[0].ID != created.ID {
+		t.Errorf("Expected conflict with event %d, got %v", created.ID, err)
+	}
+
+	patched, err := c.PatchEvent(ctx, "user1", created.ID, map[string]any{"description": "Daily sync", "tags": nil}, 0, false)
+	if err != nil || patched.Description != "Daily sync" || len(patched.Tags) != 0 {
+		t.Errorf("PatchEvent: %+v, %v", patched, err)
+	}
+	replaced, err := c.ReplaceEvent(ctx, "user1", created.ID, client.Event{Title: "Planning", Date: "2024-03-11"}, false)
+	if err != nil || replaced.Title != "Planning" || !replaced.AllDay {
+		t.Errorf("ReplaceEvent: %+v, %v", replaced, err)
+	}
+
+	for i := 0; i < 4; i++ {
+		c.CreateEvent(ctx, "user1", client.Event{Title: "Review", Date: "2024-03-12"}, false)
+	}
+	var titles []string
+	opts := client.ListOptions{From: "2024-03-01", To: "2024-03-31", Limit: 2}
+	for pages := 0; ; pages++ {
+		page, err := c.ListEvents(ctx, "user1", opts)
+		if err != nil {
+			t.Fatalf("ListEvents: %v", err)
+		}
+		for _, event := range page.Events {
+			titles = append(titles, event.Title)
+		}
+		if page.NextCursor == "" {
+			if pages != 2 {
+				t.Errorf("Expected 3 pages, got %d", pages+1)
+			}
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if !equalStrings(titles, []string{"Planning", "Review", "Review", "Review", "Review"}) {
+		t.Errorf("Unexpected events %v", titles)
+	}
+
+	found, err := c.SearchEvents(ctx, "user1", "planning", client.ListOptions{})
+	if err != nil || len(found.Events) != 1 || found.Events[0].ID != created.ID {
+		t.Errorf("SearchEvents: %+v, %v", found, err)
+	}
+
+	if err := c.DeleteEvent(ctx, "user1", created.ID, 0); err != nil {
+		t.Errorf("DeleteEvent: %v", err)
+	}
+	if _, err := c.GetEvent(ctx, "user1", created.ID); !client.IsNotFound(err) {
+		t.Errorf("Expected not found after delete, got %v", err)
+	}
+
+	// Пользователь в пути должен совпадать с токеном
+	_, err = c.GetEvent(ctx, "user2", 1)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for another user, got %v", err)
+	}
+	_, err = c.CreateEvent(ctx, "user1", client.Event{Title: "No date"}, false)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Message != "date or start is required" {
+		t.Errorf("Expected 422, got %v", err)
+	}
+}
+
+func TestClientServiceEndpoints(t *testing.T) {
+	ctx := context.Background()
+	c, calendar := newTestClient(t)
+	calendar.CreateEvent(Event{UserID: "user2", Title: "Busy", Start: time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 10, 17, 0, 0, 0, time.UTC)})
+
+	fb, err := c.GetFreeBusy(ctx, client.FreeBusyRequest{Users: []string{"user1", "user2"}, From: "2024-03-10", To: "2024-03-10", Duration: time.Hour, Slots: 1})
+	if err != nil || len(fb.Busy["user2"]) != 1 || len(fb.Free) != 1 || fb.Free[0].Start.Hour() != 0 {
+		t.Errorf("GetFreeBusy: %+v, %v", fb, err)
+	}
+
+	ics := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:imported@example.com\r\nDTSTART;VALUE=DATE:20240315\r\nSUMMARY:Imported\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	result, err := c.ImportICS(ctx, "user1", strings.NewReader(ics))
+	if err != nil || len(result.Imported) != 1 {
+		t.Fatalf("ImportICS: %+v, %v", result, err)
+	}
+	exported, err := c.ExportICS(ctx, "user1", "2024-03-01", "2024-03-31")
+	if err != nil || !strings.Contains(string(exported), "UID:imported@example.com") {
+		t.Errorf("ExportICS: %v\n%s", err, exported)
+	}
+
+	if err := c.Health(ctx); err != nil {
+		t.Errorf("Health: %v", err)
+	}
+	if err := c.Ready(ctx); err != nil {
+		t.Errorf("Ready: %v", err)
+	}
+	if metrics, err := c.Metrics(ctx); err != nil || !strings.Contains(metrics, "calendar_events_stored 2") {
+		t.Errorf("Metrics: %v\n%s", err, metrics)
+	}
+	doc, err := c.OpenAPI(ctx)
+	var parsed map[string]any
+	if err != nil || json.Unmarshal(doc, &parsed) != nil {
+		t.Errorf("OpenAPI: %v", err)
+	}
+}
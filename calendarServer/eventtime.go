@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// dateLayout — формат дат в API и поле Event.Date
+const dateLayout = "2006-01-02"
+
+// normalizeEvent проверяет временные поля события и приводит их к каноническому виду.
+//
+// Событие без start — событие на весь день: оно занимает дни с date по end_date включительно
+// и не привязано к часовому поясу («плавающие» даты, как VALUE=DATE в iCalendar).
+// Событие со start — событие со временем: end по умолчанию равен start,
+// а date заполняется датой начала в часовом поясе события для совместимости со старыми клиентами.
+func normalizeEvent(event Event) (Event, error) {
+	if err := validateReminders(event.Reminders); err != nil {
+		return event, err
+	}
+	tags, err := normalizeTags(event.Tags)
+	if err != nil {
+		return event, err
+	}
+	event.Tags = tags
+	if event.Attendees, err = normalizeAttendees(event.Attendees); err != nil {
+		return event, err
+	}
+	if event.TimeZone != "" {
+		loc, err := time.LoadLocation(event.TimeZone)
+		if err != nil {
+			return event, fmt.Errorf("unknown time zone %q", event.TimeZone)
+		}
+		event.Start = event.Start.In(loc)
+		event.End = event.End.In(loc)
+	}
+
+	if event.Start.IsZero() {
+		if !event.End.IsZero() {
+			return event, errors.New("start is required when end is set")
+		}
+		if event.Date == "" {
+			return event, errors.New("date or start is required")
+		}
+		if _, err := time.Parse(dateLayout, event.Date); err != nil {
+			return event, errors.New("date must be in format YYYY-MM-DD")
+		}
+		if event.EndDate != "" {
+			if _, err := time.Parse(dateLayout, event.EndDate); err != nil {
+				return event, errors.New("end_date must be in format YYYY-MM-DD")
+			}
+			if event.EndDate < event.Date {
+				return event, errors.New("end_date must not be before date")
+			}
+		}
+		event.AllDay = true
+		return normalizeRecurrence(event)
+	}
+
+	if event.AllDay {
+		return event, errors.New("all-day events are set by date, not start")
+	}
+	if event.End.IsZero() {
+		event.End = event.Start
+	}
+	if event.End.Before(event.Start) {
+		return event, errors.New("end must not be before start")
+	}
+	if event.Date != "" {
+		if _, err := time.Parse(dateLayout, event.Date); err != nil {
+			return event, errors.New("date must be in format YYYY-MM-DD")
+		}
+	}
+	event.Date = event.Start.Format(dateLayout)
+	event.EndDate = ""
+	return normalizeRecurrence(event)
+}
+
+// localize восстанавливает часовой пояс события после загрузки из JSON,
+// где время хранится только со смещением
+func (e *Event) localize() {
+	if e.TimeZone == "" {
+		return
+	}
+	if loc, err := time.LoadLocation(e.TimeZone); err == nil {
+		e.Start = e.Start.In(loc)
+		e.End = e.End.In(loc)
+	}
+}
+
+// interval возвращает полуинтервал [start, end), который событие занимает при просмотре в поясе loc.
+// События на весь день занимают целые сутки в поясе loc.
+func (e *Event) interval(loc *time.Location) (time.Time, time.Time) {
+	if !e.AllDay {
+		return e.Start, e.End
+	}
+	first, _ := time.ParseInLocation(dateLayout, e.Date, loc)
+	last := first
+	if e.EndDate != "" {
+		last, _ = time.ParseInLocation(dateLayout, e.EndDate, loc)
+	}
+	return first, last.AddDate(0, 0, 1)
+}
+
+// overlaps проверяет, пересекается ли событие с полуинтервалом [from, to).
+// Событие нулевой длительности попадает в интервал, если его начало лежит внутри.
+func (e *Event) overlaps(from, to time.Time) bool {
+	start, end := e.interval(from.Location())
+	if start.Equal(end) {
+		return !start.Before(from) && start.Before(to)
+	}
+	return start.Before(to) && end.After(from)
+}
+
+// DayRange возвращает границы суток date в поясе loc
+func DayRange(date string, loc *time.Location) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid date format")
+	}
+	return day, day.AddDate(0, 0, 1), nil
+}
+
+// parseRangeBound разбирает границу диапазона: RFC 3339 или дата YYYY-MM-DD в поясе loc.
+// Дата в конце диапазона включается целиком.
+func parseRangeBound(value string, loc *time.Location, end bool) (time.Time, error) {
+	if len(value) == len(dateLayout) {
+		dayStart, dayEnd, err := DayRange(value, loc)
+		if end {
+			return dayEnd, err
+		}
+		return dayStart, err
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, use YYYY-MM-DD or RFC 3339", value)
+	}
+	return t.In(loc), nil
+}
+
+// WeekRange возвращает календарную неделю, в которую попадает date, в поясе loc.
+// Неделя начинается с дня weekStart: понедельника по ISO 8601 или другого дня, принятого у пользователя.
+func WeekRange(date string, loc *time.Location, weekStart time.Weekday) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid date format")
+	}
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	first := day.AddDate(0, 0, -offset)
+	return first, first.AddDate(0, 0, 7), nil
+}
+
+// MonthRange возвращает календарный месяц, в который попадает date, в поясе loc
+func MonthRange(date string, loc *time.Location) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid date format")
+	}
+	firstOfMonth := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, loc)
+	return firstOfMonth, firstOfMonth.AddDate(0, 1, 0), nil
+}
+
+// YearRange возвращает календарный год, в который попадает date, в поясе loc
+func YearRange(date string, loc *time.Location) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid date format")
+	}
+	firstOfYear := time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, loc)
+	return firstOfYear, firstOfYear.AddDate(1, 0, 0), nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestNormalizeEvent(t *testing.T) {
+	start := time.Date(2024, 3, 10, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		event   Event
+		wantErr string
+	}{
+		{name: "legacy date", event: Event{Date: "2024-03-10"}},
+		{name: "multi-day", event: Event{Date: "2024-03-10", EndDate: "2024-03-12"}},
+		{name: "timed", event: Event{Start: start, End: start.Add(90 * time.Minute)}},
+		{name: "timed with zone", event: Event{Start: start, TimeZone: "Europe/Moscow"}},
+		{name: "nothing", event: Event{}, wantErr: "date or start is required"},
+		{name: "bad date", event: Event{Date: "10.03.2024"}, wantErr: "date must be in format YYYY-MM-DD"},
+		{name: "end date before date", event: Event{Date: "2024-03-10", EndDate: "2024-03-09"}, wantErr: "end_date must not be before date"},
+		{name: "end without start", event: Event{Date: "2024-03-10", End: start}, wantErr: "start is required when end is set"},
+		{name: "end before start", event: Event{Start: start, End: start.Add(-time.Minute)}, wantErr: "end must not be before start"},
+		{name: "unknown zone", event: Event{Start: start, TimeZone: "Mars/Olympus"}, wantErr: `unknown time zone "Mars/Olympus"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := normalizeEvent(tt.event)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("Expected error '%s', got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeTimedEventFillsDate(t *testing.T) {
+	mustLoadLocation(t, "Europe/Moscow")
+	// 22:30 UTC — это уже следующие сутки в Москве
+	start := time.Date(2024, 3, 10, 22, 30, 0, 0, time.UTC)
+
+	event, err := normalizeEvent(Event{Start: start, TimeZone: "Europe/Moscow"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Date != "2024-03-11" {
+		t.Errorf("Expected date 2024-03-11, got %s", event.Date)
+	}
+	if !event.End.Equal(start) {
+		t.Errorf("Expected end to default to start, got %s", event.End)
+	}
+	if event.Start.Location().String() != "Europe/Moscow" {
+		t.Errorf("Expected start in Europe/Moscow, got %s", event.Start.Location())
+	}
+	if event.AllDay {
+		t.Error("Timed event must not be all-day")
+	}
+}
+
+func TestWeekRange(t *testing.T) {
+	tests := []struct {
+		date      string
+		weekStart time.Weekday
+		want      string
+	}{
+		{date: "2024-03-04", weekStart: time.Monday, want: "2024-03-04"},
+		{date: "2024-03-10", weekStart: time.Monday, want: "2024-03-04"},
+		{date: "2024-03-10", weekStart: time.Sunday, want: "2024-03-10"},
+		{date: "2024-03-09", weekStart: time.Sunday, want: "2024-03-03"},
+		{date: "2024-01-03", weekStart: time.Saturday, want: "2023-12-30"},
+	}
+	for _, tt := range tests {
+		from, to, err := WeekRange(tt.date, time.UTC, tt.weekStart)
+		if err != nil || from.Format(dateLayout) != tt.want || to.Sub(from) != 7*24*time.Hour {
+			t.Errorf("WeekRange(%s, %s) = %v, %v, %v; want week from %s", tt.date, tt.weekStart, from, to, err, tt.want)
+		}
+	}
+
+	// Неделя, через которую переводятся часы, короче семи суток, но начинается в полночь
+	newYork := mustLoadLocation(t, "America/New_York")
+	from, to, _ := WeekRange("2024-03-06", newYork, time.Monday)
+	if !from.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, newYork)) || !to.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, newYork)) || to.Sub(from) != 167*time.Hour {
+		t.Errorf("Unexpected week across the DST change: %v - %v", from, to)
+	}
+
+	from, to, err := YearRange("2024-07-15", time.UTC)
+	if err != nil || !from.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected year range: %v - %v, %v", from, to, err)
+	}
+	if _, _, err := YearRange("2024-13-01", time.UTC); err == nil {
+		t.Error("Expected error for an invalid date")
+	}
+}
+
+func TestGetEventsInRangeTimeZones(t *testing.T) {
+	moscow := mustLoadLocation(t, "Europe/Moscow")
+	calendar, _ := NewCalendar(nil)
+
+	meetingStart := time.Date(2024, 3, 10, 22, 30, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Late meeting", Start: meetingStart, End: meetingStart.Add(time.Hour)})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Holiday", Date: "2024-03-11"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Trip", Date: "2024-03-09", EndDate: "2024-03-12"})
+
+	count := func(date string, loc *time.Location) int {
+		from, to, err := DayRange(date, loc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		events, _ := calendar.GetEventsInRange("user1", from, to)
+		return len(events)
+	}
+
+	// В UTC встреча 10 марта, в Москве — 11 марта
+	if got := count("2024-03-10", time.UTC); got != 2 {
+		t.Errorf("Expected 2 events on 2024-03-10 UTC, got %d", got)
+	}
+	if got := count("2024-03-10", moscow); got != 1 {
+		t.Errorf("Expected 1 event on 2024-03-10 in Moscow, got %d", got)
+	}
+	// События на весь день не зависят от пояса
+	if got := count("2024-03-11", moscow); got != 3 {
+		t.Errorf("Expected 3 events on 2024-03-11 in Moscow, got %d", got)
+	}
+	if got := count("2024-03-13", moscow); got != 0 {
+		t.Errorf("Expected no events on 2024-03-13, got %d", got)
+	}
+}
+
+func TestEventJSONCompatibility(t *testing.T) {
+	var legacy Event
+	if err := json.Unmarshal([]byte(`{"user_id":"user1","title":"Old client","date":"2024-03-10"}`), &legacy); err != nil {
+		t.Fatalf("Failed to decode legacy event: %v", err)
+	}
+	if err := ValidateEvent(legacy); err != nil {
+		t.Fatalf("Expected legacy event to be valid, got %v", err)
+	}
+
+	var timed Event
+	body := `{"user_id":"user1","title":"Meeting","start":"2024-03-10T14:00:00+03:00","end":"2024-03-10T15:30:00+03:00","time_zone":"Europe/Moscow"}`
+	if err := json.Unmarshal([]byte(body), &timed); err != nil {
+		t.Fatalf("Failed to decode timed event: %v", err)
+	}
+	timed, err := normalizeEvent(timed)
+	if err != nil {
+		t.Fatalf("Expected timed event to be valid, got %v", err)
+	}
+	if timed.End.Sub(timed.Start) != 90*time.Minute {
+		t.Errorf("Expected 90 minute duration, got %s", timed.End.Sub(timed.Start))
+	}
+	if timed.Date != "2024-03-10" {
+		t.Errorf("Expected derived date 2024-03-10, got %s", timed.Date)
+	}
+}
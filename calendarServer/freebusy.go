@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// overlapHorizon — насколько вперед проверяются на пересечения экземпляры повторяющегося события
+	overlapHorizon = 366 * 24 * time.Hour
+	// maxFreeBusyUsers и maxFreeBusyRange ограничивают запрос свободного времени
+	maxFreeBusyUsers = 50
+	maxFreeBusyRange = 366 * 24 * time.Hour
+	maxFreeSlots     = 100
+)
+
+// Interval — полуинтервал времени [start, end)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// OverlapError сообщает, с какими событиями (экземплярами) пересекается сохраняемое событие
+type OverlapError struct {
+	Conflicts []Event
+}
+
+func (e *OverlapError) Error() string {
+	return ErrOverlap.Error()
+}
+
+func (e *OverlapError) Unwrap() error {
+	return ErrOverlap
+}
+
+// blocksTime сообщает, занимает ли событие время.
+// События на весь день (праздники, дни рождения) и события без длительности время не занимают.
+func (e *Event) blocksTime() bool {
+	return !e.AllDay && e.End.After(e.Start)
+}
+
+// conflicts возвращает экземпляры других событий пользователя, пересекающиеся с event.
+// Повторяющееся событие проверяется на overlapHorizon вперед от первого экземпляра. Вызывается под блокировкой.
+func (c *Calendar) conflicts(event *Event) []Event {
+	if !event.blocksTime() {
+		return nil
+	}
+	occurrences := []Event{*event}
+	if event.IsRecurring() {
+		occurrences = event.occurrencesInRange(event.Start, event.Start.Add(overlapHorizon))
+	}
+	if len(occurrences) == 0 {
+		return nil
+	}
+
+	from, to := occurrences[0].Start, occurrences[0].End
+	for _, occ := range occurrences[1:] {
+		if occ.Start.Before(from) {
+			from = occ.Start
+		}
+		if occ.End.After(to) {
+			to = occ.End
+		}
+	}
+
+	// eventsInRange возвращает события по возрастанию начала
+	var others []Event
+	for _, other := range c.eventsInRange(event.UserID, from, to) {
+		if (event.ID == 0 || other.ID != event.ID) && other.blocksTime() {
+			others = append(others, other)
+		}
+	}
+
+	var conflicts []Event
+	seen := make(map[string]bool)
+	for _, occ := range occurrences {
+		if !occ.blocksTime() {
+			continue
+		}
+		for _, other := range others {
+			if !other.Start.Before(occ.End) {
+				break
+			}
+			key := strconv.Itoa(other.ID) + "/" + other.RecurrenceID
+			if other.End.After(occ.Start) && !seen[key] {
+				seen[key] = true
+				conflicts = append(conflicts, other)
+			}
+		}
+	}
+	return conflicts
+}
+
+// FreeBusy возвращает для каждого пользователя объединенные занятые интервалы в [from, to), обрезанные по его границам
+func (c *Calendar) FreeBusy(userIDs []string, from, to time.Time) map[string][]Interval {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	busy := make(map[string][]Interval, len(userIDs))
+	for _, userID := range userIDs {
+		var intervals []Interval
+		for _, event := range c.eventsInRange(userID, from, to) {
+			if !event.blocksTime() {
+				continue
+			}
+			intervals = append(intervals, Interval{Start: maxTime(event.Start, from), End: minTime(event.End, to)})
+		}
+		busy[userID] = mergeIntervals(intervals)
+	}
+	return busy
+}
+
+// mergeIntervals сортирует интервалы и склеивает пересекающиеся и соприкасающиеся
+func mergeIntervals(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+	merged := make([]Interval, 0, len(intervals))
+	for _, iv := range intervals {
+		if n := len(merged); n > 0 && !iv.Start.After(merged[n-1].End) {
+			merged[n-1].End = maxTime(merged[n-1].End, iv.End)
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// FreeSlots возвращает до n первых свободных от busy слотов длины length в [from, to).
+// Слоты идут подряд от начала каждого свободного промежутка.
+func FreeSlots(busy []Interval, from, to time.Time, length time.Duration, n int) []Interval {
+	slots := make([]Interval, 0)
+	if length <= 0 {
+		return slots
+	}
+	cursor := from
+	fill := func(gapEnd time.Time) {
+		for len(slots) < n && !cursor.Add(length).After(gapEnd) {
+			slots = append(slots, Interval{Start: cursor, End: cursor.Add(length)})
+			cursor = cursor.Add(length)
+		}
+	}
+	for _, iv := range mergeIntervals(busy) {
+		if len(slots) == n {
+			return slots
+		}
+		fill(minTime(iv.Start, to))
+		cursor = maxTime(cursor, iv.End)
+	}
+	fill(to)
+	return slots
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// FreeBusyResponse — занятость пользователей и общие свободные слоты
+type FreeBusyResponse struct {
+	Busy map[string][]Interval `json:"busy"`
+	Free []Interval            `json:"free"`
+}
+
+// freeBusyHandler обрабатывает GET /api/v1/freebusy?users=a,b&from=...&to=...&duration=30m&slots=5.
+// В ответе только интервалы без названий событий, поэтому занятость можно запрашивать и для других пользователей.
+func freeBusyHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var users []string
+		for _, user := range strings.Split(query.Get("users"), ",") {
+			if user = strings.TrimSpace(user); user != "" {
+				users = append(users, user)
+			}
+		}
+		if len(users) == 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "users is required")
+			return
+		}
+		if len(users) > maxFreeBusyUsers {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("at most %d users are allowed", maxFreeBusyUsers))
+			return
+		}
+
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if query.Get("from") == "" || query.Get("to") == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "from and to are required")
+			return
+		}
+		from, err := parseRangeBound(query.Get("from"), loc, false)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		to, err := parseRangeBound(query.Get("to"), loc, true)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !to.After(from) {
+			writeErrorResponse(w, http.StatusBadRequest, "to must be after from")
+			return
+		}
+		if to.Sub(from) > maxFreeBusyRange {
+			writeErrorResponse(w, http.StatusBadRequest, "range must not exceed 366 days")
+			return
+		}
+
+		length := 30 * time.Minute
+		if value := query.Get("duration"); value != "" {
+			if length, err = time.ParseDuration(value); err != nil || length <= 0 {
+				writeErrorResponse(w, http.StatusBadRequest, "duration must be a positive duration like 30m")
+				return
+			}
+		}
+		slots := 5
+		if value := query.Get("slots"); value != "" {
+			if slots, err = strconv.Atoi(value); err != nil || slots < 1 || slots > maxFreeSlots {
+				writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("slots must be between 1 and %d", maxFreeSlots))
+				return
+			}
+		}
+
+		busy := calendar.FreeBusy(users, from, to)
+		var all []Interval
+		for _, user := range users {
+			for i := range busy[user] {
+				busy[user][i] = Interval{Start: busy[user][i].Start.In(loc), End: busy[user][i].End.In(loc)}
+			}
+			all = append(all, busy[user]...)
+		}
+		free := FreeSlots(all, from, to, length, slots)
+		writeResponse(w, http.StatusOK, Response{Message: "Free/busy", Data: FreeBusyResponse{Busy: busy, Free: free}})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// at возвращает момент 10 марта 2024 года в UTC
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 3, 10, hour, minute, 0, 0, time.UTC)
+}
+
+func TestFreeSlots(t *testing.T) {
+	busy := []Interval{
+		{Start: at(10, 0), End: at(11, 0)},
+		{Start: at(9, 0), End: at(9, 30)},
+		{Start: at(10, 30), End: at(12, 0)}, // пересекается с 10:00–11:00
+		{Start: at(12, 0), End: at(12, 15)}, // соприкасается
+	}
+	merged := mergeIntervals(append([]Interval(nil), busy...))
+	if len(merged) != 2 || !merged[1].Start.Equal(at(10, 0)) || !merged[1].End.Equal(at(12, 15)) {
+		t.Fatalf("Unexpected merged intervals: %+v", merged)
+	}
+
+	slots := FreeSlots(busy, at(9, 0), at(14, 0), 30*time.Minute, 3)
+	want := []time.Time{at(9, 30), at(12, 15), at(12, 45)}
+	if len(slots) != len(want) {
+		t.Fatalf("Expected %d slots, got %+v", len(want), slots)
+	}
+	for i, slot := range slots {
+		if !slot.Start.Equal(want[i]) || slot.End.Sub(slot.Start) != 30*time.Minute {
+			t.Errorf("Slot %d: expected start %s, got %+v", i, want[i], slot)
+		}
+	}
+
+	if slots := FreeSlots(busy, at(9, 0), at(12, 0), time.Hour, 5); len(slots) != 0 {
+		t.Errorf("Expected no hour-long slot before noon, got %+v", slots)
+	}
+}
+
+func TestFreeBusy(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	calendar.CreateEvent(Event{UserID: "alice", Title: "Standup", Start: at(9, 0), End: at(9, 15), RRule: "FREQ=DAILY"})
+	calendar.CreateEvent(Event{UserID: "alice", Title: "Lunch", Start: at(13, 0), End: at(14, 0)})
+	calendar.CreateEvent(Event{UserID: "alice", Title: "Birthday", Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "bob", Title: "Review", Start: at(8, 30), End: at(10, 0)})
+
+	busy := calendar.FreeBusy([]string{"alice", "bob", "carol"}, at(9, 0), at(18, 0))
+	if len(busy["alice"]) != 2 || !busy["alice"][0].End.Equal(at(9, 15)) {
+		t.Errorf("Unexpected busy intervals for alice: %+v", busy["alice"])
+	}
+	// Интервал обрезается по началу диапазона
+	if len(busy["bob"]) != 1 || !busy["bob"][0].Start.Equal(at(9, 0)) {
+		t.Errorf("Unexpected busy intervals for bob: %+v", busy["bob"])
+	}
+	if busy["carol"] == nil || len(busy["carol"]) != 0 {
+		t.Errorf("Expected empty busy list for carol, got %+v", busy["carol"])
+	}
+}
+
+func TestRejectOverlap(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	reject := WriteOptions{RejectOverlap: true}
+	meeting, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Meeting", Start: at(10, 0), End: at(11, 0)})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Weekly", Start: at(15, 0).AddDate(0, 0, 1), End: at(16, 0).AddDate(0, 0, 1), RRule: "FREQ=WEEKLY"})
+
+	_, err := calendar.CreateEventWithOptions(Event{UserID: "user1", Title: "Clash", Start: at(10, 30), End: at(11, 30)}, reject)
+	var overlap *OverlapError
+	if !errors.As(err, &overlap) || !errors.Is(err, ErrOverlap) || len(overlap.Conflicts) != 1 || overlap.Conflicts[0].ID != meeting {
+		t.Fatalf("Expected overlap with the meeting, got %v", err)
+	}
+
+	// Соседние события, события на весь день и другие пользователи не мешают
+	for _, event := range []Event{
+		{UserID: "user1", Title: "Right after", Start: at(11, 0), End: at(12, 0)},
+		{UserID: "user1", Title: "All day", Date: "2024-03-10"},
+		{UserID: "user2", Title: "Other user", Start: at(10, 0), End: at(11, 0)},
+	} {
+		if _, err := calendar.CreateEventWithOptions(event, reject); err != nil {
+			t.Errorf("Expected %q to be created, got %v", event.Title, err)
+		}
+	}
+
+	// Повторяющееся событие сталкивается с экземпляром другого повторяющегося через неделю
+	_, err = calendar.CreateEventWithOptions(Event{UserID: "user1", Title: "Biweekly", Start: at(15, 30).AddDate(0, 0, 8), End: at(16, 30).AddDate(0, 0, 8), RRule: "FREQ=WEEKLY;INTERVAL=2"}, reject)
+	if !errors.As(err, &overlap) || overlap.Conflicts[0].RecurrenceID == "" {
+		t.Errorf("Expected overlap with a weekly occurrence, got %v", err)
+	}
+
+	// Событие не конфликтует само с собой при изменении
+	if err := calendar.UpdateEventWithOptions(Event{ID: meeting, UserID: "user1", Title: "Longer", Start: at(9, 30), End: at(10, 45)}, reject); err != nil {
+		t.Errorf("Expected update without conflicts, got %v", err)
+	}
+	if err := calendar.UpdateEventWithOptions(Event{ID: meeting, UserID: "user1", Title: "Too long", Start: at(9, 30), End: at(11, 30)}, reject); !errors.Is(err, ErrOverlap) {
+		t.Errorf("Expected overlap on update, got %v", err)
+	}
+	// Без флага пересечения разрешены, как раньше
+	if _, err := calendar.CreateEvent(Event{UserID: "user1", Title: "Double booked", Start: at(10, 0), End: at(11, 0)}); err != nil {
+		t.Errorf("Expected overlap to be allowed by default, got %v", err)
+	}
+}
+
+func TestFreeBusyHandler(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	calendar.CreateEvent(Event{UserID: "alice", Title: "Busy", Start: at(9, 0), End: at(10, 0)})
+	calendar.CreateEvent(Event{UserID: "bob", Title: "Busy", Start: at(10, 0), End: at(10, 30)})
+
+	rec := serve(router, http.MethodGet, "/api/v1/freebusy?users=alice,bob&from=2024-03-10T09:00:00Z&to=2024-03-10T12:00:00Z&duration=1h&slots=2", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data FreeBusyResponse `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.Data.Busy["alice"]) != 1 || len(resp.Data.Busy["bob"]) != 1 {
+		t.Errorf("Unexpected busy intervals: %+v", resp.Data.Busy)
+	}
+	if len(resp.Data.Free) != 1 || !resp.Data.Free[0].Start.Equal(at(10, 30)) {
+		t.Errorf("Expected single free hour at 10:30, got %+v", resp.Data.Free)
+	}
+
+	for _, target := range []string{
+		"/api/v1/freebusy?from=2024-03-10&to=2024-03-10",
+		"/api/v1/freebusy?users=alice&from=2024-03-10",
+		"/api/v1/freebusy?users=alice&from=2024-03-10&to=2026-03-10",
+		"/api/v1/freebusy?users=alice&from=2024-03-10&to=2024-03-10&duration=-1h",
+		"/api/v1/freebusy?users=alice&from=2024-03-10&to=2024-03-10&slots=0",
+	} {
+		if rec := serve(router, http.MethodGet, target, "", ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+
+	body := `{"title":"Clash","start":"2024-03-10T09:30:00Z","end":"2024-03-10T10:30:00Z"}`
+	rec = serve(router, http.MethodPost, "/api/v1/users/alice/events?reject_overlap=true", body, "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 on overlapping create, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = serve(router, http.MethodPost, "/create_event?reject_overlap=true", `{"user_id":"alice",`+body[1:], "")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 on legacy overlapping create, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodPost, "/api/v1/users/alice/events?reject_overlap=maybe", body, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid flag, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodPost, "/api/v1/users/alice/events", body, ""); rec.Code != http.StatusCreated {
+		t.Errorf("Expected overlap to be allowed without the flag, got %d", rec.Code)
+	}
+}
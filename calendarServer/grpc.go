@@ -0,0 +1,483 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"WBTechL2/calendarServer/calendarpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer реализует gRPC API calendarpb.Calendar поверх того же Calendar, что и HTTP
+type grpcServer struct {
+	calendarpb.UnimplementedCalendarServer
+	calendar *Calendar
+	limiter  *RateLimiter
+}
+
+// newGRPCServer создает gRPC-сервер календаря. Пользователь определяется по метаданным так же,
+// как в HTTP; limiter nil — без ограничения частоты запросов.
+func newGRPCServer(calendar *Calendar, auth *Authenticator, limiter *RateLimiter) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcRecoveryUnary, grpcAuthUnary(auth, limiter)),
+		grpc.ChainStreamInterceptor(grpcRecoveryStream, grpcAuthStream(auth, limiter)),
+	)
+	calendarpb.RegisterCalendarServer(server, &grpcServer{calendar: calendar, limiter: limiter})
+	return server
+}
+
+// grpcAuthenticate проверяет частоту запросов с IP-адреса и токен из метаданных authorization (Bearer)
+// или x-api-key и возвращает контекст с пользователем. При выключенной аутентификации контекст не меняется.
+func grpcAuthenticate(ctx context.Context, auth *Authenticator, limiter *RateLimiter) (context.Context, error) {
+	now := time.Now()
+	if limiter != nil {
+		if p, ok := peer.FromContext(ctx); ok {
+			host, _, err := net.SplitHostPort(p.Addr.String())
+			if err != nil {
+				host = p.Addr.String()
+			}
+			if ok, wait := limiter.ip.take(host, now); !ok {
+				return nil, grpcRateLimited(ctx, wait)
+			}
+		}
+	}
+	if !auth.Enabled() {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		token = values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		scheme, value, _ := strings.Cut(values[0], " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return nil, status.Error(codes.Unauthenticated, ErrUnauthorized.Error())
+		}
+		token = strings.TrimSpace(value)
+	}
+	userID, err := auth.authenticateToken(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return withUser(ctx, userID), nil
+}
+
+// grpcRateLimited отвечает ResourceExhausted с метаданными retry-after в целых секундах (не меньше одной), как HTTP 429
+func grpcRateLimited(ctx context.Context, wait time.Duration) error {
+	seconds := max(int(math.Ceil(wait.Seconds())), 1)
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+func grpcAuthUnary(auth *Authenticator, limiter *RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := grpcAuthenticate(ctx, auth, limiter)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func grpcAuthStream(auth *Authenticator, limiter *RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := grpcAuthenticate(ss.Context(), auth, limiter)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream — поток с контекстом, в который положен пользователь
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcRecoveryUnary превращает панику обработчика в Internal, как recoveryMiddleware в HTTP
+func grpcRecoveryUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer grpcRecover(ctx, info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func grpcRecoveryStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer grpcRecover(ss.Context(), info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+func grpcRecover(ctx context.Context, method string, err *error) {
+	if recovered := recover(); recovered != nil {
+		slog.ErrorContext(ctx, "panic",
+			slog.String("method", method),
+			slog.Any("error", recovered),
+			slog.String("stack", string(debug.Stack())),
+		)
+		*err = status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// user возвращает пользователя, от имени которого выполняется вызов, как requestUser в HTTP, и ограничивает
+// частоту его вызовов, как userMiddleware: по пользователю из токена, а без аутентификации — по user_id
+func (s *grpcServer) user(ctx context.Context, claimed string) (string, error) {
+	userID, authenticated := userFromContext(ctx)
+	if !authenticated {
+		userID = claimed
+	}
+	if s.limiter != nil && userID != "" {
+		if ok, wait := s.limiter.user.take(userID, time.Now()); !ok {
+			return "", grpcRateLimited(ctx, wait)
+		}
+	}
+	if authenticated && claimed != "" && claimed != userID {
+		return "", grpcError(ErrUserMismatch)
+	}
+	if userID == "" {
+		return "", status.Error(codes.InvalidArgument, "user ID is required")
+	}
+	return userID, nil
+}
+
+// grpcError переводит ошибку календаря в статус gRPC по тем же правилам, что apiErrorStatus в HTTP:
+// чужие события неотличимы от несуществующих, исчерпанный лимит событий — ResourceExhausted,
+// несовпадение версии — Aborted, пересечение по времени — FailedPrecondition
+func grpcError(err error) error {
+	if errors.Is(err, ErrEventLimit) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	httpStatus, err := apiErrorStatus(err)
+	code := codes.Internal
+	switch httpStatus {
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+		if errors.Is(err, ErrDuplicateUID) {
+			code = codes.AlreadyExists
+		}
+	case http.StatusPreconditionFailed:
+		code = codes.Aborted
+	case http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, err.Error())
+}
+
+func (s *grpcServer) CreateEvent(ctx context.Context, req *calendarpb.CreateEventRequest) (*calendarpb.Event, error) {
+	if req.GetEvent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is required")
+	}
+	userID, err := s.user(ctx, req.GetEvent().GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	event := eventFromProto(req.GetEvent())
+	event.UserID = userID
+	// ID назначает сервер
+	event.ID = 0
+	if err := ValidateEvent(event); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	id, err := s.calendar.CreateEventWithOptions(event, WriteOptions{RejectOverlap: req.GetRejectOverlap()})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	created, err := s.calendar.GetEvent(userID, id)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return eventToProto(created), nil
+}
+
+func (s *grpcServer) GetEvent(ctx context.Context, req *calendarpb.GetEventRequest) (*calendarpb.Event, error) {
+	userID, err := s.user(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	event, err := s.calendar.GetEvent(userID, int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return eventToProto(event), nil
+}
+
+func (s *grpcServer) UpdateEvent(ctx context.Context, req *calendarpb.UpdateEventRequest) (*calendarpb.Event, error) {
+	if req.GetEvent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is required")
+	}
+	userID, err := s.user(ctx, req.GetEvent().GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	event := eventFromProto(req.GetEvent())
+	// Сначала проверяем существование, чтобы отвечать NotFound, а не InvalidArgument, на несуществующее событие
+	if _, err := s.calendar.GetEvent(userID, event.ID); err != nil {
+		return nil, grpcError(err)
+	}
+	event.UserID = userID
+	if err := ValidateEvent(event); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	opts := WriteOptions{RejectOverlap: req.GetRejectOverlap()}
+	if req.GetVersion() != 0 {
+		opts.IfVersion = []int{int(req.GetVersion())}
+	}
+	if err := s.calendar.UpdateEventWithOptions(event, opts); err != nil {
+		return nil, grpcError(err)
+	}
+	updated, err := s.calendar.GetEvent(userID, event.ID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return eventToProto(updated), nil
+}
+
+func (s *grpcServer) DeleteEvent(ctx context.Context, req *calendarpb.DeleteEventRequest) (*emptypb.Empty, error) {
+	userID, err := s.user(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	var opts WriteOptions
+	if req.GetVersion() != 0 {
+		opts.IfVersion = []int{int(req.GetVersion())}
+	}
+	if err := s.calendar.DeleteUserEventWithOptions(userID, int(req.GetId()), opts); err != nil {
+		return nil, grpcError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ListEvents возвращает события страницами, как listEventsHandler: без периода — все события по ID,
+// с периодом from–to — экземпляры этого периода по возрастанию начала
+func (s *grpcServer) ListEvents(ctx context.Context, req *calendarpb.ListEventsRequest) (*calendarpb.ListEventsResponse, error) {
+	userID, err := s.user(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	opts := ListOptions{Limit: defaultPageLimit}
+	if req.GetLimit() != 0 {
+		if req.GetLimit() < 1 || req.GetLimit() > maxPageLimit {
+			return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxPageLimit)
+		}
+		opts.Limit = int(req.GetLimit())
+	}
+	if req.GetCursor() != "" {
+		cursor, err := decodeCursor(req.GetCursor())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts.After = &cursor
+	}
+
+	var page []Event
+	var next string
+	switch {
+	case req.GetFrom() == nil && req.GetTo() == nil:
+		page, next = paginate(s.calendar.GetUserEvents(userID, time.Time{}, time.Time{}), opts, idKey)
+	case req.GetFrom() == nil || req.GetTo() == nil:
+		return nil, status.Error(codes.InvalidArgument, "from and to must be set together")
+	default:
+		loc := time.UTC
+		if req.GetTimeZone() != "" {
+			if loc, err = time.LoadLocation(req.GetTimeZone()); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown time zone %q", req.GetTimeZone())
+			}
+		}
+		from, to := req.GetFrom().AsTime().In(loc), req.GetTo().AsTime().In(loc)
+		if !to.After(from) {
+			return nil, status.Error(codes.InvalidArgument, "to must be after from")
+		}
+		events, err := s.calendar.GetEventsInRange(userID, from, to)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		page, next = paginate(events, opts, startKey(loc))
+	}
+
+	resp := &calendarpb.ListEventsResponse{Events: make([]*calendarpb.Event, len(page)), NextCursor: next}
+	for i, event := range page {
+		resp.Events[i] = eventToProto(event)
+	}
+	return resp, nil
+}
+
+// Watch отдает изменения событий, как changesHandler: с номера since или с текущего момента.
+// Если продолжить с since нельзя, первым приходит изменение reset с текущим номером.
+// Поток заканчивается при отмене вызова или остановке ленты изменений.
+func (s *grpcServer) Watch(req *calendarpb.WatchRequest, stream calendarpb.Calendar_WatchServer) error {
+	userID, err := s.user(stream.Context(), req.GetUserId())
+	if err != nil {
+		return err
+	}
+	since := s.calendar.changes.last()
+	if req.Since != nil {
+		since = req.GetSince()
+	}
+
+	for {
+		changes, last, notify, done, err := s.calendar.changes.since(userID, since)
+		if errors.Is(err, ErrChangesExpired) {
+			if err := stream.Send(&calendarpb.Change{Seq: last, Type: "reset", Time: timestamppb.Now()}); err != nil {
+				return err
+			}
+		}
+		for _, change := range changes {
+			if err := stream.Send(changeToProto(change)); err != nil {
+				return err
+			}
+		}
+		since = last
+
+		select {
+		case <-notify:
+		case <-done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// eventFromProto переводит событие из protobuf; моменты без часового пояса события остаются в UTC
+func eventFromProto(pb *calendarpb.Event) Event {
+	event := Event{
+		ID:           int(pb.GetId()),
+		UID:          pb.GetUid(),
+		UserID:       pb.GetUserId(),
+		Title:        pb.GetTitle(),
+		Description:  pb.GetDescription(),
+		Tags:         pb.GetTags(),
+		Date:         pb.GetDate(),
+		EndDate:      pb.GetEndDate(),
+		Start:        timeFromProto(pb.GetStart()),
+		End:          timeFromProto(pb.GetEnd()),
+		AllDay:       pb.GetAllDay(),
+		TimeZone:     pb.GetTimeZone(),
+		RRule:        pb.GetRrule(),
+		ExDates:      pb.GetExdates(),
+		RecurrenceID: pb.GetRecurrenceId(),
+		Holidays:     pb.GetHolidays(),
+		OnHoliday:    pb.GetOnHoliday(),
+		CalendarID:   int(pb.GetCalendarId()),
+		Version:      int(pb.GetVersion()),
+	}
+	for _, o := range pb.GetOverrides() {
+		event.Overrides = append(event.Overrides, Override{
+			RecurrenceID: o.GetRecurrenceId(),
+			Title:        o.GetTitle(),
+			Description:  o.GetDescription(),
+			Date:         o.GetDate(),
+			Start:        timeFromProto(o.GetStart()),
+			End:          timeFromProto(o.GetEnd()),
+			Cancelled:    o.GetCancelled(),
+		})
+	}
+	for _, r := range pb.GetReminders() {
+		event.Reminders = append(event.Reminders, Duration(r.AsDuration()))
+	}
+	for _, a := range pb.GetAttendees() {
+		event.Attendees = append(event.Attendees, Attendee{UserID: a.GetUserId(), Status: a.GetStatus()})
+	}
+	return event
+}
+
+// eventToProto переводит событие в protobuf
+func eventToProto(event Event) *calendarpb.Event {
+	pb := &calendarpb.Event{
+		Id:           int64(event.ID),
+		Uid:          event.UID,
+		UserId:       event.UserID,
+		Title:        event.Title,
+		Description:  event.Description,
+		Tags:         event.Tags,
+		Date:         event.Date,
+		EndDate:      event.EndDate,
+		Start:        timeToProto(event.Start),
+		End:          timeToProto(event.End),
+		AllDay:       event.AllDay,
+		TimeZone:     event.TimeZone,
+		Rrule:        event.RRule,
+		Exdates:      event.ExDates,
+		RecurrenceId: event.RecurrenceID,
+		Holidays:     event.Holidays,
+		OnHoliday:    event.OnHoliday,
+		CalendarId:   int64(event.CalendarID),
+		Version:      int64(event.Version),
+	}
+	for _, o := range event.Overrides {
+		pb.Overrides = append(pb.Overrides, &calendarpb.Override{
+			RecurrenceId: o.RecurrenceID,
+			Title:        o.Title,
+			Description:  o.Description,
+			Date:         o.Date,
+			Start:        timeToProto(o.Start),
+			End:          timeToProto(o.End),
+			Cancelled:    o.Cancelled,
+		})
+	}
+	for _, r := range event.Reminders {
+		pb.Reminders = append(pb.Reminders, durationpb.New(time.Duration(r)))
+	}
+	for _, a := range event.Attendees {
+		pb.Attendees = append(pb.Attendees, &calendarpb.Attendee{UserId: a.UserID, Status: a.Status})
+	}
+	return pb
+}
+
+// changeToProto переводит изменение в protobuf
+func changeToProto(change Change) *calendarpb.Change {
+	pb := &calendarpb.Change{
+		Seq:        change.Seq,
+		Type:       change.Type,
+		EventId:    int64(change.EventID),
+		UserId:     change.UserID,
+		CalendarId: int64(change.CalendarID),
+		Time:       timeToProto(change.Time),
+	}
+	if change.Event != nil {
+		pb.Event = eventToProto(*change.Event)
+	}
+	return pb
+}
+
+// timeFromProto возвращает момент ts; отсутствующий Timestamp — нулевой момент
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// timeToProto возвращает Timestamp момента t; нулевой момент — отсутствующий Timestamp
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"WBTechL2/calendarServer/calendarpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestGRPC запускает gRPC API календаря на соединении в памяти и возвращает клиент к нему
+func newTestGRPC(t *testing.T, calendar *Calendar, auth *Authenticator, limiter *RateLimiter) calendarpb.CalendarClient {
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer(calendar, auth, limiter)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return calendarpb.NewCalendarClient(conn)
+}
+
+// withToken добавляет к контексту вызова API-ключ
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPCEvents(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	auth := NewAuthenticator(map[string]string{"user1-key": "user1", "user2-key": "user2"}, nil)
+	c := newTestGRPC(t, calendar, auth, nil)
+	ctx := withToken(context.Background(), "user1-key")
+
+	if _, err := c.ListEvents(context.Background(), &calendarpb.ListEventsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a token, got %v", err)
+	}
+	if _, err := c.ListEvents(ctx, &calendarpb.ListEventsRequest{UserId: "user2"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for another user, got %v", err)
+	}
+
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	created, err := c.CreateEvent(ctx, &calendarpb.CreateEventRequest{Event: &calendarpb.Event{
+		Title: "Standup",
+		Start: timestamppb.New(start),
+		End:   timestamppb.New(start.Add(30 * time.Minute)),
+		Rrule: "FREQ=DAILY;COUNT=3",
+	}})
+	if err != nil || created.GetId() == 0 || created.GetUserId() != "user1" || created.GetVersion() != 1 {
+		t.Fatalf("CreateEvent: %v, %v", created, err)
+	}
+	// Событие создано в том же календаре, что обслуживает HTTP
+	if event, err := calendar.GetEvent("user1", int(created.GetId())); err != nil || event.Title != "Standup" || !event.Start.Equal(start) {
+		t.Errorf("Event is not in the calendar: %+v, %v", event, err)
+	}
+	if _, err := c.CreateEvent(ctx, &calendarpb.CreateEventRequest{Event: &calendarpb.Event{Date: "2024-03-10"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without a title, got %v", err)
+	}
+	if _, err := c.CreateEvent(ctx, &calendarpb.CreateEventRequest{
+		Event:         &calendarpb.Event{Title: "Overlap", Start: timestamppb.New(start), End: timestamppb.New(start.Add(time.Hour))},
+		RejectOverlap: true,
+	}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for an overlap, got %v", err)
+	}
+
+	resp, err := c.ListEvents(ctx, &calendarpb.ListEventsRequest{
+		From:  timestamppb.New(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)),
+		To:    timestamppb.New(time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)),
+		Limit: 2,
+	})
+	if err != nil || len(resp.GetEvents()) != 2 || resp.GetNextCursor() == "" || resp.GetEvents()[1].GetRecurrenceId() == "" {
+		t.Fatalf("ListEvents: %v, %v", resp, err)
+	}
+	resp, err = c.ListEvents(ctx, &calendarpb.ListEventsRequest{
+		From:   timestamppb.New(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)),
+		To:     timestamppb.New(time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)),
+		Limit:  2,
+		Cursor: resp.GetNextCursor(),
+	})
+	if err != nil || len(resp.GetEvents()) != 1 || resp.GetNextCursor() != "" {
+		t.Errorf("Expected the last instance on the second page, got %v, %v", resp, err)
+	}
+	if _, err := c.ListEvents(ctx, &calendarpb.ListEventsRequest{From: timestamppb.New(start)}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a range without to, got %v", err)
+	}
+
+	update := proto.Clone(created).(*calendarpb.Event)
+	update.Title = "Daily standup"
+	if _, err := c.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{Event: update, Version: 5}); status.Code(err) != codes.Aborted {
+		t.Errorf("Expected Aborted for a stale version, got %v", err)
+	}
+	updated, err := c.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{Event: update, Version: 1})
+	if err != nil || updated.GetTitle() != "Daily standup" || updated.GetVersion() != 2 {
+		t.Errorf("UpdateEvent: %v, %v", updated, err)
+	}
+
+	// Чужие события неотличимы от несуществующих
+	other := withToken(context.Background(), "user2-key")
+	if _, err := c.GetEvent(other, &calendarpb.GetEventRequest{Id: created.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for another user's event, got %v", err)
+	}
+	if _, err := c.DeleteEvent(other, &calendarpb.DeleteEventRequest{Id: created.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound deleting another user's event, got %v", err)
+	}
+
+	if _, err := c.DeleteEvent(ctx, &calendarpb.DeleteEventRequest{Id: created.GetId(), Version: 2}); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if _, err := c.GetEvent(ctx, &calendarpb.GetEventRequest{Id: created.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound after deletion, got %v", err)
+	}
+}
+
+func TestGRPCWatch(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	c := newTestGRPC(t, calendar, nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := calendar.changes.last()
+	stream, err := c.Watch(ctx, &calendarpb.WatchRequest{UserId: "user1", Since: proto.Int64(start)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Planning", Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "user2", Title: "Private", Date: "2024-03-10"})
+	calendar.DeleteUserEvent("user1", id)
+
+	created, err := stream.Recv()
+	if err != nil || created.GetType() != ChangeCreated || created.GetEvent().GetTitle() != "Planning" {
+		t.Fatalf("Expected created change, got %v, %v", created, err)
+	}
+	// Чужие изменения в поток не попадают
+	deleted, err := stream.Recv()
+	if err != nil || deleted.GetType() != ChangeDeleted || deleted.GetEventId() != int64(id) || deleted.GetEvent() != nil {
+		t.Fatalf("Expected deleted change, got %v, %v", deleted, err)
+	}
+
+	// Поток продолжается с номера последнего полученного изменения
+	resumed, err := c.Watch(ctx, &calendarpb.WatchRequest{UserId: "user1", Since: proto.Int64(created.GetSeq())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change, err := resumed.Recv(); err != nil || change.GetSeq() != deleted.GetSeq() {
+		t.Errorf("Expected the deleted change after resuming, got %v, %v", change, err)
+	}
+
+	// Остановка ленты изменений заканчивает потоки
+	calendar.StopChangeFeed()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable after the change feed stopped, got %v", err)
+	}
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	auth := NewAuthenticator(map[string]string{"user1-key": "user1"}, nil)
+	c := newTestGRPC(t, calendar, auth, NewRateLimiter(RateLimit{Rate: 0.1, Burst: 2}, RateLimit{}))
+	ctx := withToken(context.Background(), "user1-key")
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.ListEvents(ctx, &calendarpb.ListEventsRequest{}); err != nil {
+			t.Fatalf("Call %d: %v", i, err)
+		}
+	}
+	var header metadata.MD
+	_, err := c.ListEvents(ctx, &calendarpb.ListEventsRequest{}, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted || len(header.Get("retry-after")) == 0 || header.Get("retry-after")[0] != "10" {
+		t.Errorf("Expected ResourceExhausted with retry-after 10, got %v, %v", err, header)
+	}
+
+	// Без аутентификации лимит пользователя считается по user_id, как в HTTP
+	open := newTestGRPC(t, calendar, nil, NewRateLimiter(RateLimit{Rate: 0.1, Burst: 1}, RateLimit{}))
+	if _, err := open.ListEvents(context.Background(), &calendarpb.ListEventsRequest{UserId: "user1"}); err != nil {
+		t.Fatalf("First call without auth: %v", err)
+	}
+	if _, err := open.ListEvents(context.Background(), &calendarpb.ListEventsRequest{UserId: "user1"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for user1 without auth, got %v", err)
+	}
+	if _, err := open.ListEvents(context.Background(), &calendarpb.ListEventsRequest{UserId: "user2"}); err != nil {
+		t.Errorf("Limit of one user must not affect another: %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Что делать с экземпляром повторяющегося события, выпавшим на нерабочий день (поле on_holiday)
+const (
+	HolidaySkip     = "skip"     // пропустить экземпляр
+	HolidayNext     = "next"     // перенести на следующий рабочий день
+	HolidayPrevious = "previous" // перенести на предыдущий рабочий день
+)
+
+const (
+	// maxHolidayShiftDays — дальше скольких дней рабочий день не ищется; без него экземпляр пропускается
+	maxHolidayShiftDays = 31
+	// holidayRecurrenceYears — на сколько лет раскрываются повторяющиеся праздники из .ics
+	holidayRecurrenceYears = 50
+	// holidayWorkdayCategory — категория VEVENT, отмечающая перенесенный рабочий день
+	holidayWorkdayCategory = "workday"
+)
+
+// HolidayCalendar — производственный календарь: выходные дни недели, праздники
+// и перенесенные рабочие дни, которые приходятся на выходные
+type HolidayCalendar struct {
+	Name        string
+	weekend     [7]bool
+	holidays    map[string]string // дата YYYY-MM-DD -> название праздника
+	workingDays map[string]bool   // рабочие дни, перенесенные на выходные
+}
+
+// newHolidayCalendar создает пустой производственный календарь с выходными weekend
+func newHolidayCalendar(name string, weekend []time.Weekday) *HolidayCalendar {
+	h := &HolidayCalendar{Name: name, holidays: make(map[string]string), workingDays: make(map[string]bool)}
+	for _, day := range weekend {
+		h.weekend[day] = true
+	}
+	return h
+}
+
+// WorkingDay — сведения о дне производственного календаря
+type WorkingDay struct {
+	Calendar string `json:"calendar"`
+	Date     string `json:"date"`
+	Working  bool   `json:"working"`
+	Weekend  bool   `json:"weekend"`           // выходной день недели, если рабочий день на него не перенесен
+	Holiday  string `json:"holiday,omitempty"` // название праздника
+}
+
+// Day возвращает сведения о дне date (YYYY-MM-DD)
+func (h *HolidayCalendar) Day(date string) (WorkingDay, error) {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return WorkingDay{}, errors.New("date must be in format YYYY-MM-DD")
+	}
+	wd := WorkingDay{Calendar: h.Name, Date: date, Holiday: h.holidays[date]}
+	wd.Weekend = h.weekend[day.Weekday()] && !h.workingDays[date]
+	wd.Working = !wd.Weekend && wd.Holiday == ""
+	return wd, nil
+}
+
+// working сообщает, рабочий ли день day
+func (h *HolidayCalendar) working(day time.Time) bool {
+	date := day.Format(dateLayout)
+	if _, ok := h.holidays[date]; ok {
+		return false
+	}
+	return !h.weekend[day.Weekday()] || h.workingDays[date]
+}
+
+// avoid применяет к экземпляру occ правило policy: возвращает false, если экземпляр пропускается,
+// иначе экземпляр, перенесенный с нерабочего дня на ближайший рабочий. Перенесенный экземпляр
+// сохраняет свой recurrence_id, как и экземпляр с переопределением.
+func (h *HolidayCalendar) avoid(occ Event, policy string) (Event, bool) {
+	if h == nil || policy == "" {
+		return occ, true
+	}
+	day, _ := time.Parse(dateLayout, occ.Date)
+	if h.working(day) {
+		return occ, true
+	}
+	step := 1
+	switch policy {
+	case HolidayPrevious:
+		step = -1
+	case HolidaySkip:
+		return occ, false
+	}
+	for shift := step; shift*step <= maxHolidayShiftDays; shift += step {
+		if h.working(day.AddDate(0, 0, shift)) {
+			occ.shiftDays(shift)
+			return occ, true
+		}
+	}
+	return occ, false
+}
+
+// shiftDays переносит экземпляр на days дней; у событий со временем сохраняется время на часах пояса события
+func (e *Event) shiftDays(days int) {
+	if e.AllDay {
+		first, _ := time.Parse(dateLayout, e.Date)
+		e.Date = first.AddDate(0, 0, days).Format(dateLayout)
+		if e.EndDate != "" {
+			last, _ := time.Parse(dateLayout, e.EndDate)
+			e.EndDate = last.AddDate(0, 0, days).Format(dateLayout)
+		}
+		return
+	}
+	e.Start = e.Start.AddDate(0, 0, days)
+	e.End = e.End.AddDate(0, 0, days)
+	e.Date = e.Start.Format(dateLayout)
+}
+
+// holidayCalendar возвращает производственный календарь события или nil, если правила on_holiday нет
+// или календарь больше не загружен: тогда экземпляры не переносятся. Сервер не запускается
+// с такими событиями, см. MissingHolidayCalendars.
+func (e *Event) holidayCalendar() *HolidayCalendar {
+	if e.OnHoliday == "" {
+		return nil
+	}
+	h, _ := LookupHolidayCalendar(e.Holidays)
+	return h
+}
+
+// MissingHolidayCalendars возвращает имена незагруженных производственных календарей, на которые
+// ссылаются события, включая лежащие в корзине, с числом таких событий. Экземпляры этих событий
+// не переносятся, пока календарь снова не загрузят.
+func (c *Calendar) MissingHolidayCalendars() map[string]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	missing := make(map[string]int)
+	for _, events := range []map[int]*Event{c.events, c.trash} {
+		for _, event := range events {
+			if event.OnHoliday == "" {
+				continue
+			}
+			if _, ok := LookupHolidayCalendar(event.Holidays); !ok {
+				missing[event.Holidays]++
+			}
+		}
+	}
+	return missing
+}
+
+// validateHolidayPolicy проверяет поля holidays и on_holiday повторяющегося события
+func validateHolidayPolicy(event Event) error {
+	if event.OnHoliday == "" && event.Holidays == "" {
+		return nil
+	}
+	switch event.OnHoliday {
+	case HolidaySkip, HolidayNext, HolidayPrevious:
+	case "":
+		return errors.New("holidays requires on_holiday")
+	default:
+		return fmt.Errorf("on_holiday must be %s, %s or %s", HolidaySkip, HolidayNext, HolidayPrevious)
+	}
+	if !event.IsRecurring() {
+		return errors.New("on_holiday requires rrule")
+	}
+	if event.Holidays == "" {
+		return errors.New("on_holiday requires holidays")
+	}
+	if _, ok := LookupHolidayCalendar(event.Holidays); !ok {
+		return fmt.Errorf("unknown holiday calendar %q", event.Holidays)
+	}
+	return nil
+}
+
+// holidayCalendars — загруженные производственные календари по имени. Как и база часовых поясов,
+// они общие для всего сервера и загружаются при запуске.
+var holidayCalendars = struct {
+	sync.RWMutex
+	byName map[string]*HolidayCalendar
+}{byName: make(map[string]*HolidayCalendar)}
+
+// RegisterHolidayCalendar делает производственный календарь доступным по имени, заменяя прежний с тем же именем
+func RegisterHolidayCalendar(h *HolidayCalendar) {
+	holidayCalendars.Lock()
+	defer holidayCalendars.Unlock()
+	holidayCalendars.byName[h.Name] = h
+}
+
+// LookupHolidayCalendar возвращает производственный календарь по имени
+func LookupHolidayCalendar(name string) (*HolidayCalendar, bool) {
+	holidayCalendars.RLock()
+	defer holidayCalendars.RUnlock()
+	h, ok := holidayCalendars.byName[name]
+	return h, ok
+}
+
+// LoadHolidayFile читает производственный календарь из файла .json или .ics.
+// Имя календаря — имя файла без расширения: ru.json загружается как ru.
+func LoadHolidayFile(path string) (*HolidayCalendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(filepath.Base(path), ext)
+	switch strings.ToLower(ext) {
+	case ".json":
+		return ParseHolidayJSON(name, f)
+	case ".ics":
+		return ParseHolidayICalendar(name, f)
+	}
+	return nil, fmt.Errorf("unsupported holiday file %q, expected .json or .ics", path)
+}
+
+// holidayFile — производственный календарь в JSON
+type holidayFile struct {
+	// Weekend — выходные дни недели; по умолчанию суббота и воскресенье
+	Weekend  []string `json:"weekend"`
+	Holidays []struct {
+		Date    string `json:"date"`
+		EndDate string `json:"end_date"` // последний день праздника (включительно) для нескольких дней подряд
+		Name    string `json:"name"`
+	} `json:"holidays"`
+	WorkingDays []string `json:"working_days"` // рабочие дни, перенесенные на выходные
+}
+
+// ParseHolidayJSON разбирает производственный календарь name в JSON:
+// {"weekend": [...], "holidays": [{"date", "end_date", "name"}], "working_days": [...]}
+func ParseHolidayJSON(name string, r io.Reader) (*HolidayCalendar, error) {
+	var file holidayFile
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid holiday calendar: %w", err)
+	}
+
+	weekend := []time.Weekday{time.Saturday, time.Sunday}
+	if file.Weekend != nil {
+		weekend = weekend[:0]
+		for _, value := range file.Weekend {
+			day, err := parseWeekday(value)
+			if err != nil {
+				return nil, err
+			}
+			weekend = append(weekend, day)
+		}
+	}
+	h := newHolidayCalendar(name, weekend)
+	for _, holiday := range file.Holidays {
+		if err := h.addHoliday(holiday.Date, holiday.EndDate, holiday.Name); err != nil {
+			return nil, err
+		}
+	}
+	for _, date := range file.WorkingDays {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf("working day %q must be in format YYYY-MM-DD", date)
+		}
+		h.workingDays[date] = true
+	}
+	return h, nil
+}
+
+// ParseHolidayICalendar разбирает производственный календарь name из iCalendar: каждое событие
+// на весь день — праздник с названием из SUMMARY, повторяющиеся праздники раскрываются
+// на holidayRecurrenceYears лет. События с категорией workday — перенесенные рабочие дни.
+// Выходные — суббота и воскресенье.
+func ParseHolidayICalendar(name string, r io.Reader) (*HolidayCalendar, error) {
+	root, err := parseICalendar(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidICalendar, err)
+	}
+	h := newHolidayCalendar(name, []time.Weekday{time.Saturday, time.Sunday})
+	decoded := decodeICalendar(root)
+	for i, event := range decoded.events {
+		event, err := normalizeEvent(event)
+		if err != nil {
+			return nil, fmt.Errorf("holiday %q: %w", event.Title, err)
+		}
+		if !event.AllDay {
+			return nil, fmt.Errorf("holiday %q must be an all-day event", event.Title)
+		}
+		days := []Event{event}
+		if event.IsRecurring() {
+			from, _ := time.Parse(dateLayout, event.Date)
+			days = event.occurrencesInRange(from, from.AddDate(holidayRecurrenceYears, 0, 0))
+		}
+		for _, day := range days {
+			if isWorkday(decoded.sources[i]) {
+				h.workingDays[day.Date] = true
+				continue
+			}
+			if err := h.addHoliday(day.Date, day.EndDate, day.Title); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return h, nil
+}
+
+// isWorkday сообщает, отмечен ли VEVENT категорией перенесенного рабочего дня
+func isWorkday(c *icalComponent) bool {
+	for _, p := range c.all("CATEGORIES") {
+		for _, category := range splitICalList(p.value) {
+			if strings.EqualFold(unescapeICalText(category), holidayWorkdayCategory) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addHoliday добавляет праздник name с даты date по endDate включительно (пустая — один день)
+func (h *HolidayCalendar) addHoliday(date, endDate, name string) error {
+	first, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return fmt.Errorf("holiday date %q must be in format YYYY-MM-DD", date)
+	}
+	last := first
+	if endDate != "" {
+		if last, err = time.Parse(dateLayout, endDate); err != nil || last.Before(first) {
+			return fmt.Errorf("holiday end date %q must be a date not before %s", endDate, date)
+		}
+	}
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		h.holidays[day.Format(dateLayout)] = name
+	}
+	return nil
+}
+
+// workingDayHandler отвечает, рабочий ли день date в производственном календаре {holidays}
+func workingDayHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	h, ok := LookupHolidayCalendar(vars["holidays"])
+	if !ok {
+		writeErrorResponse(w, http.StatusNotFound, "holiday calendar not found")
+		return
+	}
+	day, err := h.Day(vars["date"])
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeResponse(w, http.StatusOK, Response{Message: "Working day", Data: day})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL2/calendarServer/client"
+)
+
+const testHolidaysJSON = `{
+	"holidays": [
+		{"date": "2024-01-01", "end_date": "2024-01-08", "name": "Новогодние каникулы"},
+		{"date": "2024-05-09", "name": "День Победы"},
+		{"date": "2024-05-10", "name": "Перенесенный выходной"}
+	],
+	"working_days": ["2024-04-27"]
+}`
+
+// registerTestHolidays загружает производственный календарь ru-test из testHolidaysJSON
+func registerTestHolidays(t *testing.T) *HolidayCalendar {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ru-test.json")
+	os.WriteFile(path, []byte(testHolidaysJSON), 0o644)
+	h, err := LoadHolidayFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterHolidayCalendar(h)
+	return h
+}
+
+func TestHolidayCalendar(t *testing.T) {
+	h := registerTestHolidays(t)
+	if h.Name != "ru-test" {
+		t.Errorf("Expected the calendar named after the file, got %q", h.Name)
+	}
+	tests := []struct {
+		date    string
+		working bool
+		weekend bool
+		holiday string
+	}{
+		{date: "2024-01-03", holiday: "Новогодние каникулы"},
+		{date: "2024-01-09", working: true},
+		{date: "2024-04-27", working: true}, // рабочая суббота
+		{date: "2024-04-28", weekend: true},
+		{date: "2024-05-09", holiday: "День Победы"},
+	}
+	for _, tt := range tests {
+		day, err := h.Day(tt.date)
+		if err != nil || day.Working != tt.working || day.Weekend != tt.weekend || day.Holiday != tt.holiday {
+			t.Errorf("Day(%s) = %+v, %v", tt.date, day, err)
+		}
+	}
+	if _, err := h.Day("2024-02-30"); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+
+	if _, err := ParseHolidayJSON("bad", strings.NewReader(`{"weekend": ["someday"]}`)); err == nil {
+		t.Error("Expected an error for an unknown weekday")
+	}
+	if _, err := ParseHolidayJSON("bad", strings.NewReader(`{"holidays": [{"date": "2024-05-09", "end_date": "2024-05-01"}]}`)); err == nil {
+		t.Error("Expected an error for a holiday ending before it starts")
+	}
+
+	// В .ics праздники — события на весь день, повторяющиеся раскрываются; workday — перенесенный рабочий день
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT", "UID:new-year", "SUMMARY:Новый год", "DTSTART;VALUE=DATE:20240101", "DTEND;VALUE=DATE:20240103", "RRULE:FREQ=YEARLY", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:saturday", "SUMMARY:Рабочий день", "CATEGORIES:WORKDAY", "DTSTART;VALUE=DATE:20241228", "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	fromICS, err := ParseHolidayICalendar("ics", strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for date, working := range map[string]bool{"2030-01-02": false, "2030-01-03": true, "2024-12-28": true, "2024-12-29": false} {
+		if day, _ := fromICS.Day(date); day.Working != working {
+			t.Errorf("Day(%s) from iCalendar = %+v, expected working %v", date, day, working)
+		}
+	}
+}
+
+func TestHolidayPolicy(t *testing.T) {
+	registerTestHolidays(t)
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	calendar, _ := NewCalendar(nil)
+
+	// Еженедельно по четвергам: 2024-05-09 — праздник, 2024-05-10 тоже не рабочий
+	weekly := Event{
+		UserID:    "user1",
+		Title:     "Weekly",
+		Start:     time.Date(2024, 5, 2, 10, 0, 0, 0, moscow),
+		End:       time.Date(2024, 5, 2, 11, 0, 0, 0, moscow),
+		TimeZone:  "Europe/Moscow",
+		RRule:     "FREQ=WEEKLY;COUNT=3",
+		Holidays:  "ru-test",
+		OnHoliday: HolidaySkip,
+	}
+	starts := func(events []Event) []string {
+		res := make([]string, len(events))
+		for i, e := range events {
+			res[i] = e.Start.In(moscow).Format("2006-01-02 15:04")
+		}
+		return res
+	}
+	from, to := time.Date(2024, 5, 1, 0, 0, 0, 0, moscow), time.Date(2024, 6, 1, 0, 0, 0, 0, moscow)
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{policy: HolidaySkip, want: []string{"2024-05-02 10:00", "2024-05-16 10:00"}},
+		{policy: HolidayNext, want: []string{"2024-05-02 10:00", "2024-05-13 10:00", "2024-05-16 10:00"}},
+		{policy: HolidayPrevious, want: []string{"2024-05-02 10:00", "2024-05-08 10:00", "2024-05-16 10:00"}},
+	}
+	for _, tt := range tests {
+		event := weekly
+		event.OnHoliday = tt.policy
+		id, err := calendar.CreateEvent(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events, _ := calendar.GetEventsInRange("user1", from, to)
+		if got := starts(events); !equalStrings(got, tt.want) {
+			t.Errorf("%s: expected instances %v, got %v", tt.policy, tt.want, got)
+		}
+		calendar.DeleteUserEvent("user1", id)
+	}
+
+	// Перенесенный экземпляр сохраняет recurrence_id и попадает в интервал, даже если исходная дата вне его
+	event := weekly
+	event.OnHoliday = HolidayPrevious
+	id, _ := calendar.CreateEvent(event)
+	events, _ := calendar.GetEventsInRange("user1", time.Date(2024, 5, 8, 0, 0, 0, 0, moscow), time.Date(2024, 5, 9, 0, 0, 0, 0, moscow))
+	if len(events) != 1 || events[0].RecurrenceID != "2024-05-09T07:00:00Z" {
+		t.Errorf("Expected the instance moved from 2024-05-09, got %+v", events)
+	}
+
+	// Переопределение экземпляра важнее правила on_holiday
+	event.ID = id
+	event.Overrides = []Override{{RecurrenceID: "2024-05-09T07:00:00Z", Title: "On the holiday"}}
+	if err := calendar.UpdateEvent(event); err != nil {
+		t.Fatal(err)
+	}
+	events, _ = calendar.GetEventsInRange("user1", from, to)
+	if got := starts(events); !equalStrings(got, []string{"2024-05-02 10:00", "2024-05-09 10:00", "2024-05-16 10:00"}) {
+		t.Errorf("Expected the overridden instance on the holiday, got %v", got)
+	}
+
+	invalid := []Event{
+		{UserID: "user1", Title: "Once", Date: "2024-05-09", Holidays: "ru-test", OnHoliday: HolidaySkip},
+		{UserID: "user1", Title: "Unknown", Date: "2024-05-09", RRule: "FREQ=DAILY", Holidays: "nowhere", OnHoliday: HolidaySkip},
+		{UserID: "user1", Title: "No calendar", Date: "2024-05-09", RRule: "FREQ=DAILY", OnHoliday: HolidayNext},
+		{UserID: "user1", Title: "No policy", Date: "2024-05-09", RRule: "FREQ=DAILY", Holidays: "ru-test"},
+		{UserID: "user1", Title: "Bad policy", Date: "2024-05-09", RRule: "FREQ=DAILY", Holidays: "ru-test", OnHoliday: "later"},
+	}
+	for _, e := range invalid {
+		if err := ValidateEvent(e); err == nil {
+			t.Errorf("Expected %q to be rejected", e.Title)
+		}
+	}
+}
+
+func TestWorkingDayAPI(t *testing.T) {
+	registerTestHolidays(t)
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+
+	day, err := c.GetWorkingDay(ctx, "ru-test", "2024-05-09")
+	if err != nil || day.Working || day.Holiday != "День Победы" || day.Calendar != "ru-test" {
+		t.Errorf("GetWorkingDay: %+v, %v", day, err)
+	}
+	if day, err := c.GetWorkingDay(ctx, "ru-test", "2024-04-27"); err != nil || !day.Working {
+		t.Errorf("Expected a working Saturday, got %+v, %v", day, err)
+	}
+	if _, err := c.GetWorkingDay(ctx, "nowhere", "2024-05-09"); !client.IsNotFound(err) {
+		t.Errorf("Expected 404 for an unknown calendar, got %v", err)
+	}
+	if _, err := c.GetWorkingDay(ctx, "ru-test", "09.05.2024"); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+
+	// Правило on_holiday доступно через API
+	created, err := c.CreateEvent(ctx, "user1", client.Event{Title: "Report", Date: "2024-05-02", RRule: "FREQ=WEEKLY;COUNT=2", Holidays: "ru-test", OnHoliday: client.HolidayNext}, false)
+	if err != nil || created.OnHoliday != client.HolidayNext {
+		t.Fatalf("CreateEvent: %+v, %v", created, err)
+	}
+	page, err := c.ListEvents(ctx, "user1", client.ListOptions{From: "2024-05-01", To: "2024-05-31"})
+	if err != nil || len(page.Events) != 2 || page.Events[1].Date != "2024-05-13" {
+		t.Errorf("Expected the second instance moved to 2024-05-13, got %+v, %v", page, err)
+	}
+}
+
+func TestMissingHolidayCalendars(t *testing.T) {
+	RegisterHolidayCalendar(newHolidayCalendar("removed", nil))
+	calendar, _ := NewCalendar(nil)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Kept", Date: "2024-05-02", RRule: "FREQ=WEEKLY", Holidays: "removed", OnHoliday: HolidaySkip})
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Trashed", Date: "2024-05-02", RRule: "FREQ=WEEKLY", Holidays: "removed", OnHoliday: HolidayNext})
+	calendar.DeleteUserEvent("user1", id)
+	if missing := calendar.MissingHolidayCalendars(); len(missing) != 0 {
+		t.Errorf("Expected no missing calendars, got %v", missing)
+	}
+
+	// Файл календаря не загрузили после перезапуска
+	holidayCalendars.Lock()
+	delete(holidayCalendars.byName, "removed")
+	holidayCalendars.Unlock()
+	if missing := calendar.MissingHolidayCalendars(); len(missing) != 1 || missing["removed"] != 2 {
+		t.Errorf("Expected 2 events with the missing calendar, got %v", missing)
+	}
+}
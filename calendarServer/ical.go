@@ -0,0 +1,797 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidICalendar возвращается, если загруженный файл не является календарем iCalendar
+var ErrInvalidICalendar = errors.New("invalid iCalendar")
+
+// icalProductID — значение PRODID в выгружаемых календарях
+const icalProductID = "-//WBTechL2//calendarServer//EN"
+
+// icalMaxLine — максимальная длина строки iCalendar в октетах до переноса (RFC 5545, 3.1)
+const icalMaxLine = 75
+
+const (
+	icalDateLayout    = "20060102"
+	icalLocalLayout   = "20060102T150405"
+	icalUTCLayout     = "20060102T150405Z"
+	icalDefaultDomain = "calendarServer"
+)
+
+// uid возвращает глобальный идентификатор события для iCalendar:
+// сохраненный при импорте UID или сгенерированный из ID
+func (e *Event) uid() string {
+	if e.UID != "" {
+		return e.UID
+	}
+	return fmt.Sprintf("event-%d@%s", e.ID, icalDefaultDomain)
+}
+
+// icalWriter пишет строки содержимого iCalendar с переносом длинных строк и CRLF
+type icalWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (iw *icalWriter) line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	line := name + ":" + value
+	// Строки продолжения начинаются с пробела, который тоже входит в лимит
+	limit := icalMaxLine
+	for len(line) > limit {
+		// Не разрываем многобайтовые символы UTF-8
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, iw.err = iw.w.WriteString(line[:cut] + "\r\n "); iw.err != nil {
+			return
+		}
+		line = line[cut:]
+		limit = icalMaxLine - 1
+	}
+	_, iw.err = iw.w.WriteString(line + "\r\n")
+}
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icalText экранирует значение типа TEXT
+func icalText(s string) string {
+	return icalTextEscaper.Replace(s)
+}
+
+// icalDateTime форматирует время события со временем: в поясе TZID, если он задан, иначе в UTC
+func icalDateTime(name string, t time.Time, tz string) (string, string) {
+	if tz != "" {
+		return name + ";TZID=" + tz, t.Format(icalLocalLayout)
+	}
+	return name, t.UTC().Format(icalUTCLayout)
+}
+
+// WriteICalendar выгружает события в формате iCalendar (RFC 5545).
+// Повторяющиеся события выгружаются одним VEVENT с RRULE и EXDATE,
+// переопределенные экземпляры — отдельными VEVENT с тем же UID и RECURRENCE-ID.
+// Для событий с часовым поясом используется TZID с именем IANA без VTIMEZONE:
+// Google Calendar, Outlook и Thunderbird разрешают такие имена самостоятельно.
+func WriteICalendar(w io.Writer, events []Event, now time.Time) error {
+	iw := &icalWriter{w: bufio.NewWriter(w)}
+	stamp := now.UTC().Format(icalUTCLayout)
+
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", icalProductID)
+	iw.line("CALSCALE", "GREGORIAN")
+	for i := range events {
+		writeVEvent(iw, &events[i], stamp)
+	}
+	iw.line("END", "VCALENDAR")
+
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+func writeVEvent(iw *icalWriter, e *Event, stamp string) {
+	iw.line("BEGIN", "VEVENT")
+	iw.line("UID", icalText(e.uid()))
+	iw.line("DTSTAMP", stamp)
+	writeEventTimes(iw, e)
+	iw.line("SUMMARY", icalText(e.Title))
+	if e.Description != "" {
+		iw.line("DESCRIPTION", icalText(e.Description))
+	}
+	if len(e.Tags) > 0 {
+		categories := make([]string, len(e.Tags))
+		for i, tag := range e.Tags {
+			categories[i] = icalText(tag)
+		}
+		iw.line("CATEGORIES", strings.Join(categories, ","))
+	}
+
+	var rule *RRule
+	if e.IsRecurring() {
+		rule, _ = ParseRRule(e.RRule)
+	}
+	if rule != nil {
+		iw.line("RRULE", rule.String())
+		for _, start := range e.exceptionStarts(rule) {
+			if e.AllDay {
+				iw.line("EXDATE;VALUE=DATE", start.Format(icalDateLayout))
+			} else {
+				iw.line(icalDateTime("EXDATE", start.In(e.Start.Location()), e.TimeZone))
+			}
+		}
+	}
+	for _, before := range e.Reminders {
+		iw.line("BEGIN", "VALARM")
+		iw.line("ACTION", "DISPLAY")
+		iw.line("DESCRIPTION", icalText(e.Title))
+		iw.line("TRIGGER", formatICalDuration(-time.Duration(before)))
+		iw.line("END", "VALARM")
+	}
+	iw.line("END", "VEVENT")
+
+	if rule == nil {
+		return
+	}
+	for i := range e.Overrides {
+		o := &e.Overrides[i]
+		if o.Cancelled {
+			continue
+		}
+		start, ok := e.findOccurrence(rule, o.RecurrenceID)
+		if !ok {
+			continue
+		}
+		occ := o.apply(e.occurrence(start))
+
+		iw.line("BEGIN", "VEVENT")
+		iw.line("UID", icalText(e.uid()))
+		iw.line("DTSTAMP", stamp)
+		if e.AllDay {
+			iw.line("RECURRENCE-ID;VALUE=DATE", start.Format(icalDateLayout))
+		} else {
+			iw.line(icalDateTime("RECURRENCE-ID", start.In(e.Start.Location()), e.TimeZone))
+		}
+		writeEventTimes(iw, &occ)
+		iw.line("SUMMARY", icalText(occ.Title))
+		if occ.Description != "" {
+			iw.line("DESCRIPTION", icalText(occ.Description))
+		}
+		iw.line("END", "VEVENT")
+	}
+}
+
+// writeEventTimes пишет DTSTART и DTEND; у событий на весь день DTEND — день после последнего
+func writeEventTimes(iw *icalWriter, e *Event) {
+	if e.AllDay {
+		first, last := e.Date, e.EndDate
+		if last == "" {
+			last = first
+		}
+		start, _ := time.Parse(dateLayout, first)
+		end, _ := time.Parse(dateLayout, last)
+		iw.line("DTSTART;VALUE=DATE", start.Format(icalDateLayout))
+		iw.line("DTEND;VALUE=DATE", end.AddDate(0, 0, 1).Format(icalDateLayout))
+		return
+	}
+	iw.line(icalDateTime("DTSTART", e.Start, e.TimeZone))
+	iw.line(icalDateTime("DTEND", e.End, e.TimeZone))
+}
+
+// exceptionStarts возвращает начала исключенных и отмененных экземпляров для EXDATE
+func (e *Event) exceptionStarts(rule *RRule) []time.Time {
+	var starts []time.Time
+	add := func(id string) {
+		if start, ok := e.findOccurrence(rule, id); ok {
+			starts = append(starts, start)
+		}
+	}
+	for _, id := range e.ExDates {
+		add(id)
+	}
+	for _, o := range e.Overrides {
+		if o.Cancelled {
+			add(o.RecurrenceID)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+// icalProperty — строка содержимого iCalendar: имя, параметры и значение
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icalComponent — компонент iCalendar (VEVENT, VTODO и т.д.) со свойствами и вложенными компонентами
+type icalComponent struct {
+	name       string
+	props      []icalProperty
+	components []*icalComponent
+}
+
+func (c *icalComponent) get(name string) *icalProperty {
+	for i := range c.props {
+		if c.props[i].name == name {
+			return &c.props[i]
+		}
+	}
+	return nil
+}
+
+func (c *icalComponent) all(name string) []icalProperty {
+	var res []icalProperty
+	for _, p := range c.props {
+		if p.name == name {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// readICalLines читает строки содержимого, склеивая перенесенные строки
+func readICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalProperty разбирает строку вида NAME;PARAM=VALUE;PARAM="V:A":значение
+func parseICalProperty(line string) (icalProperty, error) {
+	prop := icalProperty{params: make(map[string]string)}
+	inQuotes := false
+	nameEnd, valueStart := -1, -1
+	for i := 0; i < len(line) && valueStart < 0; i++ {
+		switch line[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ';':
+			if !inQuotes && nameEnd < 0 {
+				nameEnd = i
+			}
+		case ':':
+			if !inQuotes {
+				valueStart = i + 1
+				if nameEnd < 0 {
+					nameEnd = i
+				}
+			}
+		}
+	}
+	if valueStart < 0 {
+		return prop, fmt.Errorf("invalid content line %q", line)
+	}
+	prop.name = strings.ToUpper(line[:nameEnd])
+	prop.value = line[valueStart:]
+	if nameEnd < valueStart-1 {
+		for _, param := range splitQuoted(line[nameEnd+1:valueStart-1], ';') {
+			key, value, _ := strings.Cut(param, "=")
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return prop, nil
+}
+
+// splitQuoted делит строку по sep, не заглядывая внутрь кавычек
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitICalList разбивает значение-список (CATEGORIES) по запятым, не экранированным обратной косой чертой
+func splitICalList(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseICalendar разбирает поток iCalendar в дерево компонентов и возвращает VCALENDAR
+func parseICalendar(r io.Reader) (*icalComponent, error) {
+	lines, err := readICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *icalComponent
+	var stack []*icalComponent
+	for _, line := range lines {
+		prop, err := parseICalProperty(line)
+		if err != nil {
+			return nil, err
+		}
+		switch prop.name {
+		case "BEGIN":
+			c := &icalComponent{name: strings.ToUpper(prop.value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.components = append(parent.components, c)
+			} else if root == nil {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(prop.value) {
+				return nil, fmt.Errorf("unexpected END:%s", prop.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("property %s outside of a component", prop.name)
+			}
+			c := stack[len(stack)-1]
+			c.props = append(c.props, prop)
+		}
+	}
+	if root == nil || root.name != "VCALENDAR" {
+		return nil, errors.New("no VCALENDAR found")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated component %s", stack[len(stack)-1].name)
+	}
+	return root, nil
+}
+
+var icalTextUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescapeICalText(s string) string {
+	return icalTextUnescaper.Replace(s)
+}
+
+// icalTime — разобранное значение DATE или DATE-TIME
+type icalTime struct {
+	time time.Time
+	date bool   // значение типа DATE (событие на весь день)
+	tz   string // имя пояса из TZID
+}
+
+// parseICalTime разбирает значение DTSTART, DTEND, EXDATE или RECURRENCE-ID.
+// Время без Z и TZID («плавающее») считается заданным в UTC.
+func parseICalTime(prop icalProperty, value string) (icalTime, error) {
+	if prop.params["VALUE"] == "DATE" || len(value) == len(icalDateLayout) {
+		t, err := time.Parse(icalDateLayout, value)
+		if err != nil {
+			return icalTime{}, fmt.Errorf("invalid date %q", value)
+		}
+		return icalTime{time: t, date: true}, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalUTCLayout, value)
+		if err != nil {
+			return icalTime{}, fmt.Errorf("invalid date-time %q", value)
+		}
+		return icalTime{time: t}, nil
+	}
+	loc := time.UTC
+	tz := prop.params["TZID"]
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return icalTime{}, fmt.Errorf("unknown time zone %q", tz)
+		}
+	}
+	t, err := time.ParseInLocation(icalLocalLayout, value, loc)
+	if err != nil {
+		return icalTime{}, fmt.Errorf("invalid date-time %q", value)
+	}
+	return icalTime{time: t, tz: tz}, nil
+}
+
+// parseICalDuration разбирает DURATION вида P1D, PT1H30M, P1W
+func parseICalDuration(s string) (time.Duration, error) {
+	orig := s
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+	}
+	s = strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		num = ""
+		switch {
+		case r == 'W' && !inTime:
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case r == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	return sign * d, nil
+}
+
+// formatICalDuration записывает интервал в формате DURATION, например -PT15M или P1D
+func formatICalDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	if days := d / (24 * time.Hour); days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		d -= days * 24 * time.Hour
+	}
+	if d > 0 {
+		b.WriteString("T")
+		for _, unit := range []struct {
+			size time.Duration
+			name string
+		}{{time.Hour, "H"}, {time.Minute, "M"}, {time.Second, "S"}} {
+			if n := d / unit.size; n > 0 {
+				fmt.Fprintf(&b, "%d%s", n, unit.name)
+				d -= n * unit.size
+			}
+		}
+	}
+	return b.String()
+}
+
+// valarmReminders переводит VALARM события в напоминания.
+// Поддерживаются только триггеры относительно начала события, срабатывающие до него или в момент начала.
+func valarmReminders(c *icalComponent) []Duration {
+	var reminders []Duration
+	seen := make(map[Duration]bool)
+	for _, alarm := range c.components {
+		if alarm.name != "VALARM" {
+			continue
+		}
+		trigger := alarm.get("TRIGGER")
+		if trigger == nil || strings.EqualFold(trigger.params["VALUE"], "DATE-TIME") || strings.EqualFold(trigger.params["RELATED"], "END") {
+			continue
+		}
+		d, err := parseICalDuration(trigger.value)
+		if err != nil || d > 0 {
+			continue
+		}
+		before := Duration(-d)
+		if seen[before] || len(reminders) == maxReminders || validateReminders([]Duration{before}) != nil {
+			continue
+		}
+		seen[before] = true
+		reminders = append(reminders, before)
+	}
+	return reminders
+}
+
+// SkippedItem описывает компонент iCalendar, который не удалось импортировать
+type SkippedItem struct {
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// ImportResult — итог импорта: ID созданных событий и пропущенные компоненты
+type ImportResult struct {
+	Imported []int         `json:"imported"`
+	Skipped  []SkippedItem `json:"skipped"`
+}
+
+// icalSupportedComponents — вложенные в VCALENDAR компоненты, которые не считаются пропущенными
+var icalSupportedComponents = map[string]bool{
+	"VEVENT":    true,
+	"VTIMEZONE": true,
+}
+
+// ImportICalendar разбирает календарь iCalendar и создает события пользователя через CreateEvent.
+// Экземпляры с RECURRENCE-ID становятся переопределениями основного события с тем же UID.
+// События, UID которых у пользователя уже есть, пропускаются, поэтому повторный импорт безопасен.
+func ImportICalendar(calendar *Calendar, userID string, r io.Reader) (ImportResult, error) {
+	result := ImportResult{Imported: make([]int, 0), Skipped: make([]SkippedItem, 0)}
+	root, err := parseICalendar(r)
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrInvalidICalendar, err)
+	}
+
+	decoded := decodeICalendar(root)
+	result.Skipped = append(result.Skipped, decoded.skipped...)
+	for i, event := range decoded.events {
+		event.UserID = userID
+		if err := ValidateEvent(event); err != nil {
+			result.Skipped = append(result.Skipped, skippedItem(decoded.sources[i], err.Error()))
+			continue
+		}
+		id, err := calendar.CreateEvent(event)
+		if errors.Is(err, ErrDuplicateUID) {
+			result.Skipped = append(result.Skipped, skippedItem(decoded.sources[i], err.Error()))
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to import event: %w", err)
+		}
+		result.Imported = append(result.Imported, id)
+	}
+	return result, nil
+}
+
+// decodedICalendar — события, разобранные из VCALENDAR, вместе с исходными VEVENT и пропущенными компонентами
+type decodedICalendar struct {
+	events  []Event
+	sources []*icalComponent
+	skipped []SkippedItem
+}
+
+// skippedItem описывает пропущенный компонент по его UID и SUMMARY
+func skippedItem(c *icalComponent, reason string) SkippedItem {
+	item := SkippedItem{Reason: reason}
+	if p := c.get("UID"); p != nil {
+		item.UID = unescapeICalText(p.value)
+	}
+	if p := c.get("SUMMARY"); p != nil {
+		item.Summary = unescapeICalText(p.value)
+	}
+	return item
+}
+
+// decodeICalendar переводит VEVENT календаря в события без владельца.
+// Экземпляры с RECURRENCE-ID привязываются к основному событию с тем же UID как переопределения.
+func decodeICalendar(root *icalComponent) decodedICalendar {
+	var res decodedICalendar
+	skip := func(c *icalComponent, reason string) {
+		res.skipped = append(res.skipped, skippedItem(c, reason))
+	}
+
+	// Сначала собираем основные события, затем привязываем к ним переопределения
+	var masters []*icalComponent
+	var overrides []*icalComponent
+	for _, c := range root.components {
+		switch {
+		case !icalSupportedComponents[c.name]:
+			skip(c, fmt.Sprintf("%s components are not supported", c.name))
+		case c.name != "VEVENT":
+		case c.get("RECURRENCE-ID") != nil:
+			overrides = append(overrides, c)
+		default:
+			masters = append(masters, c)
+		}
+	}
+
+	byUID := make(map[string]int)
+	for _, c := range masters {
+		event, err := veventToEvent(c)
+		if err != nil {
+			skip(c, err.Error())
+			continue
+		}
+		if event.UID != "" {
+			if _, dup := byUID[event.UID]; dup {
+				skip(c, "duplicate UID in file")
+				continue
+			}
+			byUID[event.UID] = len(res.events)
+		}
+		res.events = append(res.events, event)
+		res.sources = append(res.sources, c)
+	}
+
+	for _, c := range overrides {
+		uid := ""
+		if p := c.get("UID"); p != nil {
+			uid = unescapeICalText(p.value)
+		}
+		idx, ok := byUID[uid]
+		if !ok || !res.events[idx].IsRecurring() {
+			skip(c, "recurring event for RECURRENCE-ID not found")
+			continue
+		}
+		o, err := veventToOverride(c, &res.events[idx])
+		if err != nil {
+			skip(c, err.Error())
+			continue
+		}
+		res.events[idx].Overrides = append(res.events[idx].Overrides, o)
+	}
+	return res
+}
+
+// veventToEvent переводит VEVENT в событие календаря
+func veventToEvent(c *icalComponent) (Event, error) {
+	var event Event
+	if p := c.get("UID"); p != nil {
+		event.UID = unescapeICalText(p.value)
+	}
+	if p := c.get("SUMMARY"); p != nil {
+		event.Title = unescapeICalText(p.value)
+	}
+	if p := c.get("DESCRIPTION"); p != nil {
+		event.Description = unescapeICalText(p.value)
+	}
+	if p := c.get("STATUS"); p != nil && strings.EqualFold(p.value, "CANCELLED") {
+		return event, errors.New("event is cancelled")
+	}
+	event.Reminders = valarmReminders(c)
+	var categories []string
+	for _, p := range c.all("CATEGORIES") {
+		for _, category := range splitICalList(p.value) {
+			// Метки, которые нельзя сохранить, пропускаются, остальные импортируются
+			category = unescapeICalText(category)
+			if _, err := normalizeTags([]string{category}); err == nil && len(categories) < maxTags {
+				categories = append(categories, category)
+			}
+		}
+	}
+	event.Tags, _ = normalizeTags(categories)
+
+	dtstart := c.get("DTSTART")
+	if dtstart == nil {
+		return event, errors.New("DTSTART is required")
+	}
+	start, err := parseICalTime(*dtstart, dtstart.value)
+	if err != nil {
+		return event, err
+	}
+
+	var end icalTime
+	var hasEnd bool
+	if p := c.get("DTEND"); p != nil {
+		if end, err = parseICalTime(*p, p.value); err != nil {
+			return event, err
+		}
+		if end.date != start.date {
+			return event, errors.New("DTSTART and DTEND must have the same value type")
+		}
+		hasEnd = true
+	} else if p := c.get("DURATION"); p != nil {
+		d, err := parseICalDuration(p.value)
+		if err != nil {
+			return event, err
+		}
+		end = icalTime{time: start.time.Add(d), date: start.date, tz: start.tz}
+		hasEnd = true
+	}
+
+	if start.date {
+		event.Date = start.time.Format(dateLayout)
+		// DTEND события на весь день не включается в событие
+		if hasEnd && end.time.After(start.time.AddDate(0, 0, 1)) {
+			event.EndDate = end.time.AddDate(0, 0, -1).Format(dateLayout)
+		}
+	} else {
+		event.Start = start.time
+		event.TimeZone = start.tz
+		if hasEnd {
+			event.End = end.time
+		}
+	}
+
+	if p := c.get("RRULE"); p != nil {
+		rule, err := ParseRRule(p.value)
+		if err != nil {
+			return event, fmt.Errorf("unsupported RRULE: %v", err)
+		}
+		event.RRule = rule.String()
+		for _, exdate := range c.all("EXDATE") {
+			for _, value := range strings.Split(exdate.value, ",") {
+				t, err := parseICalTime(exdate, value)
+				if err != nil {
+					return event, err
+				}
+				event.ExDates = append(event.ExDates, icalRecurrenceID(&event, t))
+			}
+		}
+	}
+	return event, nil
+}
+
+// veventToOverride переводит VEVENT с RECURRENCE-ID в переопределение экземпляра master
+func veventToOverride(c *icalComponent, master *Event) (Override, error) {
+	var o Override
+	recurrence := c.get("RECURRENCE-ID")
+	rid, err := parseICalTime(*recurrence, recurrence.value)
+	if err != nil {
+		return o, err
+	}
+	o.RecurrenceID = icalRecurrenceID(master, rid)
+
+	if p := c.get("STATUS"); p != nil && strings.EqualFold(p.value, "CANCELLED") {
+		o.Cancelled = true
+		return o, nil
+	}
+
+	occ, err := veventToEvent(c)
+	if err != nil {
+		return o, err
+	}
+	if occ.Title != master.Title {
+		o.Title = occ.Title
+	}
+	if occ.Description != master.Description {
+		o.Description = occ.Description
+	}
+	if master.AllDay {
+		if occ.Date != "" && occ.Date != rid.time.Format(dateLayout) {
+			o.Date = occ.Date
+		}
+	} else {
+		o.Start = occ.Start
+		o.End = occ.End
+	}
+	return o, nil
+}
+
+// icalRecurrenceID переводит значение EXDATE или RECURRENCE-ID в recurrence_id экземпляра
+func icalRecurrenceID(event *Event, t icalTime) string {
+	if t.date || event.AllDay {
+		return t.time.Format(dateLayout)
+	}
+	return t.time.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICalendarRoundTrip(t *testing.T) {
+	mustLoadLocation(t, "Europe/Moscow")
+	source, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	source.CreateEvent(Event{UserID: "user1", Title: "Trip; with, escapes", Description: "line 1\nline 2", Date: "2024-03-09", EndDate: "2024-03-12"})
+	source.CreateEvent(Event{UserID: "user1", Title: "Dentist", Start: start, End: start.Add(45 * time.Minute), TimeZone: "Europe/Moscow"})
+	source.CreateEvent(Event{
+		UserID:  "user1",
+		Title:   "Stand-up",
+		Start:   start,
+		End:     start.Add(15 * time.Minute),
+		RRule:   "FREQ=DAILY;COUNT=5",
+		ExDates: []string{"2024-03-05T10:00:00Z"},
+		Overrides: []Override{
+			{RecurrenceID: "2024-03-06T10:00:00Z", Title: "Planning", Start: start.AddDate(0, 0, 2).Add(2 * time.Hour)},
+			{RecurrenceID: "2024-03-07T10:00:00Z", Cancelled: true},
+		},
+	})
+	source.CreateEvent(Event{UserID: "user2", Title: "Not exported", Date: "2024-03-04"})
+
+	var buf bytes.Buffer
+	if err := WriteICalendar(&buf, source.GetUserEvents("user1", time.Time{}, time.Time{}), start); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	ics := buf.String()
+	for _, want := range []string{
+		"DTSTART;VALUE=DATE:20240309\r\n",
+		"DTEND;VALUE=DATE:20240313\r\n",
+		`SUMMARY:Trip\; with\, escapes`,
+		"DTSTART;TZID=Europe/Moscow:20240304T130000\r\n",
+		"RRULE:FREQ=DAILY;COUNT=5\r\n",
+		"EXDATE:20240305T100000Z\r\n",
+		"EXDATE:20240307T100000Z\r\n",
+		"RECURRENCE-ID:20240306T100000Z\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("Expected export to contain %q, got:\n%s", want, ics)
+		}
+	}
+	if strings.Contains(ics, "Not exported") {
+		t.Error("Export must contain only the requested user's events")
+	}
+
+	target, _ := NewCalendar(nil)
+	result, err := ImportICalendar(target, "user3", strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if len(result.Imported) != 3 || len(result.Skipped) != 0 {
+		t.Fatalf("Expected 3 imported and 0 skipped, got %+v", result)
+	}
+
+	events, _ := target.GetEventsForWeek("2024-03-04", "user3")
+	got := titles(events)
+	if got["Stand-up"] != 2 || got["Planning"] != 1 || got["Dentist"] != 1 || got["Trip; with, escapes"] != 1 {
+		t.Errorf("Unexpected events after round trip: %v", got)
+	}
+	for _, e := range events {
+		if e.Title == "Trip; with, escapes" && (e.EndDate != "2024-03-12" || e.Description != "line 1\nline 2") {
+			t.Errorf("Expected multi-day event with description, got %+v", e)
+		}
+		if e.Title == "Dentist" && (e.TimeZone != "Europe/Moscow" || e.End.Sub(e.Start) != 45*time.Minute) {
+			t.Errorf("Expected 45 minute event in Europe/Moscow, got %+v", e)
+		}
+		if e.Title == "Planning" && e.Start.UTC().Hour() != 12 {
+			t.Errorf("Expected moved occurrence at 12:00 UTC, got %s", e.Start)
+		}
+	}
+
+	// Повторный импорт того же файла ничего не дублирует
+	result, err = ImportICalendar(target, "user3", strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if len(result.Imported) != 0 || len(result.Skipped) != 3 {
+		t.Errorf("Expected all events to be skipped on re-import, got %+v", result)
+	}
+}
+
+func TestImportICalendarSkipsUnsupported(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Google Inc//Google Calendar 70.9054//EN",
+		"BEGIN:VEVENT",
+		"UID:folded@example.com",
+		"DTSTART:20240310T090000Z",
+		"DURATION:PT1H30M",
+		"SUMMARY:A very long title that Google folds across several lines because it",
+		"  exceeds seventy-five octets",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT10M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:no-start@example.com",
+		"SUMMARY:No start",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:setpos@example.com",
+		"DTSTART;VALUE=DATE:20240301",
+		"RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+		"SUMMARY:Last working day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:orphan@example.com",
+		"RECURRENCE-ID:20240301T090000Z",
+		"DTSTART:20240301T100000Z",
+		"SUMMARY:Orphan",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:windows@example.com",
+		`DTSTART;TZID="Pacific Standard Time":20240301T090000`,
+		"SUMMARY:Outlook zone",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:todo@example.com",
+		"SUMMARY:Buy milk",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	calendar, _ := NewCalendar(nil)
+	result, err := ImportICalendar(calendar, "user1", strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if len(result.Imported) != 1 {
+		t.Fatalf("Expected 1 imported event, got %+v", result)
+	}
+
+	event := calendar.events[result.Imported[0]]
+	if event.Title != "A very long title that Google folds across several lines because it exceeds seventy-five octets" {
+		t.Errorf("Expected unfolded title, got %q", event.Title)
+	}
+	if event.End.Sub(event.Start) != 90*time.Minute || event.UID != "folded@example.com" {
+		t.Errorf("Expected 90 minute event with UID, got %+v", event)
+	}
+
+	reasons := make(map[string]string)
+	for _, s := range result.Skipped {
+		reasons[s.UID] = s.Reason
+	}
+	for uid, want := range map[string]string{
+		"no-start@example.com": "DTSTART is required",
+		"setpos@example.com":   "unsupported RRULE",
+		"orphan@example.com":   "recurring event for RECURRENCE-ID not found",
+		"windows@example.com":  "unknown time zone",
+		"todo@example.com":     "VTODO components are not supported",
+	} {
+		if !strings.Contains(reasons[uid], want) {
+			t.Errorf("Expected %s to be skipped with %q, got %q", uid, want, reasons[uid])
+		}
+	}
+}
+
+func TestImportICalendarInvalid(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	for _, ics := range []string{
+		"not a calendar",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VEVENT\r\nEND:VEVENT\r\n",
+	} {
+		if _, err := ImportICalendar(calendar, "user1", strings.NewReader(ics)); !errors.Is(err, ErrInvalidICalendar) {
+			t.Errorf("Expected ErrInvalidICalendar for %q, got %v", ics, err)
+		}
+	}
+}
+
+func TestICalendarLineFolding(t *testing.T) {
+	var buf bytes.Buffer
+	title := strings.Repeat("Встреча ", 20)
+	WriteICalendar(&buf, []Event{{ID: 1, Title: title, Date: "2024-03-10", AllDay: true}}, time.Now())
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > icalMaxLine {
+			t.Errorf("Line exceeds %d octets: %q", icalMaxLine, line)
+		}
+	}
+	root, err := parseICalendar(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if got := unescapeICalText(root.components[0].get("SUMMARY").value); got != title {
+		t.Errorf("Expected title to survive folding, got %q", got)
+	}
+}
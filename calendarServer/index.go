@@ -0,0 +1,153 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// allDaySlack — наибольшее смещение часового пояса от UTC.
+// События на весь день индексируются по полуночи UTC, а запрашиваются в поясе пользователя.
+const allDaySlack = 14 * time.Hour
+
+// userIndex — события одного пользователя, упорядоченные по началу.
+// Диапазонный запрос находит первое подходящее событие двоичным поиском и читает только
+// события до конца диапазона: O(log n + k) вместо полного обхода календаря.
+type userIndex struct {
+	single    []*Event       // неповторяющиеся события по возрастанию indexKey, затем ID
+	recurring map[int]*Event // повторяющиеся события раскрываются при каждом запросе
+	// maxDuration — длительность самого длинного события: событие, начавшееся раньше
+	// from - maxDuration, уже не может пересекаться с диапазоном. Не уменьшается при удалении.
+	maxDuration time.Duration
+	terms       map[string]map[int]struct{} // инвертированный индекс: слово -> ID событий
+}
+
+// indexKey возвращает момент начала события для упорядочивания; события на весь день — полночь UTC
+func indexKey(e *Event) time.Time {
+	if e.AllDay {
+		day, _ := time.Parse(dateLayout, e.Date)
+		return day
+	}
+	return e.Start
+}
+
+// indexLess упорядочивает события по началу, а при равном начале — по ID
+func indexLess(a, b *Event) bool {
+	ka, kb := indexKey(a), indexKey(b)
+	if !ka.Equal(kb) {
+		return ka.Before(kb)
+	}
+	return a.ID < b.ID
+}
+
+// count возвращает число событий в индексе; nil-индекс пуст
+func (idx *userIndex) count() int {
+	if idx == nil {
+		return 0
+	}
+	return len(idx.single) + len(idx.recurring)
+}
+
+// indexPut заменяет в индексе old на event (любой из них может быть nil). Вызывается под блокировкой записи.
+func (c *Calendar) indexPut(old, event *Event) {
+	if old != nil {
+		c.indexRemove(old)
+	}
+	if event == nil {
+		return
+	}
+	c.attendeePut(event)
+	if c.index == nil {
+		c.index = make(map[string]*userIndex)
+	}
+	idx := c.index[event.UserID]
+	if idx == nil {
+		idx = &userIndex{recurring: make(map[int]*Event)}
+		c.index[event.UserID] = idx
+	}
+	idx.indexTerms(event)
+
+	if event.IsRecurring() {
+		idx.recurring[event.ID] = event
+		return
+	}
+	start, end := event.interval(time.UTC)
+	idx.maxDuration = max(idx.maxDuration, end.Sub(start))
+	pos := sort.Search(len(idx.single), func(i int) bool { return !indexLess(idx.single[i], event) })
+	idx.single = slices.Insert(idx.single, pos, event)
+}
+
+// indexRemove удаляет событие из индекса
+func (c *Calendar) indexRemove(event *Event) {
+	c.attendeeRemove(event)
+	idx := c.index[event.UserID]
+	if idx == nil {
+		return
+	}
+	idx.removeTerms(event)
+	if _, ok := idx.recurring[event.ID]; ok {
+		delete(idx.recurring, event.ID)
+		return
+	}
+	pos := sort.Search(len(idx.single), func(i int) bool { return !indexLess(idx.single[i], event) })
+	if pos < len(idx.single) && idx.single[pos].ID == event.ID {
+		idx.single = slices.Delete(idx.single, pos, pos+1)
+	}
+}
+
+// userEvents возвращает все события пользователя из индекса в порядке начала, повторяющиеся — в конце
+func (c *Calendar) userEvents(userID string) []*Event {
+	idx := c.index[userID]
+	if idx == nil {
+		return nil
+	}
+	events := make([]*Event, 0, len(idx.single)+len(idx.recurring))
+	events = append(events, idx.single...)
+	for _, event := range idx.recurring {
+		events = append(events, event)
+	}
+	return events
+}
+
+// eventsInRange возвращает события пользователя, пересекающиеся с [from, to), по возрастанию начала.
+// Повторяющиеся события раскрываются в экземпляры. Вызывается под блокировкой.
+func (c *Calendar) eventsInRange(userID string, from, to time.Time) []Event {
+	events := make([]Event, 0)
+	idx := c.index[userID]
+	if idx == nil {
+		return events
+	}
+
+	lo := from.Add(-idx.maxDuration - allDaySlack)
+	hi := to.Add(allDaySlack)
+	first := sort.Search(len(idx.single), func(i int) bool { return !indexKey(idx.single[i]).Before(lo) })
+	for _, event := range idx.single[first:] {
+		if !indexKey(event).Before(hi) {
+			break
+		}
+		if event.overlaps(from, to) {
+			events = append(events, *event)
+		}
+	}
+	for _, event := range idx.recurring {
+		events = append(events, event.occurrencesInRange(from, to)...)
+	}
+
+	sortByStart(events, from.Location())
+	return events
+}
+
+// sortByStart упорядочивает события по началу в поясе loc, затем по ID и recurrence_id
+func sortByStart(events []Event, loc *time.Location) {
+	sort.SliceStable(events, func(i, j int) bool {
+		si, _ := events[i].interval(loc)
+		sj, _ := events[j].interval(loc)
+		if !si.Equal(sj) {
+			return si.Before(sj)
+		}
+		if events[i].ID != events[j].ID {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].RecurrenceID < events[j].RecurrenceID
+	})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// orderedTitles возвращает названия событий в порядке выдачи
+func orderedTitles(events []Event) []string {
+	res := make([]string, len(events))
+	for i, event := range events {
+		res[i] = event.Title
+	}
+	return res
+}
+
+func TestRangeResultsAreSorted(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Evening", Start: day.Add(19 * time.Hour)})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Morning", Start: day.Add(8 * time.Hour), End: day.Add(9 * time.Hour)})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "All day", Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Daily", Start: day.Add(-24*time.Hour + 12*time.Hour), RRule: "FREQ=DAILY"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Conference", Date: "2024-03-01", EndDate: "2024-03-15"})
+	calendar.CreateEvent(Event{UserID: "user2", Title: "Foreign", Start: day.Add(10 * time.Hour)})
+
+	events, _ := calendar.GetEventsForDay("2024-03-10", "user1")
+	want := []string{"Conference", "All day", "Morning", "Daily", "Evening"}
+	if got := orderedTitles(events); !equalStrings(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestIndexFollowsUpdatesAndDeletes(t *testing.T) {
+	calendar := &Calendar{events: make(map[int]*Event), nextID: 1}
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Movable", Date: "2024-03-10"})
+
+	calendar.UpdateEvent(Event{ID: id, UserID: "user1", Title: "Moved", Date: "2024-04-10"})
+	if events, _ := calendar.GetEventsForDay("2024-03-10", "user1"); len(events) != 0 {
+		t.Errorf("Expected old day to be empty, got %v", titles(events))
+	}
+	if events, _ := calendar.GetEventsForDay("2024-04-10", "user1"); len(events) != 1 {
+		t.Errorf("Expected moved event, got %v", titles(events))
+	}
+
+	// Событие становится повторяющимся и обратно
+	calendar.UpdateEvent(Event{ID: id, UserID: "user1", Title: "Weekly", Date: "2024-04-10", RRule: "FREQ=WEEKLY"})
+	if events, _ := calendar.GetEventsForDay("2024-04-17", "user1"); len(events) != 1 {
+		t.Errorf("Expected weekly occurrence, got %v", titles(events))
+	}
+	calendar.UpdateEvent(Event{ID: id, UserID: "user1", Title: "Once", Date: "2024-04-10"})
+	if events, _ := calendar.GetEventsForDay("2024-04-17", "user1"); len(events) != 0 {
+		t.Errorf("Expected no occurrences after dropping rrule, got %v", titles(events))
+	}
+
+	calendar.DeleteUserEvent("user1", id)
+	if events := calendar.GetUserEvents("user1", time.Time{}, time.Time{}); len(events) != 0 {
+		t.Errorf("Expected deleted event to leave the index, got %v", titles(events))
+	}
+}
+
+// TestIndexMatchesFullScan сравнивает индексированный поиск с полным перебором на случайных событиях и диапазонах
+func TestIndexMatchesFullScan(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	rng := rand.New(rand.NewSource(1))
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	zones := []*time.Location{time.UTC, mustLoadLocation(t, "Pacific/Kiritimati"), mustLoadLocation(t, "America/Los_Angeles")}
+
+	for i := 0; i < 500; i++ {
+		start := base.Add(time.Duration(rng.Intn(90*24)) * time.Hour)
+		event := Event{UserID: "user1", Title: fmt.Sprintf("Event %d", i)}
+		switch rng.Intn(3) {
+		case 0:
+			event.Date = start.Format(dateLayout)
+			if rng.Intn(4) == 0 {
+				event.EndDate = start.AddDate(0, 0, rng.Intn(20)).Format(dateLayout)
+			}
+		case 1:
+			event.Start = start
+			event.End = start.Add(time.Duration(rng.Intn(72)) * time.Hour)
+		default:
+			event.Start = start
+		}
+		if _, err := calendar.CreateEvent(event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+		loc := zones[rng.Intn(len(zones))]
+		from := base.Add(time.Duration(rng.Intn(100*24)) * time.Hour).In(loc)
+		to := from.Add(time.Duration(1+rng.Intn(10*24)) * time.Hour)
+
+		got, _ := calendar.GetEventsInRange("user1", from, to)
+		var want []Event
+		for _, event := range calendar.events {
+			if event.overlaps(from, to) {
+				want = append(want, *event)
+			}
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Range %s–%s: expected %d events, got %d", from, to, len(want), len(got))
+		}
+		for j := 1; j < len(got); j++ {
+			prev, _ := got[j-1].interval(loc)
+			cur, _ := got[j].interval(loc)
+			if cur.Before(prev) {
+				t.Fatalf("Range %s–%s: events are not sorted at %d", from, to, j)
+			}
+		}
+	}
+}
+
+func TestEventsForRangeHandler(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Second", Start: start.Add(48 * time.Hour)})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "First", Start: start})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Outside", Start: start.AddDate(0, 1, 0)})
+
+	rec := serve(router, http.MethodGet, "/events_for_range?user_id=user1&from=2024-03-10&to=2024-03-12", "", "")
+	var resp struct {
+		Data []Event `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || !equalStrings(orderedTitles(resp.Data), []string{"First", "Second"}) {
+		t.Errorf("Unexpected response %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(router, http.MethodGet, "/api/v1/users/user1/events?from=2024-03-10T08:00:00Z&to=2024-03-10T10:00:00Z", "", "")
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || !equalStrings(orderedTitles(resp.Data), []string{"First"}) {
+		t.Errorf("Unexpected response %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, target := range []string{
+		"/events_for_range?user_id=user1&from=2024-03-10",
+		"/events_for_range?user_id=user1&from=2024-03-12&to=2024-03-10",
+		"/events_for_range?user_id=user1&from=yesterday&to=2024-03-10",
+	} {
+		if rec := serve(router, http.MethodGet, target, "", ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
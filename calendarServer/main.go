@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
+	// Встраиваем базу часовых поясов, чтобы tz работал и в контейнерах без /usr/share/zoneinfo
+	_ "time/tzdata"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -37,12 +47,35 @@ func writeErrorResponse(w http.ResponseWriter, status int, errorMsg string) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
-	})
+// newLogger создает структурированный логгер в формате json или text с минимальным уровнем level
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected json or text)", format)
+	}
+}
+
+// requestLocation возвращает часовой пояс, в котором пользователь смотрит календарь:
+// параметр tz (имя IANA, например Europe/Moscow), по умолчанию UTC
+func requestLocation(r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", tz)
+	}
+	return loc, nil
 }
 
 func main() {
@@ -51,8 +84,67 @@ func main() {
 
 	// Получаем порт из переменной окружения или флага
 	portFlag := flag.String("port", "", "Порт для запуска сервера")
+	grpcPortFlag := flag.String("grpc-port", "", "Порт gRPC API (по умолчанию 9090)")
+	storageFlag := flag.String("storage", "", "Хранилище событий: memory, file или sqlite")
+	holidaysFlag := flag.String("holidays", "", "Файлы производственных календарей .json или .ics через запятую; календарь называется по имени файла")
+	storagePathFlag := flag.String("storage-path", "", "Путь к файлу хранилища (по умолчанию calendar.log или calendar.db)")
+	issueTokenFlag := flag.String("issue-token", "", "Выпустить JWT для указанного пользователя (подписывается JWT_SECRET) и выйти")
+	tokenTTLFlag := flag.Duration("token-ttl", 30*24*time.Hour, "Срок действия JWT, выпускаемого -issue-token (0 — бессрочный)")
+	reminderIntervalFlag := flag.Duration("reminder-interval", 30*time.Second, "Как часто проверять наступившие напоминания")
+	reminderLatenessFlag := flag.Duration("reminder-max-lateness", time.Hour, "Напоминания, опоздавшие больше чем на столько (например, пока сервер был выключен), не отправляются")
+	reminderTZFlag := flag.String("reminder-tz", "UTC", "Часовой пояс, в котором начинаются события на весь день при отправке напоминаний")
+	webhookFlag := flag.String("webhook-url", "", "URL, на который отправляются напоминания (POST JSON)")
+	smtpAddrFlag := flag.String("smtp-addr", "", "SMTP-сервер host:port для напоминаний по почте")
+	smtpFromFlag := flag.String("smtp-from", "calendar@localhost", "Адрес отправителя писем с напоминаниями")
+	smtpDomainFlag := flag.String("smtp-domain", "", "Домен, добавляемый к user_id без @ для получения адреса почты")
+	readTimeoutFlag := flag.Duration("read-timeout", 30*time.Second, "Максимальное время чтения запроса вместе с телом")
+	writeTimeoutFlag := flag.Duration("write-timeout", 30*time.Second, "Максимальное время записи ответа")
+	idleTimeoutFlag := flag.Duration("idle-timeout", 2*time.Minute, "Сколько держать открытым простаивающее keep-alive соединение")
+	shutdownTimeoutFlag := flag.Duration("shutdown-timeout", 15*time.Second, "Сколько ждать завершения активных запросов при остановке")
+	maxBodyFlag := flag.Int64("max-body-bytes", 4<<20, "Максимальный размер тела запроса в байтах")
+	userRateFlag := flag.Float64("rate-limit-user", 20, "Сколько запросов в секунду в среднем может делать один пользователь (0 — без ограничения)")
+	userBurstFlag := flag.Int("rate-burst-user", 40, "Сколько запросов подряд может сделать один пользователь")
+	ipRateFlag := flag.Float64("rate-limit-ip", 50, "Сколько запросов в секунду в среднем может приходить с одного IP-адреса (0 — без ограничения)")
+	ipBurstFlag := flag.Int("rate-burst-ip", 100, "Сколько запросов подряд может прийти с одного IP-адреса")
+	maxEventsFlag := flag.Int("max-events-per-user", 10000, "Сколько событий может хранить один пользователь и сколько удаленных событий хранится в его корзине (0 — без ограничения)")
+	maxDescriptionFlag := flag.Int("max-description-length", 10000, "Максимальная длина описания события в символах (0 — без ограничения)")
+	logFormatFlag := flag.String("log-format", "json", "Формат логов: json или text")
+	logLevelFlag := flag.String("log-level", "info", "Минимальный уровень логов: debug, info, warn или error")
+	insecureFlag := flag.Bool("insecure-no-auth", false, "Запустить сервер без аутентификации, если не заданы API_KEYS и JWT_SECRET: user_id берется из запроса, и любой клиент действует от имени любого пользователя")
 	flag.Parse()
 
+	logger, err := newLogger(os.Stderr, *logFormatFlag, *logLevelFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Стандартный log тоже пишет через этот логгер, поэтому все сообщения сервера в одном формате
+	slog.SetDefault(logger)
+
+	// Секреты передаются только через окружение, чтобы не светиться в списке процессов
+	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
+	if *issueTokenFlag != "" {
+		if len(jwtSecret) == 0 {
+			log.Fatal("JWT_SECRET is required to issue tokens")
+		}
+		token, err := SignJWT(jwtSecret, *issueTokenFlag, *tokenTTLFlag, time.Now())
+		if err != nil {
+			log.Fatalf("Не удалось выпустить токен: %v", err)
+		}
+		fmt.Println(token)
+		return
+	}
+	apiKeys, err := ParseAPIKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		log.Fatalf("Некорректная переменная API_KEYS: %v", err)
+	}
+	auth := NewAuthenticator(apiKeys, jwtSecret)
+	if !auth.Enabled() {
+		if !*insecureFlag {
+			log.Fatal("Не заданы API_KEYS или JWT_SECRET: задайте их или запустите сервер с -insecure-no-auth")
+		}
+		log.Println("Аутентификация выключена (-insecure-no-auth): любой клиент действует от имени любого пользователя")
+	}
+
 	port := os.Getenv("PORT")
 	if *portFlag != "" {
 		port = *portFlag
@@ -60,30 +152,216 @@ func main() {
 	if port == "" {
 		port = "8080"
 	}
+	grpcPort := os.Getenv("GRPC_PORT")
+	if *grpcPortFlag != "" {
+		grpcPort = *grpcPortFlag
+	}
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+
+	storage := os.Getenv("STORAGE")
+	if *storageFlag != "" {
+		storage = *storageFlag
+	}
+	storagePath := os.Getenv("STORAGE_PATH")
+	if *storagePathFlag != "" {
+		storagePath = *storagePathFlag
+	}
+
+	// Производственные календари загружаются до событий, которые на них ссылаются
+	holidays := os.Getenv("HOLIDAYS")
+	if *holidaysFlag != "" {
+		holidays = *holidaysFlag
+	}
+	for _, path := range strings.Split(holidays, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		h, err := LoadHolidayFile(path)
+		if err != nil {
+			log.Fatalf("Не удалось загрузить производственный календарь: %v", err)
+		}
+		RegisterHolidayCalendar(h)
+		log.Printf("Загружен производственный календарь %s", h.Name)
+	}
+
+	repo, err := OpenRepository(storage, storagePath)
+	if err != nil {
+		log.Fatalf("Не удалось открыть хранилище: %v", err)
+	}
+	calendar, err := NewCalendar(repo)
+	if err != nil {
+		log.Fatalf("Не удалось загрузить события: %v", err)
+	}
+	// Без производственного календаря события с on_holiday молча перестали бы пропускать и переносить экземпляры
+	if missing := calendar.MissingHolidayCalendars(); len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name, count := range missing {
+			names = append(names, fmt.Sprintf("%s (%d events)", name, count))
+		}
+		sort.Strings(names)
+		log.Fatalf("События ссылаются на незагруженные производственные календари: %s; добавьте их файлы в -holidays", strings.Join(names, ", "))
+	}
+	if err := calendar.SetQuota(Quota{MaxEvents: *maxEventsFlag, MaxDescriptionLength: *maxDescriptionFlag}); err != nil {
+		log.Fatalf("Не удалось очистить корзину: %v", err)
+	}
+
+	reminderLoc, err := time.LoadLocation(*reminderTZFlag)
+	if err != nil {
+		log.Fatalf("Неизвестный часовой пояс -reminder-tz: %v", err)
+	}
+	webhookURL := os.Getenv("REMINDER_WEBHOOK_URL")
+	if *webhookFlag != "" {
+		webhookURL = *webhookFlag
+	}
+	smtpAddr := os.Getenv("SMTP_ADDR")
+	if *smtpAddrFlag != "" {
+		smtpAddr = *smtpAddrFlag
+	}
+	notifiers := MultiNotifier{NewLogNotifier(nil)}
+	if webhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(webhookURL, 10*time.Second))
+	}
+	if smtpAddr != "" {
+		notifiers = append(notifiers, NewSMTPNotifier(smtpAddr, *smtpFromFlag, *smtpDomainFlag, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD")))
+	}
+	// SIGINT и SIGTERM запускают плавную остановку
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scheduler := NewReminderScheduler(calendar, notifiers, *reminderIntervalFlag, *reminderLatenessFlag, reminderLoc)
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.Run(ctx)
+	}()
+
+	limiter := NewRateLimiter(RateLimit{Rate: *userRateFlag, Burst: *userBurstFlag}, RateLimit{Rate: *ipRateFlag, Burst: *ipBurstFlag})
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%s", port),
+		Handler:           limitRequestBody(newRouter(calendar, auth, limiter), *maxBodyFlag),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       *readTimeoutFlag,
+		WriteTimeout:      *writeTimeoutFlag,
+		IdleTimeout:       *idleTimeoutFlag,
+	}
+
+	// Потоки изменений бесконечны: закрываем их в начале остановки, иначе Shutdown ждал бы их до таймаута
+	server.RegisterOnShutdown(calendar.StopChangeFeed)
+
+	// gRPC API для внутренних сервисов работает на отдельном порту поверх того же календаря
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
+	if err != nil {
+		log.Fatalf("Не удалось открыть порт gRPC: %v", err)
+	}
+	grpcServer := newGRPCServer(calendar, auth, limiter)
+
+	serverErr := make(chan error, 2)
+	go func() {
+		log.Printf("Сервер запущен на порту %s", port)
+		serverErr <- server.ListenAndServe()
+	}()
+	go func() {
+		log.Printf("gRPC API запущен на порту %s", grpcPort)
+		serverErr <- grpcServer.Serve(grpcListener)
+	}()
+
+	select {
+	case err := <-serverErr:
+		// Сервер не смог запуститься (например, порт занят): хранилище все равно закрываем
+		log.Printf("Ошибка сервера: %v", err)
+		stop()
+		grpcServer.Stop()
+		server.Close()
+		<-schedulerDone
+		calendar.Close()
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	log.Println("Остановка сервера...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeoutFlag)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Не все запросы завершились за %s: %v", *shutdownTimeoutFlag, err)
+		server.Close()
+	}
+	// Потоки Watch закончились вместе с лентой изменений при остановке HTTP-сервера
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		log.Printf("Не все вызовы gRPC завершились за %s", *shutdownTimeoutFlag)
+		grpcServer.Stop()
+	}
+	<-schedulerDone
+	// Закрываем хранилище только после завершения запросов и планировщика, чтобы все записи попали на диск
+	if err := calendar.Close(); err != nil {
+		log.Printf("Не удалось закрыть хранилище: %v", err)
+	}
+	log.Println("Сервер остановлен")
+}
 
+// newRouter собирает маршруты сервера календаря; limiter nil — без ограничения частоты запросов
+func newRouter(calendar *Calendar, auth *Authenticator, limiter *RateLimiter) *mux.Router {
+	metrics := NewMetrics(calendar)
 	r := mux.NewRouter()
 	r.Use(loggingMiddleware)
-	calendar := &Calendar{
-		events: make(map[int]*Event),
-		nextID: 1,
-	}
+	r.Use(metrics.middleware)
+	r.Use(recoveryMiddleware)
+	r.Use(limiter.ipMiddleware)
+	r.Use(authMiddleware(auth))
+	r.Use(limiter.userMiddleware)
+	// Параметры и тела запросов проверяются по openapi.json
+	r.Use(apiSpecification.middleware)
+
+	// Служебные маршруты доступны без токена (см. publicPaths)
+	r.HandleFunc("/healthz", healthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", readyzHandler(calendar)).Methods("GET")
+	r.Handle("/metrics", metrics).Methods("GET")
+	r.Handle("/openapi.json", apiSpecification).Methods("GET")
+	registerAPI(r, calendar)
+
+	// Старые RPC-маршруты оставлены для совместимости
 	r.HandleFunc("/create_event", createEventHandler(calendar)).Methods("POST")
 	r.HandleFunc("/update_event", updateEventHandler(calendar)).Methods("POST")
 	r.HandleFunc("/delete_event", deleteEventHandler(calendar)).Methods("POST")
 	r.HandleFunc("/events_for_day", eventsForDayHandler(calendar)).Methods("GET")
 	r.HandleFunc("/events_for_week", eventsForWeekHandler(calendar)).Methods("GET")
 	r.HandleFunc("/events_for_month", eventsForMonthHandler(calendar)).Methods("GET")
+	r.HandleFunc("/events_for_range", eventsForRangeHandler(calendar)).Methods("GET")
+	r.HandleFunc("/export_ics", exportICSHandler(calendar)).Methods("GET")
+	r.HandleFunc("/import_ics", importICSHandler(calendar)).Methods("POST")
+
+	// CalDAV для синхронизации с календарями телефонов и почтовых клиентов
+	caldav := NewCalDAVHandler(calendar, "/caldav")
+	r.PathPrefix("/caldav/").Handler(caldav)
+	r.Handle("/.well-known/caldav", http.RedirectHandler("/caldav/", http.StatusMovedPermanently))
+	return r
+}
 
-	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Сервер запущен на порту %s", port)
-	log.Fatal(http.ListenAndServe(addr, r))
+// writeLegacyError отвечает на ошибку изменения события в старых маршрутах:
+// пересечение по времени — 409 со списком конфликтов, ошибки доступа и исчерпанный лимит событий — 401/403,
+// слишком длинное описание — 400, несовпадение версии с If-Match — 412, остальное — 503, как раньше
+func writeLegacyError(w http.ResponseWriter, err error) {
+	var overlap *OverlapError
+	if errors.As(err, &overlap) {
+		writeOverlapError(w, overlap)
+		return
+	}
+	writeErrorResponse(w, errorStatus(err, http.StatusServiceUnavailable), err.Error())
 }
 
 func createEventHandler(calendar *Calendar) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "Failed to read request body")
+			writeBodyError(w, err, http.StatusInternalServerError, "Failed to read request body")
 			return
 		}
 		defer r.Body.Close()
@@ -95,15 +373,26 @@ func createEventHandler(calendar *Calendar) http.HandlerFunc {
 			return
 		}
 
+		// Владелец события — пользователь из токена, если аутентификация включена
+		data.UserID, err = requestUser(r, data.UserID)
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+
 		// Валидация события
 		if err := ValidateEvent(data); err != nil {
 			writeErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		opts, ok := requestWriteOptions(w, r)
+		if !ok {
+			return
+		}
 
-		id, err := calendar.CreateEvent(data)
+		id, err := calendar.CreateEventWithOptions(data, opts)
 		if err != nil {
-			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+			writeLegacyError(w, err)
 			return
 		}
 
@@ -116,7 +405,7 @@ func updateEventHandler(calendar *Calendar) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "Failed to read request body")
+			writeBodyError(w, err, http.StatusInternalServerError, "Failed to read request body")
 			return
 		}
 		defer r.Body.Close()
@@ -128,15 +417,26 @@ func updateEventHandler(calendar *Calendar) http.HandlerFunc {
 			return
 		}
 
+		// Владелец события — пользователь из токена, если аутентификация включена
+		data.UserID, err = requestUser(r, data.UserID)
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+
 		// Валидация события
 		if err := ValidateEvent(data); err != nil {
 			writeErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		opts, ok := requestWriteOptions(w, r)
+		if !ok {
+			return
+		}
 
-		err = calendar.UpdateEvent(data)
+		err = calendar.UpdateEventWithOptions(data, opts)
 		if err != nil {
-			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+			writeLegacyError(w, err)
 			return
 		}
 
@@ -149,13 +449,14 @@ func deleteEventHandler(calendar *Calendar) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "Failed to read request body")
+			writeBodyError(w, err, http.StatusInternalServerError, "Failed to read request body")
 			return
 		}
 		defer r.Body.Close()
 
 		var data struct {
-			EventID int `json:"event_id"`
+			EventID int    `json:"event_id"`
+			UserID  string `json:"user_id"`
 		}
 		err = json.Unmarshal(body, &data)
 		if err != nil {
@@ -168,9 +469,19 @@ func deleteEventHandler(calendar *Calendar) http.HandlerFunc {
 			return
 		}
 
-		err = calendar.DeleteEvent(data.EventID)
+		userID, err := requestUser(r, data.UserID)
 		if err != nil {
-			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		// Без пользователя нельзя проверить владельца, поэтому удаление не выполняется
+		if userID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "user_id is required")
+			return
+		}
+		opts := WriteOptions{IfVersion: parseIfMatch(r.Header.Get("If-Match"))}
+		if err := calendar.DeleteUserEventWithOptions(userID, data.EventID, opts); err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusServiceUnavailable), err.Error())
 			return
 		}
 
@@ -188,25 +499,99 @@ func eventsForDayHandler(calendar *Calendar) http.HandlerFunc {
 			return
 		}
 
-		userID := r.URL.Query().Get("user_id")
+		userID, err := requestUser(r, r.URL.Query().Get("user_id"))
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
 		if userID == "" {
 			http.Error(w, "User ID is required", http.StatusBadRequest)
 			return
 		}
 
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Валидация даты
-		if _, err := time.Parse("2006-01-02", date); err != nil {
+		from, to, err := DayRange(date, loc)
+		if err != nil {
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD")
 			return
 		}
 
-		events, err := calendar.GetEventsForDay(date, userID)
+		opts, err := parseListOptions(r, 0)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := calendar.GetEventsInRange(userID, from, to)
 		if err != nil {
 			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
 			return
 		}
 
-		writeResponse(w, http.StatusOK, Response{Message: "Events for day", Data: events})
+		writeEventPage(w, r, "Events for day", events, opts, startKey(loc))
+	}
+}
+
+// eventsForRangeHandler возвращает события за произвольный период from–to:
+// даты YYYY-MM-DD (to включительно) или моменты RFC 3339
+func eventsForRangeHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fromParam, toParam := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		if fromParam == "" || toParam == "" {
+			http.Error(w, "from and to are required", http.StatusBadRequest)
+			return
+		}
+
+		userID, err := requestUser(r, r.URL.Query().Get("user_id"))
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		if userID == "" {
+			http.Error(w, "User ID is required", http.StatusBadRequest)
+			return
+		}
+
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		from, err := parseRangeBound(fromParam, loc, false)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		to, err := parseRangeBound(toParam, loc, true)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !to.After(from) {
+			writeErrorResponse(w, http.StatusBadRequest, "to must be after from")
+			return
+		}
+
+		opts, err := parseListOptions(r, 0)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := calendar.GetEventsInRange(userID, from, to)
+		if err != nil {
+			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		writeEventPage(w, r, "Events for range", events, opts, startKey(loc))
 	}
 }
 
@@ -218,25 +603,48 @@ func eventsForWeekHandler(calendar *Calendar) http.HandlerFunc {
 			return
 		}
 
-		userID := r.URL.Query().Get("user_id")
+		userID, err := requestUser(r, r.URL.Query().Get("user_id"))
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
 		if userID == "" {
 			http.Error(w, "User ID is required", http.StatusBadRequest)
 			return
 		}
 
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		weekStart, err := requestWeekStart(r, calendar, userID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Валидация даты
-		if _, err := time.Parse("2006-01-02", date); err != nil {
+		from, to, err := WeekRange(date, loc, weekStart)
+		if err != nil {
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD")
 			return
 		}
 
-		events, err := calendar.GetEventsForWeek(date, userID)
+		opts, err := parseListOptions(r, 0)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := calendar.GetEventsInRange(userID, from, to)
 		if err != nil {
 			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
 			return
 		}
 
-		writeResponse(w, http.StatusOK, Response{Message: "Events for week", Data: events})
+		writeEventPage(w, r, "Events for week", events, opts, startKey(loc))
 	}
 }
 
@@ -248,24 +656,131 @@ func eventsForMonthHandler(calendar *Calendar) http.HandlerFunc {
 			return
 		}
 
-		userID := r.URL.Query().Get("user_id")
+		userID, err := requestUser(r, r.URL.Query().Get("user_id"))
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
 		if userID == "" {
 			http.Error(w, "User ID is required", http.StatusBadRequest)
 			return
 		}
 
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Валидация даты
-		if _, err := time.Parse("2006-01-02", date); err != nil {
+		from, to, err := MonthRange(date, loc)
+		if err != nil {
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD")
 			return
 		}
 
-		events, err := calendar.GetEventsForMonth(date, userID)
+		opts, err := parseListOptions(r, 0)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := calendar.GetEventsInRange(userID, from, to)
+		if err != nil {
+			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		writeEventPage(w, r, "Events for month", events, opts, startKey(loc))
+	}
+}
+
+// exportICSHandler выгружает события пользователя в .ics.
+// Необязательные from и to (YYYY-MM-DD, включительно) ограничивают выгрузку событиями этого периода.
+func exportICSHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := requestUser(r, r.URL.Query().Get("user_id"))
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		if userID == "" {
+			http.Error(w, "User ID is required", http.StatusBadRequest)
+			return
+		}
+
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var from, to time.Time
+		fromParam, toParam := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		if (fromParam == "") != (toParam == "") {
+			writeErrorResponse(w, http.StatusBadRequest, "from and to must be set together")
+			return
+		}
+		if fromParam != "" {
+			var errFrom, errTo error
+			from, _, errFrom = DayRange(fromParam, loc)
+			_, to, errTo = DayRange(toParam, loc)
+			if errFrom != nil || errTo != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD")
+				return
+			}
+			if !to.After(from) {
+				writeErrorResponse(w, http.StatusBadRequest, "to must not be before from")
+				return
+			}
+		}
+
+		events := calendar.GetUserEvents(userID, from, to)
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", userID+".ics"))
+		if err := WriteICalendar(w, events, time.Now()); err != nil {
+			log.Printf("Failed to write iCalendar: %v", err)
+		}
+	}
+}
+
+// importICSHandler импортирует события пользователя из .ics:
+// файл передается телом запроса или полем file формы multipart/form-data
+func importICSHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := requestUser(r, r.URL.Query().Get("user_id"))
+		if err != nil {
+			writeErrorResponse(w, errorStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		if userID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "user ID is required")
+			return
+		}
+		defer r.Body.Close()
+
+		var body io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				writeBodyError(w, err, http.StatusBadRequest, "file is required")
+				return
+			}
+			defer file.Close()
+			body = file
+		}
+
+		result, err := ImportICalendar(calendar, userID, body)
+		if errors.Is(err, ErrInvalidICalendar) {
+			writeBodyError(w, err, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err != nil {
 			writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
 			return
 		}
 
-		writeResponse(w, http.StatusOK, Response{Message: "Events for month", Data: events})
+		log.Printf("Imported %d events for %s, skipped %d", len(result.Imported), userID, len(result.Skipped))
+		writeResponse(w, http.StatusOK, Response{Message: "Events imported", Data: result})
 	}
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// durationBuckets — границы гистограммы длительности запросов в секундах (как в клиентах Prometheus по умолчанию)
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestLabels — метки счетчика запросов. route — шаблон маршрута, а не путь,
+// чтобы ID событий и пользователей не плодили временные ряды.
+type requestLabels struct {
+	method string
+	route  string
+	status int
+}
+
+type routeLabels struct {
+	method string
+	route  string
+}
+
+// histogram — накопленные значения гистограммы: counts[i] — число наблюдений не больше durationBuckets[i]
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range durationBuckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Metrics собирает метрики сервера и отдает их в текстовом формате Prometheus
+type Metrics struct {
+	calendar  *Calendar
+	start     time.Time
+	mu        sync.Mutex
+	requests  map[requestLabels]uint64
+	durations map[routeLabels]*histogram
+}
+
+// NewMetrics создает метрики; calendar нужен для числа хранимых событий и состояния хранилища
+func NewMetrics(calendar *Calendar) *Metrics {
+	return &Metrics{
+		calendar:  calendar,
+		start:     time.Now(),
+		requests:  make(map[requestLabels]uint64),
+		durations: make(map[routeLabels]*histogram),
+	}
+}
+
+// observe учитывает завершенный запрос
+func (m *Metrics) observe(method, route string, status int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestLabels{method, route, status}]++
+	key := routeLabels{method, route}
+	h := m.durations[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(durationBuckets))}
+		m.durations[key] = h
+	}
+	h.observe(elapsed.Seconds())
+}
+
+// middleware считает запросы и их длительность по маршрутам mux
+func (m *Metrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		m.observe(r.Method, route, rec.status, time.Since(start))
+	})
+}
+
+// WriteTo выводит метрики в текстовом формате Prometheus 0.0.4
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	m.mu.Lock()
+	requestKeys := make([]requestLabels, 0, len(m.requests))
+	for key := range m.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, c := requestKeys[i], requestKeys[j]
+		if a.route != c.route {
+			return a.route < c.route
+		}
+		if a.method != c.method {
+			return a.method < c.method
+		}
+		return a.status < c.status
+	})
+	b.WriteString("# HELP calendar_http_requests_total Total number of HTTP requests by route, method and status.\n")
+	b.WriteString("# TYPE calendar_http_requests_total counter\n")
+	for _, key := range requestKeys {
+		fmt.Fprintf(&b, "calendar_http_requests_total{method=%s,route=%s,status=\"%d\"} %d\n",
+			labelValue(key.method), labelValue(key.route), key.status, m.requests[key])
+	}
+
+	routeKeys := make([]routeLabels, 0, len(m.durations))
+	for key := range m.durations {
+		routeKeys = append(routeKeys, key)
+	}
+	sort.Slice(routeKeys, func(i, j int) bool {
+		if routeKeys[i].route != routeKeys[j].route {
+			return routeKeys[i].route < routeKeys[j].route
+		}
+		return routeKeys[i].method < routeKeys[j].method
+	})
+	b.WriteString("# HELP calendar_http_request_duration_seconds HTTP request latency by route and method.\n")
+	b.WriteString("# TYPE calendar_http_request_duration_seconds histogram\n")
+	for _, key := range routeKeys {
+		h := m.durations[key]
+		labels := fmt.Sprintf("method=%s,route=%s", labelValue(key.method), labelValue(key.route))
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(&b, "calendar_http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				labels, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(&b, "calendar_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "calendar_http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "calendar_http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	m.mu.Unlock()
+
+	storageUp := 1
+	if m.calendar.Ping() != nil {
+		storageUp = 0
+	}
+	b.WriteString("# HELP calendar_events_stored Number of events currently stored.\n")
+	b.WriteString("# TYPE calendar_events_stored gauge\n")
+	fmt.Fprintf(&b, "calendar_events_stored %d\n", m.calendar.Count())
+	b.WriteString("# HELP calendar_storage_up Whether the event storage is reachable (1) or not (0).\n")
+	b.WriteString("# TYPE calendar_storage_up gauge\n")
+	fmt.Fprintf(&b, "calendar_storage_up %d\n", storageUp)
+	b.WriteString("# HELP calendar_uptime_seconds Time since the server started.\n")
+	b.WriteString("# TYPE calendar_uptime_seconds gauge\n")
+	fmt.Fprintf(&b, "calendar_uptime_seconds %s\n", strconv.FormatFloat(time.Since(m.start).Seconds(), 'f', 3, 64))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labelValue экранирует значение метки по правилам текстового формата Prometheus
+func labelValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// ServeHTTP отдает метрики для сборщика Prometheus
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// healthzHandler — проверка живости: процесс запущен и обрабатывает запросы
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, Response{Message: "ok"})
+}
+
+// readyzHandler — проверка готовности: хранилище событий доступно, иначе 503,
+// чтобы балансировщик перестал направлять запросы на этот экземпляр
+func readyzHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := calendar.Ping(); err != nil {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "storage unavailable: "+err.Error())
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "ready"})
+	}
+}
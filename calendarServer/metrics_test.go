@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(map[string]string{"secret": "user1"}, nil), nil)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "One", Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Two", Date: "2024-03-11"})
+
+	serve(router, http.MethodGet, "/api/v1/users/user1/events/1", "", "secret")
+	serve(router, http.MethodGet, "/api/v1/users/user1/events/2", "", "secret")
+	serve(router, http.MethodGet, "/api/v1/users/user1/events/2", "", "")
+
+	// /metrics доступен без токена
+	rec := serve(router, http.MethodGet, "/metrics", "", "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("Expected Prometheus text format, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE calendar_http_requests_total counter\n",
+		`calendar_http_requests_total{method="GET",route="/api/v1/users/{user}/events/{id:[0-9]+}",status="200"} 2` + "\n",
+		`calendar_http_requests_total{method="GET",route="/api/v1/users/{user}/events/{id:[0-9]+}",status="401"} 1` + "\n",
+		"# TYPE calendar_http_request_duration_seconds histogram\n",
+		`calendar_http_request_duration_seconds_bucket{method="GET",route="/api/v1/users/{user}/events/{id:[0-9]+}",le="+Inf"} 3` + "\n",
+		`calendar_http_request_duration_seconds_count{method="GET",route="/api/v1/users/{user}/events/{id:[0-9]+}"} 3` + "\n",
+		"calendar_events_stored 2\n",
+		"calendar_storage_up 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, body)
+		}
+	}
+
+	if got := labelValue("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("Unexpected escaping %s", got)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	repo, err := OpenFileRepository(filepath.Join(t.TempDir(), "calendar.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar, _ := NewCalendar(repo)
+	router := newRouter(calendar, NewAuthenticator(map[string]string{"secret": "user1"}, nil), nil)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if rec := serve(router, http.MethodGet, path, "", ""); rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 without token, got %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	// После закрытия хранилища экземпляр жив, но не готов принимать запросы
+	calendar.Close()
+	if rec := serve(router, http.MethodGet, "/healthz", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected /healthz to stay 200, got %d", rec.Code)
+	}
+	rec := serve(router, http.MethodGet, "/readyz", "", "")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "storage unavailable") {
+		t.Errorf("Expected /readyz to fail after storage is closed, got %d: %s", rec.Code, rec.Body)
+	}
+	if body := serve(router, http.MethodGet, "/metrics", "", "").Body.String(); !strings.Contains(body, "calendar_storage_up 0\n") {
+		t.Errorf("Expected storage to be reported down:\n%s", body)
+	}
+}
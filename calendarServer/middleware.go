@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxRequestIDLength ограничивает X-Request-ID, пришедший от клиента или прокси
+const maxRequestIDLength = 128
+
+// statusRecorder запоминает код ответа и размер тела для лога и метрик
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter (Flush, дедлайны)
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush нужен обработчикам, которые отправляют ответ частями
+func (r *statusRecorder) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// newRequestID генерирует случайный идентификатор запроса
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID допускает идентификаторы из печатных ASCII-символов разумной длины,
+// чтобы чужой заголовок не испортил лог
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestInfo — сведения о запросе для лога. Хранится в контексте по указателю,
+// чтобы authMiddleware, работающий внутри loggingMiddleware, мог дописать пользователя.
+type requestInfo struct {
+	id   string
+	user string
+}
+
+// withRequestID сохраняет идентификатор запроса в контексте
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestInfoContextKey, &requestInfo{id: id})
+}
+
+// requestIDFromContext возвращает идентификатор запроса или пустую строку
+func requestIDFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoContextKey).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// setLogUser запоминает аутентифицированного пользователя для записи в лог
+func setLogUser(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(requestInfoContextKey).(*requestInfo); ok {
+		info.user = userID
+	}
+}
+
+// logUser возвращает пользователя запроса для лога: из токена, а без аутентификации —
+// из пути ({user}) или параметра user_id
+func logUser(r *http.Request) string {
+	if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok && info.user != "" {
+		return info.user
+	}
+	if user := mux.Vars(r)["user"]; user != "" {
+		return user
+	}
+	return r.URL.Query().Get("user_id")
+}
+
+// loggingMiddleware назначает запросу идентификатор (или берет X-Request-ID от прокси),
+// возвращает его в заголовке ответа и пишет в структурированный лог метод, путь, статус,
+// размер ответа, пользователя и длительность. Ответы 5xx пишутся с уровнем ERROR.
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(withRequestID(r.Context(), id))
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.String("user", logUser(r)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
+
+// recoveryMiddleware перехватывает панику обработчика: запрос получает 500, а сервер продолжает работу
+func recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// ErrAbortHandler — штатный способ прервать ответ, net/http обработает его сам
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			slog.ErrorContext(r.Context(), "panic",
+				slog.String("request_id", requestIDFromContext(r.Context())),
+				slog.Any("error", err),
+				slog.String("stack", string(debug.Stack())),
+			)
+			writeErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitRequestBody ограничивает размер тела любого запроса maxBytes байтами
+func limitRequestBody(next http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyTooLarge сообщает, что чтение тела прервано лимитом limitRequestBody
+func bodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// writeBodyError отвечает на ошибку чтения или разбора тела: 413 при превышении лимита, иначе status с message
+func writeBodyError(w http.ResponseWriter, err error, status int, message string) {
+	if bodyTooLarge(err) {
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	writeErrorResponse(w, status, message)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if id := rec.Header().Get("X-Request-ID"); len(id) != 16 || id != seen {
+		t.Errorf("Expected generated request ID in header and context, got %q and %q", id, seen)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "from-proxy-42")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("X-Request-ID") != "from-proxy-42" || seen != "from-proxy-42" {
+		t.Errorf("Expected request ID from proxy to be kept, got %q", rec.Header().Get("X-Request-ID"))
+	}
+
+	req.Header.Set("X-Request-ID", "bad id\twith spaces")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if id := rec.Header().Get("X-Request-ID"); id == "bad id\twith spaces" || len(id) != 16 {
+		t.Errorf("Expected invalid request ID to be replaced, got %q", id)
+	}
+}
+
+// captureLogs перенаправляет логи в буфер в формате JSON до конца теста
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() {
+		slog.SetDefault(old)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+	return &buf
+}
+
+// logRecords разбирает записи JSON-лога
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Log line is not JSON: %q", line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	logs := captureLogs(t)
+	h := loggingMiddleware(recoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/explode", nil))
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "internal server error") {
+		t.Errorf("Expected 500 after panic, got %d: %s", rec.Code, rec.Body)
+	}
+	id := rec.Header().Get("X-Request-ID")
+	records := logRecords(t, logs)
+	if len(records) != 2 {
+		t.Fatalf("Expected panic and request records, got:\n%s", logs)
+	}
+	if records[0]["msg"] != "panic" || records[0]["error"] != "boom" || records[0]["request_id"] != id || records[0]["stack"] == "" {
+		t.Errorf("Unexpected panic record %v", records[0])
+	}
+	if records[1]["level"] != "ERROR" || records[1]["request_id"] != id || records[1]["path"] != "/explode" || records[1]["status"] != 500.0 {
+		t.Errorf("Unexpected request record %v", records[1])
+	}
+}
+
+func TestStructuredRequestLog(t *testing.T) {
+	logs := captureLogs(t)
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(map[string]string{"secret": "alice"}, nil), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/alice/events", nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	serve(router, http.MethodGet, "/api/v1/users/alice/events", "", "")
+
+	records := logRecords(t, logs)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got:\n%s", logs)
+	}
+	got := records[0]
+	if got["msg"] != "request" || got["level"] != "INFO" || got["method"] != "GET" || got["status"] != 200.0 ||
+		got["user"] != "alice" || got["bytes"] != float64(rec.Body.Len()) || got["duration_ms"] == nil {
+		t.Errorf("Unexpected request record %v", got)
+	}
+	if records[1]["status"] != 401.0 || records[1]["user"] != "alice" {
+		t.Errorf("Expected unauthenticated request to log status 401 and the claimed user, got %v", records[1])
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	h := limitRequestBody(newRouter(calendar, NewAuthenticator(nil, nil), nil), 256)
+	large := `{"user_id":"user1","title":"` + strings.Repeat("x", 512) + `","date":"2024-03-10"}`
+
+	for _, target := range []string{"/create_event", "/api/v1/users/user1/events", "/import_ics?user_id=user1"} {
+		if rec := serve(h, http.MethodPost, target, large, ""); rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: expected 413, got %d: %s", target, rec.Code, rec.Body)
+		}
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("file", "big.ics")
+	part.Write([]byte(strings.Repeat("X", 1024)))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/import_ics?user_id=user1", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for large multipart upload, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(h, http.MethodPost, "/create_event", `{"user_id":"user1","title":"Small","date":"2024-03-10"}`, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected small request to pass, got %d: %s", rec.Code, rec.Body)
+	}
+}
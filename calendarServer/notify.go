@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// LogNotifier пишет напоминания в лог — удобно для отладки и как запасной канал
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+// NewLogNotifier создает уведомитель, пишущий в logger (nil — стандартный лог)
+func NewLogNotifier(logger *log.Logger) *LogNotifier {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.logger.Printf("Reminder for %s: %q starts at %s", notification.UserID, notification.Title, notification.Start.Format(time.RFC3339))
+	return nil
+}
+
+// WebhookNotifier отправляет напоминание POST-запросом с JSON на заданный URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier создает уведомитель, отправляющий напоминания на url
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier отправляет напоминание письмом.
+// Адрес получателя — user_id, если он похож на email, иначе user_id@domain.
+type SMTPNotifier struct {
+	addr   string // host:port SMTP-сервера
+	from   string
+	domain string
+	auth   smtp.Auth // nil — без аутентификации
+}
+
+// NewSMTPNotifier создает уведомитель, отправляющий письма через SMTP-сервер addr.
+// Если задан username, используется аутентификация PLAIN (net/smtp разрешает ее только по TLS или на localhost).
+func NewSMTPNotifier(addr, from, domain, username, password string) *SMTPNotifier {
+	n := &SMTPNotifier{addr: addr, from: from, domain: domain}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n
+}
+
+// recipient возвращает адрес получателя напоминаний пользователя
+func (n *SMTPNotifier) recipient(userID string) (string, error) {
+	if strings.Contains(userID, "@") {
+		return userID, nil
+	}
+	if n.domain == "" {
+		return "", fmt.Errorf("no email address for user %q", userID)
+	}
+	return userID + "@" + n.domain, nil
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	to, err := n.recipient(notification.UserID)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(to, "\r\n") {
+		return errors.New("invalid recipient address")
+	}
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{to}, reminderMessage(n.from, to, notification)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// reminderMessage формирует письмо с напоминанием
+func reminderMessage(from, to string, notification Notification) []byte {
+	when := notification.Start.Format("2006-01-02 15:04 MST")
+	if notification.AllDay {
+		when = notification.Start.Format(dateLayout)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Reminder: "+notification.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	fmt.Fprintf(&b, "%s\r\n", notification.Title)
+	fmt.Fprintf(&b, "Starts: %s\r\n", when)
+	if notification.Description != "" {
+		b.WriteString("\r\n")
+		b.WriteString(strings.ReplaceAll(notification.Description, "\n", "\r\n"))
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
+
+// MultiNotifier доставляет напоминание через все уведомители.
+// Ошибка любого из них приводит к повторной попытке для всех, поэтому лучше не смешивать ненадежные каналы.
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(ctx context.Context, notification Notification) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
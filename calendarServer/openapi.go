@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// openAPIDocument — описание API в формате OpenAPI 3; по нему же проверяются входящие запросы
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// apiSpec — часть OpenAPI-документа, нужная для проверки запросов
+type apiSpec struct {
+	Paths      map[string]map[string]*apiOperation `json:"paths"` // путь -> метод в нижнем регистре -> операция
+	Components struct {
+		Schemas    map[string]*apiSchema    `json:"schemas"`
+		Parameters map[string]*apiParameter `json:"parameters"`
+		Responses  map[string]*apiResponse  `json:"responses"`
+	} `json:"components"`
+	patterns map[string]*regexp.Regexp
+}
+
+type apiOperation struct {
+	OperationID string                  `json:"operationId"`
+	Deprecated  bool                    `json:"deprecated"`
+	Parameters  []*apiParameter         `json:"parameters"`
+	RequestBody *apiRequestBody         `json:"requestBody"`
+	Responses   map[string]*apiResponse `json:"responses"`
+}
+
+type apiParameter struct {
+	Ref      string     `json:"$ref"`
+	Name     string     `json:"name"`
+	In       string     `json:"in"` // path или query
+	Required bool       `json:"required"`
+	Schema   *apiSchema `json:"schema"`
+}
+
+type apiMediaType struct {
+	Schema *apiSchema `json:"schema"`
+}
+
+type apiRequestBody struct {
+	Required bool                    `json:"required"`
+	Content  map[string]apiMediaType `json:"content"`
+}
+
+type apiResponse struct {
+	Ref     string                  `json:"$ref"`
+	Content map[string]apiMediaType `json:"content"`
+}
+
+// apiSchema — поддерживаемое подмножество JSON Schema из OpenAPI 3.0
+type apiSchema struct {
+	Ref                  string                `json:"$ref"`
+	Type                 string                `json:"type"`
+	Format               string                `json:"format"`
+	Enum                 []any                 `json:"enum"`
+	Nullable             bool                  `json:"nullable"`
+	Required             []string              `json:"required"`
+	Properties           map[string]*apiSchema `json:"properties"`
+	AdditionalProperties json.RawMessage       `json:"additionalProperties"` // true/false или схема
+	Items                *apiSchema            `json:"items"`
+	AllOf                []*apiSchema          `json:"allOf"`
+	MinLength            *int                  `json:"minLength"`
+	MaxLength            *int                  `json:"maxLength"`
+	MaxItems             *int                  `json:"maxItems"`
+	Minimum              *float64              `json:"minimum"`
+	Maximum              *float64              `json:"maximum"`
+	Pattern              string                `json:"pattern"`
+
+	additional *apiSchema // разобранная схема additionalProperties
+}
+
+// mergePatchType — тело PATCH: поля необязательны, null удаляет поле
+const mergePatchType = "application/merge-patch+json"
+
+// apiSpecification — разобранный openapi.json; ошибка в нем — ошибка сборки, поэтому паникуем при старте
+var apiSpecification = mustLoadAPISpec(openAPIDocument)
+
+func mustLoadAPISpec(data []byte) *apiSpec {
+	spec, err := loadAPISpec(data)
+	if err != nil {
+		panic(fmt.Sprintf("invalid openapi.json: %v", err))
+	}
+	return spec
+}
+
+// loadAPISpec разбирает OpenAPI-документ и подставляет все $ref
+func loadAPISpec(data []byte) (*apiSpec, error) {
+	spec := &apiSpec{patterns: make(map[string]*regexp.Regexp)}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, err
+	}
+	for name, schema := range spec.Components.Schemas {
+		if err := spec.resolveSchema(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	for path, methods := range spec.Paths {
+		for method, op := range methods {
+			if err := spec.resolveOperation(op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+	return spec, nil
+}
+
+func (s *apiSpec) resolveOperation(op *apiOperation) error {
+	for i, param := range op.Parameters {
+		if param.Ref != "" {
+			target := s.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+			if target == nil {
+				return fmt.Errorf("unknown reference %s", param.Ref)
+			}
+			op.Parameters[i] = target
+		}
+		schema, err := s.resolve(op.Parameters[i].Schema)
+		if err != nil {
+			return err
+		}
+		op.Parameters[i].Schema = schema
+	}
+	if op.RequestBody != nil {
+		if err := s.resolveContent(op.RequestBody.Content); err != nil {
+			return err
+		}
+	}
+	for status, resp := range op.Responses {
+		if resp.Ref != "" {
+			target := s.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+			if target == nil {
+				return fmt.Errorf("unknown reference %s", resp.Ref)
+			}
+			op.Responses[status] = target
+		}
+		if err := s.resolveContent(op.Responses[status].Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *apiSpec) resolveContent(content map[string]apiMediaType) error {
+	for mediaType, media := range content {
+		schema, err := s.resolve(media.Schema)
+		if err != nil {
+			return err
+		}
+		content[mediaType] = apiMediaType{Schema: schema}
+	}
+	return nil
+}
+
+// resolve возвращает схему из components, если schema — ссылка $ref, иначе саму schema с подставленными ссылками
+func (s *apiSpec) resolve(schema *apiSchema) (*apiSchema, error) {
+	if schema == nil || schema.Ref == "" {
+		return schema, s.resolveSchema(schema)
+	}
+	target := s.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	if target == nil {
+		return nil, fmt.Errorf("unknown reference %s", schema.Ref)
+	}
+	return target, nil
+}
+
+// resolveSchema заменяет ссылки $ref внутри схемы на схемы из components и компилирует pattern
+func (s *apiSpec) resolveSchema(schema *apiSchema) error {
+	if schema == nil {
+		return nil
+	}
+	var err error
+	for name, child := range schema.Properties {
+		if schema.Properties[name], err = s.resolve(child); err != nil {
+			return err
+		}
+	}
+	for i, child := range schema.AllOf {
+		if schema.AllOf[i], err = s.resolve(child); err != nil {
+			return err
+		}
+	}
+	if schema.Items, err = s.resolve(schema.Items); err != nil {
+		return err
+	}
+	if len(schema.AdditionalProperties) > 0 && schema.AdditionalProperties[0] == '{' {
+		var additional *apiSchema
+		if err := json.Unmarshal(schema.AdditionalProperties, &additional); err != nil {
+			return err
+		}
+		if schema.additional, err = s.resolve(additional); err != nil {
+			return err
+		}
+	}
+	if schema.Pattern != "" && s.patterns[schema.Pattern] == nil {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return err
+		}
+		s.patterns[schema.Pattern] = re
+	}
+	return nil
+}
+
+// routeParam — переменная шаблона gorilla/mux с регулярным выражением, например {id:[0-9]+}
+var routeParam = regexp.MustCompile(`\{(\w+):[^}]*\}`)
+
+// operation находит описание операции по маршруту mux и методу запроса
+func (s *apiSpec) operation(r *http.Request) *apiOperation {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return nil
+	}
+	return s.Paths[routeParam.ReplaceAllString(tpl, "{$1}")][strings.ToLower(r.Method)]
+}
+
+// ServeHTTP отдает OpenAPI-документ
+func (s *apiSpec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// middleware проверяет параметры и JSON-тело запроса по описанию операции.
+// Ошибки параметров — 400, тело не по схеме — 422 (или 400, если операция не описывает 422).
+// Синтаксически неверный JSON пропускается дальше: обработчик отвечает на него как раньше.
+func (s *apiSpec) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := s.operation(r)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := s.validateParameters(op, r); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		mediaType, schema := op.jsonBody(r.Header.Get("Content-Type"))
+		if schema == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, err, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if decoder.Decode(&value) == nil {
+			if err := s.validateValue(schema, value, "", mediaType == mergePatchType); err != nil {
+				status := http.StatusBadRequest
+				if op.Responses["422"] != nil {
+					status = http.StatusUnprocessableEntity
+				}
+				writeErrorResponse(w, status, "invalid request body: "+err.Error())
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jsonBody возвращает JSON-схему тела для Content-Type запроса или nil, если тело не JSON.
+// Запрос без Content-Type считается JSON, а application/json допускается и для merge patch.
+func (op *apiOperation) jsonBody(contentType string) (string, *apiSchema) {
+	if op.RequestBody == nil {
+		return "", nil
+	}
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return "", nil
+		}
+	}
+	for _, mediaType := range []string{"application/json", mergePatchType} {
+		if media, ok := op.RequestBody.Content[mediaType]; ok {
+			return mediaType, media.Schema
+		}
+	}
+	return "", nil
+}
+
+// validateParameters проверяет параметры пути и строки запроса
+func (s *apiSpec) validateParameters(op *apiOperation, r *http.Request) error {
+	query := r.URL.Query()
+	vars := mux.Vars(r)
+	for _, param := range op.Parameters {
+		var value string
+		switch param.In {
+		case "path":
+			value = vars[param.Name]
+		case "query":
+			value = query.Get(param.Name)
+		case "header":
+			value = r.Header.Get(param.Name)
+		default:
+			continue
+		}
+		if value == "" {
+			if param.Required {
+				return fmt.Errorf("%s parameter %q is required", param.In, param.Name)
+			}
+			continue
+		}
+		if err := s.validateParameter(param.Schema, value); err != nil {
+			return fmt.Errorf("invalid %s parameter %q: %w", param.In, param.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateParameter приводит строковое значение параметра к типу схемы и проверяет его
+func (s *apiSpec) validateParameter(schema *apiSchema, value string) error {
+	if schema == nil {
+		return nil
+	}
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("must be an integer")
+		}
+		return s.validateValue(schema, json.Number(value), "", false)
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.New("must be true or false")
+		}
+		return nil
+	}
+	return s.validateValue(schema, value, "", false)
+}
+
+// validateValue проверяет значение, разобранное из JSON с UseNumber, по схеме.
+// path — путь к значению для сообщения об ошибке; patch — значение является JSON Merge Patch
+// (на верхнем уровне нет обязательных полей, а null означает удаление поля).
+func (s *apiSpec) validateValue(schema *apiSchema, value any, path string, patch bool) error {
+	fail := func(format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		if path == "" {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("%s: %s", path, msg)
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fail("must not be null")
+	}
+	for _, sub := range schema.AllOf {
+		if err := s.validateValue(sub, value, path, patch); err != nil {
+			return err
+		}
+	}
+
+	typ := schema.Type
+	if typ == "" && schema.Properties != nil {
+		typ = "object"
+	}
+	switch typ {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		if !patch {
+			for _, name := range schema.Required {
+				if _, ok := obj[name]; !ok {
+					return fail("%s is required", name)
+				}
+			}
+		}
+		for name, field := range obj {
+			prop := schema.Properties[name]
+			if prop == nil {
+				prop = schema.additional
+			}
+			if prop == nil || (patch && field == nil) {
+				continue
+			}
+			if err := s.validateValue(prop, field, joinPath(path, name), false); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			return fail("must have at most %d items", *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for i, item := range items {
+				if err := s.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), false); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		length := utf8.RuneCountInString(str)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			if *schema.MinLength == 1 {
+				return fail("must not be empty")
+			}
+			return fail("must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fail("must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" && !s.patterns[schema.Pattern].MatchString(str) {
+			return fail("must match %s", schema.Pattern)
+		}
+		switch schema.Format {
+		case "date":
+			if _, err := time.Parse(dateLayout, str); err != nil {
+				return fail("must be a date in format YYYY-MM-DD")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fail("must be a date-time in RFC 3339 format")
+			}
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return fail("must be a number")
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return fail("must be a number")
+		}
+		if typ == "integer" {
+			if _, err := num.Int64(); err != nil {
+				return fail("must be an integer")
+			}
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return fail("must be at least %s", strconv.FormatFloat(*schema.Minimum, 'g', -1, 64))
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			return fail("must be at most %s", strconv.FormatFloat(*schema.Maximum, 'g', -1, 64))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				return nil
+			}
+		}
+		return fail("must be one of %v", schema.Enum)
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+
+	described := make(map[string]bool)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// CalDAV обслуживает все методы WebDAV и в OpenAPI не описывается
+			return nil
+		}
+		path := routeParam.ReplaceAllString(tpl, "{$1}")
+		for _, method := range methods {
+			key := method + " " + path
+			described[key] = true
+			if apiSpecification.Paths[path][strings.ToLower(method)] == nil {
+				t.Errorf("%s is not described in openapi.json", key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, methods := range apiSpecification.Paths {
+		for method := range methods {
+			if key := strings.ToUpper(method) + " " + path; !described[key] {
+				t.Errorf("openapi.json describes %s, but there is no such route", key)
+			}
+		}
+	}
+
+	rec := serve(newRouter(calendar, NewAuthenticator(map[string]string{"key": "user1"}, nil), nil), http.MethodGet, "/openapi.json", "", "")
+	var doc map[string]any
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &doc) != nil || doc["openapi"] != "3.0.3" {
+		t.Errorf("Expected the document to be served without a token, got %d", rec.Code)
+	}
+}
+
+func TestOpenAPIRequestValidation(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Meeting", Description: "Agenda", Date: "2024-03-10"})
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		want    int
+		wantErr string
+	}{
+		{"integer parameter", http.MethodGet, "/api/v1/users/user1/events?limit=ten", "", http.StatusBadRequest, `invalid query parameter "limit": must be an integer`},
+		{"boolean parameter", http.MethodPost, "/api/v1/users/user1/events?reject_overlap=maybe", `{"title":"x","date":"2024-03-10"}`, http.StatusBadRequest, `invalid query parameter "reject_overlap": must be true or false`},
+		{"required parameter", http.MethodGet, "/api/v1/freebusy?from=2024-03-10&to=2024-03-10", "", http.StatusBadRequest, `query parameter "users" is required`},
+		{"legacy required parameter", http.MethodGet, "/events_for_day?user_id=user1", "", http.StatusBadRequest, `query parameter "date" is required`},
+		{"wrong field type", http.MethodPost, "/api/v1/users/user1/events", `{"title":5,"date":"2024-03-10"}`, http.StatusUnprocessableEntity, "invalid request body: title: must be a string"},
+		{"nested field", http.MethodPost, "/api/v1/users/user1/events", `{"title":"x","date":"2024-03-10","rrule":"FREQ=DAILY","overrides":[{"title":"y"}]}`, http.StatusUnprocessableEntity, "invalid request body: overrides[0]: recurrence_id is required"},
+		{"date-time format", http.MethodPost, "/api/v1/users/user1/events", `{"title":"x","start":"10:00"}`, http.StatusUnprocessableEntity, "invalid request body: start: must be a date-time in RFC 3339 format"},
+		{"too many tags", http.MethodPost, "/api/v1/users/user1/events", `{"title":"x","date":"2024-03-10","tags":[` + strings.Repeat(`"t",`, 20) + `"t"]}`, http.StatusUnprocessableEntity, "invalid request body: tags: must have at most 20 items"},
+		{"legacy body", http.MethodPost, "/create_event", `{"user_id":"user1","title":["x"],"date":"2024-03-10"}`, http.StatusBadRequest, "invalid request body: title: must be a string"},
+		{"legacy delete", http.MethodPost, "/delete_event", `{"event_id":"1"}`, http.StatusBadRequest, "invalid request body: event_id: must be a number"},
+		{"patch without required fields", http.MethodPatch, "/api/v1/users/user1/events/1", `{"description":null}`, http.StatusOK, ""},
+		{"patch field type", http.MethodPatch, "/api/v1/users/user1/events/1", `{"all_day":"yes"}`, http.StatusUnprocessableEntity, "invalid request body: all_day: must be a boolean"},
+		{"malformed JSON is left to the handler", http.MethodPost, "/api/v1/users/user1/events", `{"title":`, http.StatusBadRequest, "Invalid JSON format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, tt.method, tt.target, tt.body, "")
+			var resp Response
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if rec.Code != tt.want || resp.Error != tt.wantErr {
+				t.Errorf("Expected %d %q, got %d: %s", tt.want, tt.wantErr, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// Тело iCalendar не проверяется по JSON-схеме
+	req := httptest.NewRequest(http.MethodPost, "/import_ics?user_id=user1", strings.NewReader("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+	req.Header.Set("Content-Type", "text/calendar")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected iCalendar import to pass validation, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// validateResponses проверяет JSON-ответы маршрутов router по схемам ответов из openapi.json
+func validateResponses(t *testing.T, router *mux.Router) {
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+			for key, values := range rec.Header() {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(rec.Code)
+			w.Write(rec.Body.Bytes())
+
+			op := apiSpecification.operation(r)
+			if op == nil {
+				return
+			}
+			resp := op.Responses[strconv.Itoa(rec.Code)]
+			if resp == nil {
+				t.Errorf("%s %s: status %d is not described in openapi.json", r.Method, r.URL.Path, rec.Code)
+				return
+			}
+			media, ok := resp.Content["application/json"]
+			if !ok || media.Schema == nil {
+				return
+			}
+			decoder := json.NewDecoder(bytes.NewReader(rec.Body.Bytes()))
+			decoder.UseNumber()
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				t.Errorf("%s %s: response is not JSON: %v", r.Method, r.URL.Path, err)
+				return
+			}
+			if err := apiSpecification.validateValue(media.Schema, value, "", false); err != nil {
+				t.Errorf("%s %s: response does not match openapi.json: %v", r.Method, r.URL.Path, err)
+			}
+		})
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPageLimit — размер страницы REST API по умолчанию
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// pageCursor — позиция последнего события страницы; следующая страница начинается строго после нее.
+// Курсор не зависит от смещения, поэтому страницы не сдвигаются при добавлении и удалении событий.
+type pageCursor struct {
+	Start int64  `json:"s,omitempty"` // начало в наносекундах Unix; 0 для списков по ID
+	ID    int    `json:"i"`
+	RID   string `json:"r,omitempty"` // recurrence_id экземпляра
+}
+
+func (c pageCursor) less(other pageCursor) bool {
+	if c.Start != other.Start {
+		return c.Start < other.Start
+	}
+	if c.ID != other.ID {
+		return c.ID < other.ID
+	}
+	return c.RID < other.RID
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// eventKey возвращает позицию события в порядке выдачи списка
+type eventKey func(e *Event) pageCursor
+
+// idKey — порядок списков по ID
+func idKey(e *Event) pageCursor {
+	return pageCursor{ID: e.ID}
+}
+
+// startKey — порядок диапазонных запросов: по началу в поясе loc, затем по ID и recurrence_id (как sortByStart)
+func startKey(loc *time.Location) eventKey {
+	return func(e *Event) pageCursor {
+		start, _ := e.interval(loc)
+		return pageCursor{Start: start.UnixNano(), ID: e.ID, RID: e.RecurrenceID}
+	}
+}
+
+// ListOptions — фильтры и пагинация списка событий
+type ListOptions struct {
+	Filter EventFilter
+	Limit  int         // 0 — без ограничения
+	After  *pageCursor // nil — с начала списка
+}
+
+// parseListOptions читает параметры q (подстрока), tags (через запятую), limit и cursor.
+// defaultLimit применяется, если limit не задан (0 — без ограничения, как в старых маршрутах).
+func parseListOptions(r *http.Request, defaultLimit int) (ListOptions, error) {
+	query := r.URL.Query()
+	opts := ListOptions{Limit: defaultLimit}
+	opts.Filter.Text = foldText(strings.TrimSpace(query.Get("q")))
+
+	if value := query.Get("tags"); value != "" {
+		tags, err := normalizeTags(strings.Split(value, ","))
+		if err != nil {
+			return opts, err
+		}
+		opts.Filter.Tags = tags
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return opts, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		opts.Limit = limit
+	}
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil {
+			return opts, err
+		}
+		opts.After = &cursor
+	}
+	return opts, nil
+}
+
+// paginate отбирает события фильтром и возвращает страницу после курсора и курсор следующей страницы ("" — страниц больше нет).
+// events должны быть упорядочены по key.
+func paginate(events []Event, opts ListOptions, key eventKey) ([]Event, string) {
+	start := 0
+	if opts.After != nil {
+		start = sort.Search(len(events), func(i int) bool { return opts.After.less(key(&events[i])) })
+	}
+
+	page := make([]Event, 0)
+	for i := start; i < len(events); i++ {
+		if !opts.Filter.matches(&events[i]) {
+			continue
+		}
+		if opts.Limit > 0 && len(page) == opts.Limit {
+			return page, key(&page[len(page)-1]).encode()
+		}
+		page = append(page, events[i])
+	}
+	return page, ""
+}
+
+// writeEventPage отвечает страницей событий. Курсор следующей страницы передается в заголовках
+// X-Next-Cursor и Link (rel="next"), чтобы data осталась массивом, как раньше.
+func writeEventPage(w http.ResponseWriter, r *http.Request, message string, events []Event, opts ListOptions, key eventKey) {
+	page, next := paginate(events, opts, key)
+	if next != "" {
+		query := r.URL.Query()
+		query.Set("cursor", next)
+		w.Header().Set("X-Next-Cursor", next)
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode()))
+	}
+	writeResponse(w, http.StatusOK, Response{Message: message, Data: page})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrEventLimit — у пользователя уже максимальное число событий
+	ErrEventLimit = errors.New("event limit reached")
+	// ErrDescriptionTooLong — описание события длиннее допустимого
+	ErrDescriptionTooLong = errors.New("description is too long")
+)
+
+// Quota — ограничения на данные одного пользователя; нулевое поле — без ограничения
+type Quota struct {
+	MaxEvents            int // событий пользователя во всех его календарях и отдельно в его корзине
+	MaxDescriptionLength int // символов в описании события и каждого измененного экземпляра
+}
+
+// SetQuota задает ограничения для новых изменений; уже сохраненные события не проверяются,
+// но лишние удаленные события сразу удаляются из корзины окончательно
+func (c *Calendar) SetQuota(quota Quota) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quota = quota
+	return c.purgeTrash(time.Now())
+}
+
+// checkQuota проверяет, что event укладывается в ограничения его владельца.
+// added — событие добавляется к событиям владельца (создание или восстановление из корзины).
+// Вызывается под блокировкой.
+func (c *Calendar) checkQuota(event *Event, added bool) error {
+	if limit := c.quota.MaxDescriptionLength; limit > 0 {
+		tooLong := utf8.RuneCountInString(event.Description) > limit
+		for _, override := range event.Overrides {
+			tooLong = tooLong || utf8.RuneCountInString(override.Description) > limit
+		}
+		if tooLong {
+			return fmt.Errorf("%w: at most %d characters", ErrDescriptionTooLong, limit)
+		}
+	}
+	if limit := c.quota.MaxEvents; added && limit > 0 && c.index[event.UserID].count() >= limit {
+		return fmt.Errorf("%w: at most %d events per user", ErrEventLimit, limit)
+	}
+	return nil
+}
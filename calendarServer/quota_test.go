@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQuota(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	calendar.SetQuota(Quota{MaxEvents: 2, MaxDescriptionLength: 10})
+
+	first, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "First", Date: "2024-03-10"})
+	if _, err := calendar.CreateEvent(Event{UserID: "user1", Title: "Second", Date: "2024-03-11", Description: "десять букв"}); !errors.Is(err, ErrDescriptionTooLong) {
+		t.Errorf("Expected ErrDescriptionTooLong, got %v", err)
+	}
+	second, err := calendar.CreateEvent(Event{UserID: "user1", Title: "Second", Date: "2024-03-11", Description: "девять бу"})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if _, err := calendar.CreateEvent(Event{UserID: "user1", Title: "Third", Date: "2024-03-12"}); !errors.Is(err, ErrEventLimit) {
+		t.Errorf("Expected ErrEventLimit, got %v", err)
+	}
+	if _, err := calendar.ApplyBatch("user1", []BatchOperation{{Op: BatchCreate, Event: &Event{Title: "Third", Date: "2024-03-12"}}}); !errors.Is(err, ErrEventLimit) {
+		t.Errorf("Expected ErrEventLimit from a batch, got %v", err)
+	}
+	if _, err := calendar.CreateEvent(Event{UserID: "user2", Title: "Other", Date: "2024-03-12"}); err != nil {
+		t.Errorf("Limit of one user must not affect another: %v", err)
+	}
+
+	// Изменение не увеличивает число событий, но описание проверяется, в том числе у экземпляров
+	err = calendar.UpdateEvent(Event{ID: first, UserID: "user1", Title: "First", Date: "2024-03-10", RRule: "FREQ=DAILY",
+		Overrides: []Override{{RecurrenceID: "2024-03-11", Description: "слишком длинное"}}})
+	if !errors.Is(err, ErrDescriptionTooLong) {
+		t.Errorf("Expected ErrDescriptionTooLong for an override, got %v", err)
+	}
+	if err := calendar.UpdateEvent(Event{ID: first, UserID: "user1", Title: "Renamed", Date: "2024-03-10"}); err != nil {
+		t.Errorf("Failed to update event at the limit: %v", err)
+	}
+
+	// Удаленное событие освобождает место, а восстановить его можно, только если место есть
+	calendar.DeleteUserEvent("user1", second)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Replacement", Date: "2024-03-12"})
+	if _, err := calendar.RestoreEvent("user1", second); !errors.Is(err, ErrEventLimit) {
+		t.Errorf("Expected ErrEventLimit on restore, got %v", err)
+	}
+
+	// В корзине хранится не больше MaxEvents событий: самые старые удаляются окончательно
+	var churned []int
+	for i := 0; i < 3; i++ {
+		id, err := calendar.CreateEvent(Event{UserID: "user2", Title: "Churn", Date: "2024-03-12"})
+		if err != nil {
+			t.Fatalf("Failed to create event after deletion: %v", err)
+		}
+		calendar.DeleteUserEvent("user2", id)
+		churned = append(churned, id)
+	}
+	if deleted := calendar.DeletedEvents("user2"); len(deleted) != 2 || deleted[0].ID != churned[1] || deleted[1].ID != churned[2] {
+		t.Errorf("Expected the trash capped at 2 events, got %+v", deleted)
+	}
+
+	// Корзина, переполненная до установки ограничений (например, при запуске), сразу сокращается
+	calendar.SetQuota(Quota{MaxEvents: 1, MaxDescriptionLength: 10})
+	if deleted := calendar.DeletedEvents("user2"); len(deleted) != 1 || deleted[0].ID != churned[2] {
+		t.Errorf("Expected the trash capped at 1 event by SetQuota, got %+v", deleted)
+	}
+	calendar.SetQuota(Quota{MaxEvents: 2, MaxDescriptionLength: 10})
+
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	validateResponses(t, router)
+	if rec := serve(router, http.MethodPost, "/api/v1/users/user1/events", `{"title": "Third", "date": "2024-03-12"}`, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 at the event limit, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodPost, "/create_event", `{"user_id": "user1", "title": "Third", "date": "2024-03-12"}`, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 at the event limit in the legacy route, got %d", rec.Code)
+	}
+	body := `{"title": "Renamed", "date": "2024-03-10", "description": "` + strings.Repeat("x", 11) + `"}`
+	if rec := serve(router, http.MethodPut, "/api/v1/users/user1/events/1", body, ""); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a long description, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval — как часто удаляются корзины, которые успели наполниться (ключи без запросов)
+const rateLimitSweepInterval = time.Minute
+
+// RateLimit — допустимая частота запросов: в среднем Rate в секунду и до Burst запросов подряд.
+// Нулевой Rate снимает ограничение.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter ограничивает частоту запросов каждого пользователя и каждого IP-адреса
+// по алгоритму token bucket. Запрос сверх ограничения получает 429 с заголовком Retry-After.
+type RateLimiter struct {
+	user *bucketSet
+	ip   *bucketSet
+}
+
+// NewRateLimiter создает ограничитель с лимитами на пользователя и на IP-адрес
+func NewRateLimiter(user, ip RateLimit) *RateLimiter {
+	return &RateLimiter{user: newBucketSet(user), ip: newBucketSet(ip)}
+}
+
+// bucket — корзина одного ключа: сколько запросов можно сделать сейчас
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// bucketSet — корзины всех ключей с одним лимитом
+type bucketSet struct {
+	limit   RateLimit
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// newBucketSet возвращает корзины лимита limit или nil, если лимит не задан
+func newBucketSet(limit RateLimit) *bucketSet {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	limit.Burst = max(limit.Burst, 1)
+	return &bucketSet{limit: limit, buckets: make(map[string]*bucket)}
+}
+
+// refill пополняет корзину за время, прошедшее с прошлого запроса
+func (s *bucketSet) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(s.limit.Burst), b.tokens+elapsed*s.limit.Rate)
+		b.updated = now
+	}
+}
+
+// take забирает из корзины key один запрос. Если корзина пуста, возвращает false
+// и время, через которое запрос станет возможен. nil-корзины запросы не ограничивают.
+func (s *bucketSet) take(key string, now time.Time) (bool, time.Duration) {
+	if s == nil {
+		return true, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.swept) >= rateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	b := s.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: float64(s.limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	s.refill(b, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / s.limit.Rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// sweep удаляет полные корзины: они ничем не отличаются от новых. Вызывается под блокировкой.
+func (s *bucketSet) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		s.refill(b, now)
+		if b.tokens >= float64(s.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
+
+// clientIP возвращает IP-адрес клиента из RemoteAddr. X-Forwarded-For не учитывается:
+// без доверенного прокси его может подделать любой клиент.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeRateLimited отвечает 429 с Retry-After в целых секундах (не меньше одной)
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	seconds := max(int(math.Ceil(wait.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeErrorResponse(w, http.StatusTooManyRequests, "rate limit exceeded")
+}
+
+// ipMiddleware ограничивает запросы с одного IP-адреса. Стоит до аутентификации,
+// чтобы ограничивать и подбор токенов. Служебные маршруты (publicPaths) не ограничиваются.
+func (l *RateLimiter) ipMiddleware(next http.Handler) http.Handler {
+	if l == nil || l.ip == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !publicPaths[r.URL.Path] {
+			if ok, wait := l.ip.take(clientIP(r), time.Now()); !ok {
+				writeRateLimited(w, wait)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// userMiddleware ограничивает запросы одного пользователя: из токена, а без аутентификации —
+// из пути или user_id. Стоит после аутентификации.
+func (l *RateLimiter) userMiddleware(next http.Handler) http.Handler {
+	if l == nil || l.user == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := logUser(r); user != "" && !publicPaths[r.URL.Path] {
+			if ok, wait := l.user.take(user, time.Now()); !ok {
+				writeRateLimited(w, wait)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
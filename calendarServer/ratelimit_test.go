@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBucketSet(t *testing.T) {
+	s := newBucketSet(RateLimit{Rate: 2, Burst: 3})
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if ok, _ := s.take("alice", now); !ok {
+			t.Fatalf("Request %d within the burst was rejected", i)
+		}
+	}
+	ok, wait := s.take("alice", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Expected rejection with 500ms wait, got %v, %v", ok, wait)
+	}
+	if ok, _ := s.take("bob", now); !ok {
+		t.Error("Another key must have its own bucket")
+	}
+
+	// За полсекунды накапливается один запрос
+	if ok, _ := s.take("alice", now.Add(500*time.Millisecond)); !ok {
+		t.Error("Expected the bucket to refill")
+	}
+
+	// Наполнившиеся корзины удаляются
+	s.take("carol", now.Add(time.Hour))
+	if len(s.buckets) != 1 {
+		t.Errorf("Expected idle buckets to be swept, got %d", len(s.buckets))
+	}
+
+	if newBucketSet(RateLimit{}) != nil {
+		t.Error("Expected no buckets without a rate")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	auth := NewAuthenticator(map[string]string{"alice-key": "alice", "bob-key": "bob"}, nil)
+	limiter := NewRateLimiter(RateLimit{Rate: 0.1, Burst: 2}, RateLimit{Rate: 0.1, Burst: 5})
+	router := newRouter(calendar, auth, limiter)
+
+	for i := 0; i < 2; i++ {
+		if rec := serve(router, http.MethodGet, "/api/v1/users/alice/events", "", "alice-key"); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := serve(router, http.MethodGet, "/api/v1/users/alice/events", "", "alice-key")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "10" {
+		t.Errorf("Expected 429 with Retry-After 10, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := serve(router, http.MethodGet, "/api/v1/users/bob/events", "", "bob-key"); rec.Code != http.StatusOK {
+		t.Errorf("Another user must not be limited, got %d", rec.Code)
+	}
+
+	// Запросы без токена ограничиваются по IP-адресу, служебные маршруты — нет
+	if rec := serve(router, http.MethodGet, "/api/v1/users/bob/events", "", "wrong-key"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong token, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/api/v1/users/bob/events", "", "wrong-key"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after the IP limit, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/healthz", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected health checks to be exempt, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Override изменяет или отменяет один экземпляр повторяющегося события.
+// Пустые поля наследуются от основного события.
+type Override struct {
+	RecurrenceID string    `json:"recurrence_id"`         // исходное начало экземпляра
+	Title        string    `json:"title,omitempty"`       // новый заголовок
+	Description  string    `json:"description,omitempty"` // новое описание
+	Date         string    `json:"date,omitempty"`        // новая дата экземпляра события на весь день
+	Start        time.Time `json:"start,omitzero"`        // новое начало экземпляра события со временем
+	End          time.Time `json:"end,omitzero"`          // новое окончание экземпляра события со временем
+	Cancelled    bool      `json:"cancelled,omitempty"`   // экземпляр отменен
+}
+
+// IsRecurring сообщает, задано ли у события правило повторения
+func (e *Event) IsRecurring() bool {
+	return e.RRule != ""
+}
+
+// recurrenceID возвращает идентификатор экземпляра, начинающегося в start:
+// дату для событий на весь день и время начала в UTC (RFC 3339) для событий со временем
+func (e *Event) recurrenceID(start time.Time) string {
+	if e.AllDay {
+		return start.Format(dateLayout)
+	}
+	return start.UTC().Format(time.RFC3339)
+}
+
+// matchesRecurrenceID проверяет, относится ли идентификатор id к экземпляру с началом start.
+// Для событий со временем допускается и просто дата — тогда подходит любой экземпляр этого дня.
+func (e *Event) matchesRecurrenceID(id string, start time.Time) bool {
+	if e.AllDay || len(id) == len(dateLayout) {
+		return id == start.Format(dateLayout)
+	}
+	t, err := time.Parse(time.RFC3339, id)
+	return err == nil && t.Equal(start)
+}
+
+// normalizeRecurrenceID проверяет идентификатор экземпляра и приводит его к каноническому виду
+func (e *Event) normalizeRecurrenceID(id string) (string, error) {
+	if _, err := time.Parse(dateLayout, id); err == nil {
+		return id, nil
+	}
+	if e.AllDay {
+		return "", fmt.Errorf("recurrence id %q must be in format YYYY-MM-DD", id)
+	}
+	t, err := time.Parse(time.RFC3339, id)
+	if err != nil {
+		return "", fmt.Errorf("recurrence id %q must be a date or an RFC 3339 time", id)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// normalizeRecurrence проверяет правило повторения, исключения и переопределения экземпляров
+func normalizeRecurrence(event Event) (Event, error) {
+	// recurrence_id заполняется только у раскрытых экземпляров
+	event.RecurrenceID = ""
+	if err := validateHolidayPolicy(event); err != nil {
+		return event, err
+	}
+	if !event.IsRecurring() {
+		if len(event.ExDates) > 0 || len(event.Overrides) > 0 {
+			return event, errors.New("exdates and overrides require rrule")
+		}
+		return event, nil
+	}
+
+	rule, err := ParseRRule(event.RRule)
+	if err != nil {
+		return event, fmt.Errorf("invalid rrule: %w", err)
+	}
+	event.RRule = rule.String()
+
+	exdates := make([]string, 0, len(event.ExDates))
+	for _, id := range event.ExDates {
+		id, err := event.normalizeRecurrenceID(id)
+		if err != nil {
+			return event, err
+		}
+		exdates = append(exdates, id)
+	}
+	event.ExDates = exdates
+
+	overrides := make([]Override, 0, len(event.Overrides))
+	seen := make(map[string]bool)
+	for _, o := range event.Overrides {
+		id, err := event.normalizeRecurrenceID(o.RecurrenceID)
+		if err != nil {
+			return event, err
+		}
+		if seen[id] {
+			return event, fmt.Errorf("duplicate override for %s", id)
+		}
+		seen[id] = true
+		o.RecurrenceID = id
+
+		if event.AllDay {
+			if !o.Start.IsZero() || !o.End.IsZero() {
+				return event, errors.New("overrides of all-day events are set by date, not start")
+			}
+			if o.Date != "" {
+				if _, err := time.Parse(dateLayout, o.Date); err != nil {
+					return event, errors.New("override date must be in format YYYY-MM-DD")
+				}
+			}
+		} else {
+			if o.Date != "" {
+				return event, errors.New("overrides of timed events are set by start, not date")
+			}
+			// Без start переопределение end меняет только окончание исходного экземпляра
+			start := o.Start
+			if start.IsZero() {
+				start, _ = time.Parse(time.RFC3339, id)
+			}
+			if !o.End.IsZero() && o.End.Before(start) {
+				return event, errors.New("override end must not be before start")
+			}
+		}
+		overrides = append(overrides, o)
+	}
+	event.Overrides = overrides
+
+	if len(event.ExDates) == 0 {
+		event.ExDates = nil
+	}
+	if len(event.Overrides) == 0 {
+		event.Overrides = nil
+	}
+	return event, nil
+}
+
+// firstStart возвращает начало первого экземпляра, от которого отсчитывается правило.
+// Даты событий на весь день «плавающие», поэтому для них используется полночь UTC.
+func (e *Event) firstStart() time.Time {
+	if e.AllDay {
+		start, _ := time.Parse(dateLayout, e.Date)
+		return start
+	}
+	return e.Start
+}
+
+// occurrence строит экземпляр повторяющегося события, начинающийся в start
+func (e *Event) occurrence(start time.Time) Event {
+	occ := *e
+	occ.ExDates = nil
+	occ.Overrides = nil
+	occ.RecurrenceID = e.recurrenceID(start)
+
+	if e.AllDay {
+		occ.Date = start.Format(dateLayout)
+		if e.EndDate != "" {
+			first, _ := time.Parse(dateLayout, e.Date)
+			last, _ := time.Parse(dateLayout, e.EndDate)
+			occ.EndDate = start.Add(last.Sub(first)).Format(dateLayout)
+		}
+	} else {
+		occ.Start = start
+		occ.End = start.Add(e.End.Sub(e.Start))
+		occ.Date = start.Format(dateLayout)
+	}
+	return occ
+}
+
+// apply применяет переопределение к экземпляру
+func (o *Override) apply(occ Event) Event {
+	if o.Title != "" {
+		occ.Title = o.Title
+	}
+	if o.Description != "" {
+		occ.Description = o.Description
+	}
+	if occ.AllDay && o.Date != "" {
+		first, _ := time.Parse(dateLayout, occ.Date)
+		moved, _ := time.Parse(dateLayout, o.Date)
+		if occ.EndDate != "" {
+			last, _ := time.Parse(dateLayout, occ.EndDate)
+			occ.EndDate = last.Add(moved.Sub(first)).Format(dateLayout)
+		}
+		occ.Date = o.Date
+	}
+	if !occ.AllDay && !o.Start.IsZero() {
+		duration := occ.End.Sub(occ.Start)
+		occ.Start = o.Start.In(occ.Start.Location())
+		if o.End.IsZero() {
+			occ.End = occ.Start.Add(duration)
+		} else {
+			occ.End = o.End.In(occ.Start.Location())
+		}
+		occ.Date = occ.Start.Format(dateLayout)
+	} else if !occ.AllDay && !o.End.IsZero() {
+		occ.End = o.End.In(occ.Start.Location())
+	}
+	return occ
+}
+
+// excluded проверяет, исключен ли экземпляр с началом start
+func (e *Event) excluded(start time.Time) bool {
+	for _, id := range e.ExDates {
+		if e.matchesRecurrenceID(id, start) {
+			return true
+		}
+	}
+	return false
+}
+
+// override возвращает переопределение экземпляра с началом start
+func (e *Event) override(start time.Time) *Override {
+	for i := range e.Overrides {
+		if e.matchesRecurrenceID(e.Overrides[i].RecurrenceID, start) {
+			return &e.Overrides[i]
+		}
+	}
+	return nil
+}
+
+// occurrencesInRange раскрывает повторяющееся событие в экземпляры, пересекающиеся с [from, to).
+// Исключенные и отмененные экземпляры пропускаются, переопределения применяются,
+// в том числе к экземплярам, перенесенным в интервал извне.
+func (e *Event) occurrencesInRange(from, to time.Time) []Event {
+	return e.expandRange(from, to, 0)
+}
+
+// expandRange раскрывает экземпляры в [from, to); limit > 0 останавливает раскрытие после limit экземпляров
+func (e *Event) expandRange(from, to time.Time, limit int) []Event {
+	rule, err := ParseRRule(e.RRule)
+	if err != nil {
+		return nil
+	}
+
+	holidays := e.holidayCalendar()
+	// Экземпляры после интервала могут переноситься в него на предыдущий рабочий день
+	horizon := to
+	if holidays != nil && e.OnHoliday == HolidayPrevious {
+		horizon = to.AddDate(0, 0, maxHolidayShiftDays)
+	}
+
+	occurrences := make([]Event, 0)
+	emitted := make(map[*Override]bool)
+	rule.Each(e.firstStart(), func(start time.Time) bool {
+		occ := e.occurrence(start)
+		// Экземпляры идут по возрастанию начала: дальше интервала искать нечего
+		if occStart, _ := occ.interval(from.Location()); !occStart.Before(horizon) {
+			return false
+		}
+		if e.excluded(start) {
+			return true
+		}
+		if o := e.override(start); o != nil {
+			emitted[o] = true
+			if o.Cancelled {
+				return true
+			}
+			occ = o.apply(occ)
+		} else if shifted, ok := holidays.avoid(occ, e.OnHoliday); ok {
+			// Переопределение экземпляра важнее правила on_holiday
+			occ = shifted
+		} else {
+			return true
+		}
+		if occ.overlaps(from, to) {
+			occurrences = append(occurrences, occ)
+		}
+		return limit == 0 || len(occurrences) < limit
+	})
+	if limit > 0 && len(occurrences) >= limit {
+		return occurrences
+	}
+
+	// Экземпляры, перенесенные в интервал с более поздних дат
+	for i := range e.Overrides {
+		o := &e.Overrides[i]
+		if emitted[o] || o.Cancelled || (o.Date == "" && o.Start.IsZero() && o.End.IsZero()) {
+			continue
+		}
+		start, ok := e.findOccurrence(rule, o.RecurrenceID)
+		if !ok || e.excluded(start) {
+			continue
+		}
+		if occ := o.apply(e.occurrence(start)); occ.overlaps(from, to) {
+			occurrences = append(occurrences, occ)
+		}
+	}
+	return occurrences
+}
+
+// occursIn проверяет, попадает ли в [from, to) само событие или хотя бы один его экземпляр
+func (e *Event) occursIn(from, to time.Time) bool {
+	if !e.IsRecurring() {
+		return e.overlaps(from, to)
+	}
+	return len(e.expandRange(from, to, 1)) > 0
+}
+
+// findOccurrence ищет экземпляр правила с идентификатором id
+func (e *Event) findOccurrence(rule *RRule, id string) (time.Time, bool) {
+	var limit time.Time
+	if len(id) == len(dateLayout) {
+		day, _ := time.Parse(dateLayout, id)
+		limit = day.AddDate(0, 0, 2)
+	} else {
+		limit, _ = time.Parse(time.RFC3339, id)
+		limit = limit.Add(time.Nanosecond)
+	}
+
+	var found time.Time
+	rule.Each(e.firstStart(), func(start time.Time) bool {
+		if e.matchesRecurrenceID(id, start) {
+			found = start
+			return false
+		}
+		return start.Before(limit)
+	})
+	return found, !found.IsZero()
+}
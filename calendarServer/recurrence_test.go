@@ -0,0 +1,170 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func titles(events []Event) map[string]int {
+	res := make(map[string]int)
+	for _, e := range events {
+		res[e.Title]++
+	}
+	return res
+}
+
+func TestRecurringStandup(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	id, err := calendar.CreateEvent(Event{
+		UserID: "user1",
+		Title:  "Stand-up",
+		Start:  start,
+		End:    start.Add(15 * time.Minute),
+		RRule:  "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	events, _ := calendar.GetEventsForWeek("2024-03-04", "user1")
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 stand-ups in a week, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.ID != id {
+			t.Errorf("Expected occurrence to keep event ID %d, got %d", id, e.ID)
+		}
+		if e.RecurrenceID != e.Start.UTC().Format(time.RFC3339) {
+			t.Errorf("Expected recurrence ID to match start, got %s for %s", e.RecurrenceID, e.Start)
+		}
+		if e.End.Sub(e.Start) != 15*time.Minute {
+			t.Errorf("Expected 15 minute occurrence, got %s", e.End.Sub(e.Start))
+		}
+	}
+
+	events, _ = calendar.GetEventsForDay("2024-03-09", "user1")
+	if len(events) != 0 {
+		t.Errorf("Expected no stand-up on Saturday, got %d", len(events))
+	}
+	events, _ = calendar.GetEventsForMonth("2024-02-15", "user1")
+	if len(events) != 21 {
+		t.Errorf("Expected 21 stand-ups in February 2024, got %d", len(events))
+	}
+	events, _ = calendar.GetEventsForDay("2023-12-29", "user1")
+	if len(events) != 0 {
+		t.Errorf("Expected no occurrences before the first one, got %d", len(events))
+	}
+}
+
+func TestRecurringExceptionsAndOverrides(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	_, err := calendar.CreateEvent(Event{
+		UserID:  "user1",
+		Title:   "Sync",
+		Start:   start,
+		End:     start.Add(time.Hour),
+		RRule:   "FREQ=DAILY;COUNT=5",
+		ExDates: []string{"2024-03-05"},
+		Overrides: []Override{
+			{RecurrenceID: "2024-03-06T10:00:00Z", Title: "Long sync", End: time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)},
+			{RecurrenceID: "2024-03-07T13:00:00+03:00", Cancelled: true},
+			// Пятничный экземпляр переносится на следующий понедельник
+			{RecurrenceID: "2024-03-08T10:00:00Z", Start: time.Date(2024, 3, 11, 15, 0, 0, 0, time.UTC)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	events, _ := calendar.GetEventsForWeek("2024-03-04", "user1")
+	got := titles(events)
+	if len(events) != 2 || got["Sync"] != 1 || got["Long sync"] != 1 {
+		t.Fatalf("Expected Sync and Long sync in the first week, got %v", got)
+	}
+	for _, e := range events {
+		if e.Title == "Long sync" && e.End.Sub(e.Start) != 2*time.Hour {
+			t.Errorf("Expected overridden end, got %s", e.End.Sub(e.Start))
+		}
+	}
+
+	events, _ = calendar.GetEventsForDay("2024-03-11", "user1")
+	if len(events) != 1 {
+		t.Fatalf("Expected moved occurrence on 2024-03-11, got %d events", len(events))
+	}
+	moved := events[0]
+	if !moved.Start.Equal(time.Date(2024, 3, 11, 15, 0, 0, 0, time.UTC)) || moved.End.Sub(moved.Start) != time.Hour {
+		t.Errorf("Expected moved occurrence 15:00-16:00, got %s-%s", moved.Start, moved.End)
+	}
+	if moved.RecurrenceID != "2024-03-08T10:00:00Z" {
+		t.Errorf("Expected original recurrence ID, got %s", moved.RecurrenceID)
+	}
+}
+
+func TestRecurringAllDayInTimeZone(t *testing.T) {
+	moscow := mustLoadLocation(t, "Europe/Moscow")
+	calendar, _ := NewCalendar(nil)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Review", Date: "2024-01-31", RRule: "FREQ=MONTHLY;BYMONTHDAY=-1"})
+
+	from, to, _ := MonthRange("2024-02-10", moscow)
+	events, _ := calendar.GetEventsInRange("user1", from, to)
+	if len(events) != 1 || events[0].Date != "2024-02-29" || events[0].RecurrenceID != "2024-02-29" {
+		t.Fatalf("Expected review on 2024-02-29, got %+v", events)
+	}
+}
+
+func TestRecurringTimedInEventZone(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	calendar, _ := NewCalendar(nil)
+	calendar.CreateEvent(Event{
+		UserID:   "user1",
+		Title:    "Morning",
+		Start:    time.Date(2024, 3, 25, 9, 0, 0, 0, berlin),
+		TimeZone: "Europe/Berlin",
+		RRule:    "FREQ=DAILY",
+	})
+
+	// После перехода на летнее время событие остается в 09:00 по Берлину, то есть в 07:00 UTC
+	events, _ := calendar.GetEventsForDay("2024-04-02", "user1")
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 occurrence, got %d", len(events))
+	}
+	if got := events[0].Start.UTC().Hour(); got != 7 {
+		t.Errorf("Expected occurrence at 07:00 UTC, got %02d:00", got)
+	}
+}
+
+func TestRecurrenceValidation(t *testing.T) {
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		event Event
+	}{
+		{name: "bad rule", event: Event{Date: "2024-03-04", RRule: "FREQ=SOMETIMES"}},
+		{name: "exdates without rule", event: Event{Date: "2024-03-04", ExDates: []string{"2024-03-05"}}},
+		{name: "bad exdate", event: Event{Date: "2024-03-04", RRule: "FREQ=DAILY", ExDates: []string{"2024-03-05T10:00:00Z"}}},
+		{name: "duplicate override", event: Event{Start: start, RRule: "FREQ=DAILY", Overrides: []Override{
+			{RecurrenceID: "2024-03-05T10:00:00Z", Title: "a"},
+			{RecurrenceID: "2024-03-05T13:00:00+03:00", Title: "b"},
+		}}},
+		{name: "timed override by date", event: Event{Start: start, RRule: "FREQ=DAILY", Overrides: []Override{
+			{RecurrenceID: "2024-03-05", Date: "2024-03-06"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := normalizeEvent(tt.event); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+
+	event, err := normalizeEvent(Event{Start: start, RRule: "freq=weekly;byday=mo", ExDates: []string{"2024-03-11T13:00:00+03:00"}, RecurrenceID: "x"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.RRule != "FREQ=WEEKLY;BYDAY=MO" || event.ExDates[0] != "2024-03-11T10:00:00Z" || event.RecurrenceID != "" {
+		t.Errorf("Expected canonical recurrence fields, got %q %v %q", event.RRule, event.ExDates, event.RecurrenceID)
+	}
+}
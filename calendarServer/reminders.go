@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	// maxReminders ограничивает число напоминаний одного события
+	maxReminders = 10
+	// maxReminderOffset — самое раннее напоминание до начала события
+	maxReminderOffset = 4 * 7 * 24 * time.Hour
+)
+
+// Duration — интервал времени, в JSON записывается строкой вида "15m" или "1h30m"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\"")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// validateReminders проверяет смещения напоминаний события
+func validateReminders(reminders []Duration) error {
+	if len(reminders) > maxReminders {
+		return fmt.Errorf("at most %d reminders are allowed", maxReminders)
+	}
+	seen := make(map[Duration]bool)
+	for _, before := range reminders {
+		if before < 0 || time.Duration(before) > maxReminderOffset {
+			return fmt.Errorf("reminder must be between 0 and %s before the event", maxReminderOffset)
+		}
+		if seen[before] {
+			return fmt.Errorf("duplicate reminder %s", time.Duration(before))
+		}
+		seen[before] = true
+	}
+	return nil
+}
+
+// Notification — сработавшее напоминание о событии или его экземпляре
+type Notification struct {
+	EventID      int       `json:"event_id"`
+	UserID       string    `json:"user_id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description,omitempty"`
+	Start        time.Time `json:"start"`
+	AllDay       bool      `json:"all_day"`
+	Before       Duration  `json:"before"`
+	RecurrenceID string    `json:"recurrence_id,omitempty"`
+	FireAt       time.Time `json:"fire_at"`
+}
+
+// Notifier доставляет напоминания пользователям
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// ReminderScheduler периодически ищет наступившие напоминания и доставляет их через Notifier.
+//
+// Для каждого события хранится отметка reminded_until: все напоминания до нее уже доставлены.
+// Отметка сдвигается только после успешной доставки и сохраняется в хранилище, когда напоминания
+// наступали, поэтому после перезапуска напоминания не повторяются. Напоминания, опоздавшие больше чем на
+// maxLateness (например, пока сервер был выключен), пропускаются, чтобы не засыпать пользователя старыми.
+type ReminderScheduler struct {
+	calendar    *Calendar
+	notifier    Notifier
+	interval    time.Duration
+	maxLateness time.Duration
+	location    *time.Location // пояс, в котором начинаются события на весь день
+	now         func() time.Time
+}
+
+// NewReminderScheduler создает планировщик, проверяющий напоминания раз в interval
+func NewReminderScheduler(calendar *Calendar, notifier Notifier, interval, maxLateness time.Duration, location *time.Location) *ReminderScheduler {
+	return &ReminderScheduler{
+		calendar:    calendar,
+		notifier:    notifier,
+		interval:    interval,
+		maxLateness: maxLateness,
+		location:    location,
+		now:         time.Now,
+	}
+}
+
+// Run проверяет напоминания до отмены ctx
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce доставляет все наступившие напоминания и возвращает число доставленных
+func (s *ReminderScheduler) RunOnce(ctx context.Context) int {
+	now := s.now()
+	delivered := 0
+	for _, event := range s.calendar.eventsWithReminders() {
+		delivered += s.processEvent(ctx, &event, now)
+	}
+	return delivered
+}
+
+// processEvent доставляет напоминания события со временем срабатывания в (reminded_until, now]
+func (s *ReminderScheduler) processEvent(ctx context.Context, event *Event, now time.Time) int {
+	since := event.RemindedUntil
+	if oldest := now.Add(-s.maxLateness); since.Before(oldest) {
+		since = oldest
+	}
+
+	due := s.dueNotifications(event, since, now)
+	watermark := now
+	delivered := 0
+	for _, n := range due {
+		if err := s.notifier.Notify(ctx, n); err != nil {
+			log.Printf("Failed to deliver reminder for event %d: %v", event.ID, err)
+			// Следующая попытка начнется с этого напоминания
+			watermark = n.FireAt.Add(-time.Nanosecond)
+			break
+		}
+		delivered++
+	}
+
+	if watermark.After(event.RemindedUntil) {
+		if err := s.calendar.markReminded(event.ID, watermark, len(due) > 0); err != nil && !errors.Is(err, ErrEventNotFound) {
+			log.Printf("Failed to store reminder state for event %d: %v", event.ID, err)
+		}
+	}
+	return delivered
+}
+
+// dueNotifications возвращает напоминания события со временем срабатывания в (since, now], по возрастанию
+func (s *ReminderScheduler) dueNotifications(event *Event, since, now time.Time) []Notification {
+	var longest time.Duration
+	for _, before := range event.Reminders {
+		longest = max(longest, time.Duration(before))
+	}
+
+	// Подходят экземпляры, начинающиеся в (since, now + самое раннее напоминание].
+	// Экземпляры на весь день раскрываются в UTC, поэтому диапазон расширен на сутки в обе стороны.
+	occurrences := []Event{*event}
+	if event.IsRecurring() {
+		occurrences = event.occurrencesInRange(since.Add(-24*time.Hour), now.Add(longest+24*time.Hour))
+	}
+
+	var due []Notification
+	for _, occ := range occurrences {
+		start, _ := occ.interval(s.location)
+		for _, before := range event.Reminders {
+			fireAt := start.Add(-time.Duration(before))
+			if !fireAt.After(since) || fireAt.After(now) {
+				continue
+			}
+			due = append(due, Notification{
+				EventID:      event.ID,
+				UserID:       event.UserID,
+				Title:        occ.Title,
+				Description:  occ.Description,
+				Start:        start,
+				AllDay:       occ.AllDay,
+				Before:       before,
+				RecurrenceID: occ.RecurrenceID,
+				FireAt:       fireAt,
+			})
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].FireAt.Before(due[j].FireAt) })
+	return due
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier запоминает доставленные напоминания и может имитировать сбой
+type recordingNotifier struct {
+	mu   sync.Mutex
+	got  []Notification
+	fail bool
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fail {
+		return errors.New("delivery failed")
+	}
+	n.got = append(n.got, notification)
+	return nil
+}
+
+// newTestScheduler создает планировщик с управляемыми часами
+func newTestScheduler(calendar *Calendar, notifier Notifier, now *time.Time) *ReminderScheduler {
+	s := NewReminderScheduler(calendar, notifier, time.Minute, time.Hour, time.UTC)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestReminderFiresExactlyOnce(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	id, err := calendar.CreateEvent(Event{UserID: "user1", Title: "Standup", Start: start, Reminders: []Duration{Duration(15 * time.Minute), Duration(time.Hour)}})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	notifier := &recordingNotifier{}
+	now := start.Add(-2 * time.Hour)
+	s := newTestScheduler(calendar, notifier, &now)
+
+	for _, step := range []struct {
+		at   time.Duration
+		want int
+	}{
+		{-2 * time.Hour, 0},
+		{-time.Hour, 1},
+		{-time.Hour, 0}, // повторная проверка в тот же момент
+		{-10 * time.Minute, 1},
+		{time.Hour, 0},
+	} {
+		now = start.Add(step.at)
+		if got := s.RunOnce(context.Background()); got != step.want {
+			t.Errorf("At %s: expected %d reminders, got %d", step.at, step.want, got)
+		}
+	}
+	if len(notifier.got) != 2 || notifier.got[0].Before != Duration(time.Hour) || notifier.got[1].Before != Duration(15*time.Minute) {
+		t.Fatalf("Unexpected notifications: %+v", notifier.got)
+	}
+
+	// Клиент не может сбросить отметку и получить напоминания повторно
+	event, _ := calendar.GetEvent("user1", id)
+	event.RemindedUntil = time.Time{}
+	event.Title = "Renamed"
+	if err := calendar.UpdateEvent(event); err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if got := s.RunOnce(context.Background()); got != 0 {
+		t.Errorf("Expected no reminders after update, got %d", got)
+	}
+}
+
+func TestReminderRetriesAfterFailure(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Call", Start: start, Reminders: []Duration{Duration(10 * time.Minute)}})
+
+	notifier := &recordingNotifier{fail: true}
+	now := start.Add(-5 * time.Minute)
+	s := newTestScheduler(calendar, notifier, &now)
+	if got := s.RunOnce(context.Background()); got != 0 {
+		t.Fatalf("Expected failed delivery, got %d", got)
+	}
+
+	notifier.fail = false
+	now = now.Add(time.Minute)
+	if got := s.RunOnce(context.Background()); got != 1 {
+		t.Errorf("Expected reminder to be retried, got %d", got)
+	}
+
+	// Напоминания, опоздавшие больше чем на maxLateness, не отправляются
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Long ago", Start: start.Add(-3 * time.Hour), Reminders: []Duration{0}})
+	if got := s.RunOnce(context.Background()); got != 0 {
+		t.Errorf("Expected stale reminder to be skipped, got %d", got)
+	}
+}
+
+func TestRecurringReminders(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{
+		UserID: "user1", Title: "Daily", Start: start, RRule: "FREQ=DAILY",
+		ExDates:   []string{"2024-03-05"},
+		Overrides: []Override{{RecurrenceID: "2024-03-06T09:00:00Z", Title: "Moved", Start: start.Add(2*24*time.Hour + time.Hour)}},
+		Reminders: []Duration{Duration(30 * time.Minute)},
+	})
+	// Напоминание о событии на весь день приходит в полночь пояса планировщика
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Holiday", Date: "2024-03-06", Reminders: []Duration{0}})
+
+	notifier := &recordingNotifier{}
+	now := start.Add(-time.Hour)
+	s := newTestScheduler(calendar, notifier, &now)
+	for now.Before(start.Add(3 * 24 * time.Hour)) {
+		s.RunOnce(context.Background())
+		now = now.Add(10 * time.Minute)
+	}
+
+	var got []string
+	for _, n := range notifier.got {
+		got = append(got, n.Title+" "+n.FireAt.Format(time.RFC3339))
+	}
+	want := []string{
+		"Daily 2024-03-04T08:30:00Z",
+		"Holiday 2024-03-06T00:00:00Z",
+		"Moved 2024-03-06T09:30:00Z",
+		"Daily 2024-03-07T08:30:00Z",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if notifier.got[2].RecurrenceID != "2024-03-06T09:00:00Z" {
+		t.Errorf("Expected recurrence ID of the moved occurrence, got %q", notifier.got[2].RecurrenceID)
+	}
+}
+
+func TestRemindersSurviveRestart(t *testing.T) {
+	for kind, open := range openTestRepositories(t) {
+		t.Run(kind, func(t *testing.T) {
+			calendar, _ := NewCalendar(open())
+			start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+			calendar.CreateEvent(Event{UserID: "user1", Title: "Persistent", Start: start, Reminders: []Duration{Duration(5 * time.Minute)}})
+
+			notifier := &recordingNotifier{}
+			now := start.Add(-time.Minute)
+			if got := newTestScheduler(calendar, notifier, &now).RunOnce(context.Background()); got != 1 {
+				t.Fatalf("Expected one reminder, got %d", got)
+			}
+			calendar.Close()
+
+			restarted, err := NewCalendar(open())
+			if err != nil {
+				t.Fatalf("Failed to reopen calendar: %v", err)
+			}
+			defer restarted.Close()
+			if got := newTestScheduler(restarted, notifier, &now).RunOnce(context.Background()); got != 0 {
+				t.Errorf("Expected no repeated reminders after restart, got %d", got)
+			}
+		})
+	}
+}
+
+func TestIdleRemindersDoNotWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, err := OpenFileRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar, _ := NewCalendar(repo)
+	defer calendar.Close()
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Later", Start: start, Reminders: []Duration{Duration(5 * time.Minute)}})
+	info, _ := os.Stat(path)
+
+	// Напоминание еще не наступило: проверки ничего не пишут в журнал
+	notifier := &recordingNotifier{}
+	now := start.Add(-time.Hour)
+	s := newTestScheduler(calendar, notifier, &now)
+	for i := 0; i < 2; i++ {
+		if got := s.RunOnce(context.Background()); got != 0 {
+			t.Fatalf("Expected no reminders, got %d", got)
+		}
+		now = now.Add(time.Minute)
+	}
+	if after, _ := os.Stat(path); after.Size() != info.Size() {
+		t.Errorf("Expected no storage writes without due reminders, log grew from %d to %d bytes", info.Size(), after.Size())
+	}
+
+	now = start.Add(-time.Minute)
+	if got := s.RunOnce(context.Background()); got != 1 {
+		t.Errorf("Expected the reminder once it is due, got %d", got)
+	}
+	if after, _ := os.Stat(path); after.Size() == info.Size() {
+		t.Error("Expected the delivered reminder to be stored")
+	}
+}
+
+func TestReminderValidation(t *testing.T) {
+	var event Event
+	if err := json.Unmarshal([]byte(`{"reminders":["15m","1h30m"]}`), &event); err != nil {
+		t.Fatalf("Failed to decode reminders: %v", err)
+	}
+	if len(event.Reminders) != 2 || event.Reminders[1] != Duration(90*time.Minute) {
+		t.Errorf("Unexpected reminders: %v", event.Reminders)
+	}
+	if err := json.Unmarshal([]byte(`{"reminders":[15]}`), &event); err == nil {
+		t.Error("Expected error for numeric reminder")
+	}
+
+	base := Event{UserID: "user1", Title: "Event", Date: "2024-03-10"}
+	for _, reminders := range [][]Duration{
+		{Duration(-time.Minute)},
+		{Duration(60 * 24 * time.Hour)},
+		{Duration(time.Hour), Duration(time.Hour)},
+	} {
+		base.Reminders = reminders
+		if err := ValidateEvent(base); err == nil {
+			t.Errorf("Expected validation error for %v", reminders)
+		}
+	}
+}
+
+func TestRemindersICalendarRoundTrip(t *testing.T) {
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	var buf strings.Builder
+	event := Event{ID: 1, UserID: "user1", Title: "Alarm", Start: start, End: start.Add(time.Hour), Reminders: []Duration{Duration(15 * time.Minute), Duration(25 * time.Hour)}}
+	if err := WriteICalendar(&buf, []Event{event}, start); err != nil {
+		t.Fatalf("Failed to write calendar: %v", err)
+	}
+	for _, want := range []string{"BEGIN:VALARM", "TRIGGER:-PT15M", "TRIGGER:-P1DT1H"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, buf.String())
+		}
+	}
+
+	calendar, _ := NewCalendar(nil)
+	if _, err := ImportICalendar(calendar, "user1", strings.NewReader(buf.String())); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	imported := calendar.GetUserEvents("user1", time.Time{}, time.Time{})
+	if len(imported) != 1 || len(imported[0].Reminders) != 2 || imported[0].Reminders[1] != Duration(25*time.Hour) {
+		t.Errorf("Expected reminders to survive round trip, got %+v", imported)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Notification
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, time.Second)
+	n := Notification{EventID: 7, UserID: "user1", Title: "Hook", Before: Duration(time.Minute)}
+	if err := notifier.Notify(context.Background(), n); err != nil {
+		t.Fatalf("Expected delivery, got %v", err)
+	}
+	if received.EventID != 7 || received.Before != Duration(time.Minute) {
+		t.Errorf("Unexpected payload: %+v", received)
+	}
+
+	status = http.StatusInternalServerError
+	if err := notifier.Notify(context.Background(), n); err == nil {
+		t.Error("Expected error on 500 response")
+	}
+}
+
+// fakeSMTPServer принимает письма по минимальному подмножеству SMTP
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	rcpts    []string
+	messages []string
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost fake SMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var msg strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				msg.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	notifier := NewSMTPNotifier(server.listener.Addr().String(), "calendar@example.com", "example.com", "", "")
+
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	err := notifier.Notify(context.Background(), Notification{UserID: "user1", Title: "Планёрка", Description: "Комната 1", Start: start})
+	if err != nil {
+		t.Fatalf("Failed to send email: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), Notification{UserID: "bob@other.org", Title: "Direct", Start: start}); err != nil {
+		t.Fatalf("Failed to send email: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !equalStrings(server.rcpts, []string{"user1@example.com", "bob@other.org"}) {
+		t.Errorf("Unexpected recipients: %v", server.rcpts)
+	}
+	if len(server.messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(server.messages))
+	}
+	for _, want := range []string{"To: user1@example.com", "Subject: =?utf-8?q?", "Starts: 2024-03-10 09:00 UTC", "Комната 1"} {
+		if !strings.Contains(server.messages[0], want) {
+			t.Errorf("Expected %q in message:\n%s", want, server.messages[0])
+		}
+	}
+
+	if err := NewSMTPNotifier(server.listener.Addr().String(), "calendar@example.com", "", "", "").Notify(context.Background(), Notification{UserID: "user1"}); err == nil {
+		t.Error("Expected error without email domain")
+	}
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency — частота повторения (FREQ)
+type Frequency int
+
+const (
+	Daily Frequency = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencyNames = map[string]Frequency{
+	"DAILY":   Daily,
+	"WEEKLY":  Weekly,
+	"MONTHLY": Monthly,
+	"YEARLY":  Yearly,
+}
+
+func (f Frequency) String() string {
+	for name, freq := range frequencyNames {
+		if freq == f {
+			return name
+		}
+	}
+	return ""
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// weekdayCode возвращает двухбуквенный код дня недели из RFC 5545
+func weekdayCode(d time.Weekday) string {
+	return strings.ToUpper(d.String()[:2])
+}
+
+// WeekdayNum — элемент BYDAY: день недели и необязательный порядковый номер (1MO, -1FR)
+type WeekdayNum struct {
+	N   int // 0 — каждый такой день периода
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayCode(w.Day)
+	}
+	return strconv.Itoa(w.N) + weekdayCode(w.Day)
+}
+
+// RRule — правило повторения RFC 5545.
+// Поддерживаются FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH и WKST.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time // нулевое значение — без ограничения
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+
+	untilDate     bool // UNTIL задан датой: ограничение включает весь этот день
+	untilFloating bool // UNTIL задан местным временем без пояса
+}
+
+// maxEmptyYears ограничивает перебор периодов подряд без единого экземпляра,
+// чтобы правила вроде FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30 не зацикливались. Ограничение по времени,
+// а не по числу периодов: григорианский календарь повторяется через 400 лет, поэтому правило без
+// экземпляров за такой срок их уже не даст, а редкие даты (29 февраля) не теряются и у FREQ=DAILY.
+const maxEmptyYears = 400
+
+// ParseRRule разбирает значение RRULE (с префиксом "RRULE:" или без него)
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	r := &RRule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate recurrence rule part %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			freq, ok := frequencyNames[value]
+			if !ok {
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+			r.Freq = freq
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			if err := r.parseUntil(value); err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(item)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", item)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", item)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			day, ok := weekdayNames[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", value)
+			}
+			r.WeekStart = day
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part %s", key)
+		}
+	}
+
+	if r.Freq == 0 {
+		return nil, errors.New("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL must not be used together")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return nil, errors.New("BYMONTHDAY must not be used with FREQ=WEEKLY")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, fmt.Errorf("BYDAY with ordinal %s requires FREQ=MONTHLY or FREQ=YEARLY", wd)
+		}
+	}
+	return r, nil
+}
+
+// parseUntil разбирает UNTIL в одной из форм: дата, время UTC или местное время
+func (r *RRule) parseUntil(value string) error {
+	var err error
+	switch {
+	case len(value) == 8:
+		r.Until, err = time.Parse("20060102", value)
+		r.untilDate = true
+	case strings.HasSuffix(value, "Z"):
+		r.Until, err = time.Parse("20060102T150405Z", value)
+	default:
+		r.Until, err = time.Parse("20060102T150405", value)
+		r.untilFloating = true
+	}
+	if err != nil {
+		return fmt.Errorf("invalid UNTIL %q", value)
+	}
+	return nil
+}
+
+// parseWeekdayNum разбирает элемент BYDAY: MO, 2TU, -1FR
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	day, ok := weekdayNames[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// String возвращает правило в каноническом виде RFC 5545 (без префикса RRULE:)
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		switch {
+		case r.untilDate:
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		case r.untilFloating:
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		default:
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if len(r.ByDay) > 0 {
+		items := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			items[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(items, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		items := make([]string, len(r.ByMonthDay))
+		for i, n := range r.ByMonthDay {
+			items[i] = strconv.Itoa(n)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(items, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		items := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			items[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(items, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.WeekStart))
+	}
+	return strings.Join(parts, ";")
+}
+
+// civilDate — календарная дата без времени и пояса
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) civilDate {
+	y, m, d := t.Date()
+	return civilDate{y, m, d}
+}
+
+// normalized переводит дату вида «32 января» в корректную
+func (d civilDate) normalized() civilDate {
+	return dateOf(time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC))
+}
+
+func (d civilDate) weekday() time.Weekday {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC).Weekday()
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Each перебирает экземпляры правила, начиная с dtstart, по возрастанию времени.
+// Первый экземпляр — всегда сам dtstart. Время суток каждого экземпляра совпадает
+// с dtstart в его поясе, поэтому переходы на летнее время не сдвигают события.
+// Перебор останавливается, когда fn возвращает false или правило исчерпано (COUNT, UNTIL).
+func (r *RRule) Each(dtstart time.Time, fn func(time.Time) bool) {
+	loc := dtstart.Location()
+	until := r.Until
+	switch {
+	case r.untilDate:
+		// Дата UNTIL включается целиком
+		until = time.Date(until.Year(), until.Month(), until.Day()+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+	case r.untilFloating:
+		until = time.Date(until.Year(), until.Month(), until.Day(), until.Hour(), until.Minute(), until.Second(), 0, loc)
+	}
+
+	count := 0
+	emit := func(t time.Time) bool {
+		if !until.IsZero() && t.After(until) {
+			return false
+		}
+		count++
+		if !fn(t) {
+			return false
+		}
+		return r.Count == 0 || count < r.Count
+	}
+
+	if !emit(dtstart) {
+		return
+	}
+
+	hour, min, sec := dtstart.Clock()
+	start := dateOf(dtstart)
+	maxEmpty := r.periodsIn(maxEmptyYears)
+	empty := 0
+	for period := 0; empty < maxEmpty; period++ {
+		days := r.periodDays(start, period)
+		if len(days) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, d := range days {
+			t := time.Date(d.year, d.month, d.day, hour, min, sec, dtstart.Nanosecond(), loc)
+			if !t.After(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// periodsIn возвращает, сколько периодов правила (с учетом INTERVAL) укладывается в years лет
+func (r *RRule) periodsIn(years int) int {
+	days := years * 146097 / 400 // средняя длина григорианского года
+	var perPeriod int
+	switch r.Freq {
+	case Daily:
+		perPeriod = 1
+	case Weekly:
+		perPeriod = 7
+	case Monthly:
+		return max(years*12/r.Interval, 1)
+	default:
+		return max(years/r.Interval, 1)
+	}
+	return max(days/(perPeriod*r.Interval), 1)
+}
+
+// periodDays возвращает отсортированные даты-кандидаты периода с номером period
+func (r *RRule) periodDays(start civilDate, period int) []civilDate {
+	step := period * r.Interval
+	switch r.Freq {
+	case Daily:
+		d := civilDate{start.year, start.month, start.day + step}.normalized()
+		if r.matchMonth(d) && r.matchMonthDay(d) && r.matchWeekday(d) {
+			return []civilDate{d}
+		}
+		return nil
+
+	case Weekly:
+		// Неделя начинается с WKST и содержит dtstart
+		offset := (int(start.weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := civilDate{start.year, start.month, start.day - offset + 7*step}
+		var days []civilDate
+		for i := 0; i < 7; i++ {
+			d := civilDate{weekStart.year, weekStart.month, weekStart.day + i}.normalized()
+			if !r.matchMonth(d) {
+				continue
+			}
+			if len(r.ByDay) == 0 {
+				if d.weekday() == start.weekday() {
+					days = append(days, d)
+				}
+			} else if r.matchWeekday(d) {
+				days = append(days, d)
+			}
+		}
+		return days
+
+	case Monthly:
+		first := civilDate{start.year, start.month + time.Month(step), 1}.normalized()
+		if !r.matchMonth(first) {
+			return nil
+		}
+		return r.monthDays(first.year, first.month, start.day)
+
+	case Yearly:
+		year := start.year + step
+		// BYDAY без BYMONTH и BYMONTHDAY — дни недели в пределах года
+		if len(r.ByDay) > 0 && len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 {
+			return r.yearWeekdays(year)
+		}
+		months := r.ByMonth
+		if len(months) == 0 {
+			if len(r.ByMonthDay) > 0 || len(r.ByDay) > 0 {
+				months = []time.Month{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+			} else {
+				months = []time.Month{start.month}
+			}
+		}
+		var days []civilDate
+		for _, m := range sortedMonths(months) {
+			days = append(days, r.monthDays(year, m, start.day)...)
+		}
+		return days
+	}
+	return nil
+}
+
+// monthDays раскрывает BYMONTHDAY и BYDAY в пределах месяца.
+// Без них берется день месяца dtstart; если такого дня в месяце нет, месяц пропускается.
+func (r *RRule) monthDays(year int, month time.Month, defaultDay int) []civilDate {
+	n := daysIn(year, month)
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if defaultDay > n {
+			return nil
+		}
+		return []civilDate{{year, month, defaultDay}}
+	}
+
+	var byMonthDay, byDay map[int]bool
+	if len(r.ByMonthDay) > 0 {
+		byMonthDay = make(map[int]bool)
+		for _, md := range r.ByMonthDay {
+			day := md
+			if md < 0 {
+				day = n + 1 + md
+			}
+			if day >= 1 && day <= n {
+				byMonthDay[day] = true
+			}
+		}
+	}
+	if len(r.ByDay) > 0 {
+		byDay = make(map[int]bool)
+		for _, wd := range r.ByDay {
+			var matches []int
+			for day := 1; day <= n; day++ {
+				if (civilDate{year, month, day}).weekday() == wd.Day {
+					matches = append(matches, day)
+				}
+			}
+			for _, day := range pickOrdinal(matches, wd.N) {
+				byDay[day] = true
+			}
+		}
+	}
+
+	var days []civilDate
+	for day := 1; day <= n; day++ {
+		if byMonthDay != nil && !byMonthDay[day] {
+			continue
+		}
+		if byDay != nil && !byDay[day] {
+			continue
+		}
+		days = append(days, civilDate{year, month, day})
+	}
+	return days
+}
+
+// yearWeekdays раскрывает BYDAY в пределах года (например, 20MO — двадцатый понедельник года)
+func (r *RRule) yearWeekdays(year int) []civilDate {
+	selected := make(map[civilDate]bool)
+	for _, wd := range r.ByDay {
+		var matches []civilDate
+		for d := (civilDate{year, time.January, 1}); d.year == year; d = (civilDate{d.year, d.month, d.day + 1}).normalized() {
+			if d.weekday() == wd.Day {
+				matches = append(matches, d)
+			}
+		}
+		idx := make([]int, len(matches))
+		for i := range idx {
+			idx[i] = i
+		}
+		for _, i := range pickOrdinal(idx, wd.N) {
+			selected[matches[i]] = true
+		}
+	}
+	days := make([]civilDate, 0, len(selected))
+	for d := range selected {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		a, b := days[i], days[j]
+		if a.month != b.month {
+			return a.month < b.month
+		}
+		return a.day < b.day
+	})
+	return days
+}
+
+// pickOrdinal выбирает из списка элемент с порядковым номером n (с конца, если n < 0), при n == 0 — все
+func pickOrdinal(items []int, n int) []int {
+	switch {
+	case n == 0:
+		return items
+	case n > 0 && n <= len(items):
+		return []int{items[n-1]}
+	case n < 0 && -n <= len(items):
+		return []int{items[len(items)+n]}
+	}
+	return nil
+}
+
+func sortedMonths(months []time.Month) []time.Month {
+	res := append([]time.Month(nil), months...)
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func (r *RRule) matchMonth(d civilDate) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == d.month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchMonthDay(d civilDate) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	n := daysIn(d.year, d.month)
+	for _, md := range r.ByMonthDay {
+		if md == d.day || (md < 0 && n+1+md == d.day) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchWeekday(d civilDate) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	wd := d.weekday()
+	for _, item := range r.ByDay {
+		if item.Day == wd {
+			return true
+		}
+	}
+	return false
+}
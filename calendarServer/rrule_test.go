@@ -0,0 +1,159 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// expand возвращает первые limit экземпляров правила в формате layout
+func expand(t *testing.T, rule string, dtstart time.Time, limit int, layout string) []string {
+	t.Helper()
+	r, err := ParseRRule(rule)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", rule, err)
+	}
+	var res []string
+	r.Each(dtstart, func(occ time.Time) bool {
+		res = append(res, occ.Format(layout))
+		return len(res) < limit
+	})
+	return res
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRRuleExpansion(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		limit   int
+		want    []string
+	}{
+		{
+			name: "daily count", rule: "FREQ=DAILY;COUNT=3", dtstart: "2024-01-30", limit: 10,
+			want: []string{"2024-01-30", "2024-01-31", "2024-02-01"},
+		},
+		{
+			name: "every other weekday", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR", dtstart: "2024-01-01", limit: 6,
+			want: []string{"2024-01-01", "2024-01-03", "2024-01-05", "2024-01-15", "2024-01-17", "2024-01-19"},
+		},
+		{
+			name: "weekly defaults to start weekday", rule: "FREQ=WEEKLY", dtstart: "2024-01-03", limit: 3,
+			want: []string{"2024-01-03", "2024-01-10", "2024-01-17"},
+		},
+		{
+			name: "last friday of month", rule: "FREQ=MONTHLY;BYDAY=-1FR", dtstart: "2024-01-26", limit: 3,
+			want: []string{"2024-01-26", "2024-02-23", "2024-03-29"},
+		},
+		{
+			name: "last day of month", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", dtstart: "2024-01-31", limit: 3,
+			want: []string{"2024-01-31", "2024-02-29", "2024-03-31"},
+		},
+		{
+			name: "31st skips short months", rule: "FREQ=MONTHLY", dtstart: "2024-01-31", limit: 3,
+			want: []string{"2024-01-31", "2024-03-31", "2024-05-31"},
+		},
+		{
+			name: "friday the 13th", rule: "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", dtstart: "2024-09-13", limit: 3,
+			want: []string{"2024-09-13", "2024-12-13", "2025-06-13"},
+		},
+		{
+			name: "leap day", rule: "FREQ=YEARLY", dtstart: "2024-02-29", limit: 2,
+			want: []string{"2024-02-29", "2028-02-29"},
+		},
+		{
+			name: "yearly by month and day", rule: "FREQ=YEARLY;BYMONTH=3;BYDAY=2SU", dtstart: "2024-03-10", limit: 2,
+			want: []string{"2024-03-10", "2025-03-09"},
+		},
+		{
+			name: "until date is inclusive", rule: "FREQ=DAILY;INTERVAL=3;UNTIL=20240107", dtstart: "2024-01-01", limit: 10,
+			want: []string{"2024-01-01", "2024-01-04", "2024-01-07"},
+		},
+		{
+			name: "impossible date stops", rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", dtstart: "2024-01-01", limit: 10,
+			want: []string{"2024-01-01"},
+		},
+		{
+			name: "impossible daily date stops", rule: "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=30", dtstart: "2024-01-01", limit: 10,
+			want: []string{"2024-01-01"},
+		},
+		{
+			// 2100 не високосный: между 29 февраля 2096 и 2104 больше 2900 пустых дней
+			name: "daily leap day across a long gap", rule: "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29", dtstart: "2096-02-29", limit: 3,
+			want: []string{"2096-02-29", "2104-02-29", "2108-02-29"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dtstart, _ := time.Parse(dateLayout, tt.dtstart)
+			got := expand(t, tt.rule, dtstart, tt.limit, dateLayout)
+			if !equalStrings(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRRuleKeepsWallClockAcrossDST(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	// Переход на летнее время в Берлине — 31 марта 2024
+	dtstart := time.Date(2024, 3, 29, 9, 0, 0, 0, berlin)
+
+	got := expand(t, "FREQ=DAILY;COUNT=4", dtstart, 10, "2006-01-02 15:04 -0700")
+	want := []string{"2024-03-29 09:00 +0100", "2024-03-30 09:00 +0100", "2024-03-31 09:00 +0200", "2024-04-01 09:00 +0200"}
+	if !equalStrings(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRRuleUntilTimestamp(t *testing.T) {
+	dtstart := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	got := expand(t, "FREQ=DAILY;UNTIL=20240103T100000Z", dtstart, 10, time.RFC3339)
+	want := []string{"2024-01-01T10:00:00Z", "2024-01-02T10:00:00Z", "2024-01-03T10:00:00Z"}
+	if !equalStrings(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240101",
+		"FREQ=DAILY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		if _, err := ParseRRule(rule); err == nil {
+			t.Errorf("Expected error for %q, got nil", rule)
+		}
+	}
+}
+
+func TestRRuleString(t *testing.T) {
+	r, err := ParseRRule("RRULE:freq=monthly;byday=-1fr,2MO;interval=2;until=20241231T235959Z")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	want := "FREQ=MONTHLY;INTERVAL=2;UNTIL=20241231T235959Z;BYDAY=-1FR,2MO"
+	if got := r.String(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
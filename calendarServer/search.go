@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxTags и maxTagLength ограничивают метки одного события
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+// textFolder приводит текст к виду для сравнения без учета регистра: ё и е не различаются
+var textFolder = strings.NewReplacer("ё", "е")
+
+// foldText приводит строку к нижнему регистру (в том числе кириллицу) и заменяет ё на е
+func foldText(s string) string {
+	return textFolder.Replace(strings.ToLower(s))
+}
+
+// tokenize разбивает текст на уникальные слова из букв и цифр в свернутом регистре
+func tokenize(s string) []string {
+	words := strings.FieldsFunc(foldText(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	tokens := words[:0]
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// normalizeTags проверяет метки и приводит их к каноническому виду: нижний регистр, без повторов, по алфавиту
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = foldText(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errors.New("tag must not be empty")
+		}
+		if len([]rune(tag)) > maxTagLength || strings.ContainsAny(tag, ",;\r\n") {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			res = append(res, tag)
+		}
+	}
+	if len(res) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// EventFilter отбирает события по подстроке в названии или описании и по меткам
+type EventFilter struct {
+	Text string   // подстрока в свернутом регистре
+	Tags []string // событие должно иметь все метки
+}
+
+func (f EventFilter) matches(e *Event) bool {
+	if f.Text != "" && !strings.Contains(foldText(e.Title), f.Text) && !strings.Contains(foldText(e.Description), f.Text) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !containsString(e.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// eventTerms возвращает слова события для полнотекстового индекса, включая измененные экземпляры
+func eventTerms(e *Event) []string {
+	text := []string{e.Title, e.Description}
+	for _, o := range e.Overrides {
+		text = append(text, o.Title, o.Description)
+	}
+	return tokenize(strings.Join(text, " "))
+}
+
+// indexTerms добавляет слова события в инвертированный индекс пользователя
+func (idx *userIndex) indexTerms(e *Event) {
+	if idx.terms == nil {
+		idx.terms = make(map[string]map[int]struct{})
+	}
+	for _, term := range eventTerms(e) {
+		ids := idx.terms[term]
+		if ids == nil {
+			ids = make(map[int]struct{})
+			idx.terms[term] = ids
+		}
+		ids[e.ID] = struct{}{}
+	}
+}
+
+// removeTerms удаляет слова события из инвертированного индекса
+func (idx *userIndex) removeTerms(e *Event) {
+	for _, term := range eventTerms(e) {
+		delete(idx.terms[term], e.ID)
+		if len(idx.terms[term]) == 0 {
+			delete(idx.terms, term)
+		}
+	}
+}
+
+// Search возвращает события пользователя, содержащие все слова запроса в названии или описании, по возрастанию ID.
+// Слова сравниваются целиком без учета регистра; ё и е не различаются.
+func (c *Calendar) Search(userID, query string) []Event {
+	terms := tokenize(query)
+	events := make([]Event, 0)
+	if len(terms) == 0 {
+		return events
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	idx := c.index[userID]
+	if idx == nil {
+		return events
+	}
+
+	// Пересекаем списки, начиная с самого короткого
+	sort.Slice(terms, func(i, j int) bool { return len(idx.terms[terms[i]]) < len(idx.terms[terms[j]]) })
+	for id := range idx.terms[terms[0]] {
+		found := true
+		for _, term := range terms[1:] {
+			if _, ok := idx.terms[term][id]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			events = append(events, *c.events[id])
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
+
+// searchHandler обрабатывает GET /api/v1/users/{user}/search?q=...: полнотекстовый поиск без раскрытия повторений
+func searchHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		query := r.URL.Query().Get("q")
+		if len(tokenize(query)) == 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "q must contain at least one word")
+			return
+		}
+		opts, err := parseListOptions(r, defaultPageLimit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Параметр q здесь — поисковый запрос, а не фильтр по подстроке
+		opts.Filter.Text = ""
+		writeEventPage(w, r, "Search results", calendar.Search(userID, query), opts, idKey)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Планёрка: ОТДЕЛ продаж, отдел-2024 (Ёлка)")
+	want := []string{"планерка", "отдел", "продаж", "2024", "елка"}
+	if !equalStrings(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	tags, err := normalizeTags([]string{" Работа ", "работа", "ЛИЧНОЕ"})
+	if err != nil || !equalStrings(tags, []string{"личное", "работа"}) {
+		t.Errorf("Unexpected tags %v, err %v", tags, err)
+	}
+	for _, bad := range [][]string{{""}, {"a,b"}, {strings.Repeat("x", maxTagLength+1)}} {
+		if _, err := normalizeTags(bad); err == nil {
+			t.Errorf("Expected error for tags %q", bad)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	standup, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Ежедневная планёрка", Description: "Отдел продаж", Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Планерка руководства", Date: "2024-03-11"})
+	calendar.CreateEvent(Event{UserID: "user2", Title: "Планерка", Date: "2024-03-10"})
+
+	check := func(query string, want ...string) {
+		t.Helper()
+		var got []string
+		for _, event := range calendar.Search("user1", query) {
+			got = append(got, event.Title)
+		}
+		if !equalStrings(got, want) {
+			t.Errorf("Search %q: expected %v, got %v", query, want, got)
+		}
+	}
+	check("ПЛАНЕРКА", "Ежедневная планёрка", "Планерка руководства")
+	check("планерка продаж", "Ежедневная планёрка")
+	check("планерка бухгалтерии")
+	check("план")
+
+	// Индекс обновляется при изменении и удалении
+	calendar.UpdateEvent(Event{ID: standup, UserID: "user1", Title: "Ретроспектива", Date: "2024-03-10"})
+	check("продаж")
+	check("ретроспектива", "Ретроспектива")
+	calendar.DeleteUserEvent("user1", standup)
+	check("ретроспектива")
+}
+
+// fetchPage выполняет запрос списка и возвращает названия событий и курсор следующей страницы
+func fetchPage(t *testing.T, h http.Handler, target string) ([]string, string) {
+	t.Helper()
+	rec := serve(h, http.MethodGet, target, "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: expected 200, got %d: %s", target, rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data []Event `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	next := rec.Header().Get("X-Next-Cursor")
+	if next != "" && !strings.Contains(rec.Header().Get("Link"), "cursor="+next) {
+		t.Errorf("Expected Link header with the next cursor, got %q", rec.Header().Get("Link"))
+	}
+	return orderedTitles(resp.Data), next
+}
+
+func TestCursorPagination(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		calendar.CreateEvent(Event{UserID: "user1", Title: fmt.Sprintf("Event %d", i), Start: start.Add(time.Duration(i) * time.Hour)})
+	}
+
+	var all []string
+	target := "/api/v1/users/user1/events?from=2024-03-10&to=2024-03-10&limit=3"
+	page, next := fetchPage(t, router, target)
+	all = append(all, page...)
+	// Событие, добавленное в начало между запросами, не сдвигает следующие страницы
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Early", Start: start.Add(-time.Hour)})
+	for next != "" {
+		page, next = fetchPage(t, router, target+"&cursor="+next)
+		all = append(all, page...)
+	}
+	want := []string{"Event 0", "Event 1", "Event 2", "Event 3", "Event 4", "Event 5", "Event 6"}
+	if !equalStrings(all, want) {
+		t.Errorf("Expected %v, got %v", want, all)
+	}
+
+	// Без from и to список упорядочен по ID
+	page, next = fetchPage(t, router, "/api/v1/users/user1/events?limit=7")
+	if len(page) != 7 || page[0] != "Event 0" || next == "" {
+		t.Errorf("Unexpected first page %v, next %q", page, next)
+	}
+	if page, next = fetchPage(t, router, "/api/v1/users/user1/events?limit=7&cursor="+next); !equalStrings(page, []string{"Early"}) || next != "" {
+		t.Errorf("Unexpected last page %v, next %q", page, next)
+	}
+
+	// Старые маршруты без limit возвращают все события, как раньше
+	if page, next := fetchPage(t, router, "/events_for_day?user_id=user1&date=2024-03-10"); len(page) != 8 || next != "" {
+		t.Errorf("Expected all events on legacy route, got %v", page)
+	}
+	for _, target := range []string{
+		"/api/v1/users/user1/events?cursor=garbage!",
+		"/api/v1/users/user1/events?limit=0",
+		"/events_for_day?user_id=user1&date=2024-03-10&limit=100000",
+	} {
+		if rec := serve(router, http.MethodGet, target, "", ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(nil, nil), nil)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Встреча с клиентом", Tags: []string{"Работа", "Клиенты"}, Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Спортзал", Description: "Встреча с тренером", Tags: []string{"личное"}, Date: "2024-03-10"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Отчет", Tags: []string{"работа"}, Date: "2024-03-10"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"q=" + url.QueryEscape("ВСТРЕЧА"), []string{"Встреча с клиентом", "Спортзал"}},
+		{"tags=" + url.QueryEscape("Работа"), []string{"Встреча с клиентом", "Отчет"}},
+		{"tags=" + url.QueryEscape("работа,клиенты"), []string{"Встреча с клиентом"}},
+		{"q=" + url.QueryEscape("тренер") + "&tags=" + url.QueryEscape("работа"), nil},
+	}
+	for _, tt := range tests {
+		page, _ := fetchPage(t, router, "/events_for_day?user_id=user1&date=2024-03-10&"+tt.query)
+		if !equalStrings(page, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, page)
+		}
+	}
+
+	page, _ := fetchPage(t, router, "/api/v1/users/user1/search?q="+url.QueryEscape("встреча")+"&tags=work-free")
+	if len(page) != 0 {
+		t.Errorf("Expected tag filter to apply to search, got %v", page)
+	}
+	page, _ = fetchPage(t, router, "/api/v1/users/user1/search?q="+url.QueryEscape("встреча"))
+	if !equalStrings(page, []string{"Встреча с клиентом", "Спортзал"}) {
+		t.Errorf("Unexpected search results %v", page)
+	}
+	if rec := serve(router, http.MethodGet, "/api/v1/users/user1/search?q=...", "", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty search, got %d", rec.Code)
+	}
+}
+
+func TestTagsICalendarRoundTrip(t *testing.T) {
+	var buf strings.Builder
+	WriteICalendar(&buf, []Event{{ID: 1, UserID: "user1", Title: "Tagged", Date: "2024-03-10", AllDay: true, Tags: []string{"дом", "работа"}}}, time.Now())
+	if !strings.Contains(buf.String(), "CATEGORIES:дом,работа") {
+		t.Fatalf("Expected CATEGORIES in:\n%s", buf.String())
+	}
+
+	calendar, _ := NewCalendar(nil)
+	ics := strings.Replace(buf.String(), "CATEGORIES:дом,работа", `CATEGORIES:Дом,a\,b,Работа`, 1)
+	if _, err := ImportICalendar(calendar, "user1", strings.NewReader(ics)); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	events := calendar.GetUserEvents("user1", time.Time{}, time.Time{})
+	if len(events) != 1 || !equalStrings(events[0].Tags, []string{"дом", "работа"}) {
+		t.Errorf("Expected tags to be imported, got %+v", events)
+	}
+}
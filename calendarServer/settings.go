@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSettings — недопустимые настройки пользователя
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// localeTag — тег языка BCP 47: язык и необязательные подтеги (регион, письменность), например en-US
+var localeTag = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+
+// regionWeekStart — регионы, где неделя начинается не с понедельника (по данным CLDR).
+// В остальных регионах, как и в ISO 8601, первый день недели — понедельник.
+var regionWeekStart = map[string]time.Weekday{
+	"AE": time.Saturday, "AF": time.Saturday, "BH": time.Saturday, "DJ": time.Saturday, "DZ": time.Saturday,
+	"EG": time.Saturday, "IQ": time.Saturday, "IR": time.Saturday, "JO": time.Saturday, "KW": time.Saturday,
+	"LY": time.Saturday, "OM": time.Saturday, "QA": time.Saturday, "SD": time.Saturday, "SY": time.Saturday,
+	"MV": time.Friday,
+
+	"AG": time.Sunday, "AS": time.Sunday, "BD": time.Sunday, "BR": time.Sunday, "BS": time.Sunday,
+	"BT": time.Sunday, "BW": time.Sunday, "BZ": time.Sunday, "CA": time.Sunday, "CN": time.Sunday,
+	"CO": time.Sunday, "DM": time.Sunday, "DO": time.Sunday, "ET": time.Sunday, "GT": time.Sunday,
+	"GU": time.Sunday, "HK": time.Sunday, "HN": time.Sunday, "ID": time.Sunday, "IL": time.Sunday,
+	"IN": time.Sunday, "JM": time.Sunday, "JP": time.Sunday, "KE": time.Sunday, "KH": time.Sunday,
+	"KR": time.Sunday, "LA": time.Sunday, "MH": time.Sunday, "MM": time.Sunday, "MO": time.Sunday,
+	"MT": time.Sunday, "MX": time.Sunday, "MZ": time.Sunday, "NI": time.Sunday, "NP": time.Sunday,
+	"PA": time.Sunday, "PE": time.Sunday, "PH": time.Sunday, "PK": time.Sunday, "PR": time.Sunday,
+	"PT": time.Sunday, "PY": time.Sunday, "SA": time.Sunday, "SG": time.Sunday, "SV": time.Sunday,
+	"TH": time.Sunday, "TT": time.Sunday, "TW": time.Sunday, "UM": time.Sunday, "US": time.Sunday,
+	"VE": time.Sunday, "VI": time.Sunday, "WS": time.Sunday, "YE": time.Sunday, "ZA": time.Sunday,
+	"ZW": time.Sunday,
+}
+
+// UserSettings — настройки пользователя
+type UserSettings struct {
+	UserID string `json:"user_id"`
+	// WeekStart — первый день недели: monday ... sunday. Если не задан, берется из региона Locale,
+	// а без него — понедельник, как в ISO 8601. В ответах API всегда заполнен.
+	WeekStart string `json:"week_start,omitempty"`
+	Locale    string `json:"locale,omitempty"` // тег BCP 47, например en-US
+}
+
+// parseWeekday разбирает английское название дня недели без учета регистра
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, nil
+		}
+	}
+	return time.Monday, fmt.Errorf("unknown day of week %q, use monday ... sunday", name)
+}
+
+// localeWeekStart возвращает первый день недели в регионе тега locale; без региона — понедельник
+func localeWeekStart(locale string) time.Weekday {
+	subtags := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	for _, subtag := range subtags[min(1, len(subtags)):] {
+		if len(subtag) == 2 {
+			if day, ok := regionWeekStart[strings.ToUpper(subtag)]; ok {
+				return day
+			}
+			break
+		}
+	}
+	return time.Monday
+}
+
+// weekStart возвращает первый день недели пользователя
+func (s UserSettings) weekStart() time.Weekday {
+	if s.WeekStart != "" {
+		if day, err := parseWeekday(s.WeekStart); err == nil {
+			return day
+		}
+	}
+	return localeWeekStart(s.Locale)
+}
+
+// normalizeSettings проверяет настройки и приводит их к каноническому виду
+func normalizeSettings(settings UserSettings) (UserSettings, error) {
+	settings.WeekStart = strings.TrimSpace(settings.WeekStart)
+	if settings.WeekStart != "" {
+		day, err := parseWeekday(settings.WeekStart)
+		if err != nil {
+			return settings, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+		settings.WeekStart = strings.ToLower(day.String())
+	}
+	settings.Locale = strings.ReplaceAll(strings.TrimSpace(settings.Locale), "_", "-")
+	if settings.Locale != "" && !localeTag.MatchString(settings.Locale) {
+		return settings, fmt.Errorf("%w: locale must be a BCP 47 tag such as en-US", ErrInvalidSettings)
+	}
+	return settings, nil
+}
+
+// view возвращает настройки для ответа API: с заполненным первым днем недели
+func (s UserSettings) view() UserSettings {
+	s.WeekStart = strings.ToLower(s.weekStart().String())
+	return s
+}
+
+// Settings возвращает настройки пользователя; если он их не менял — настройки по умолчанию
+func (c *Calendar) Settings(userID string) UserSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	settings, ok := c.settings[userID]
+	if !ok {
+		settings.UserID = userID
+	}
+	return settings.view()
+}
+
+// WeekStart возвращает первый день недели пользователя
+func (c *Calendar) WeekStart(userID string) time.Weekday {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.settings[userID].weekStart()
+}
+
+// UpdateSettings заменяет настройки пользователя settings.UserID
+func (c *Calendar) UpdateSettings(settings UserSettings) (UserSettings, error) {
+	if settings.UserID == "" {
+		return UserSettings{}, errors.New("user ID is required")
+	}
+	settings, err := normalizeSettings(settings)
+	if err != nil {
+		return UserSettings{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.repo != nil {
+		if err := c.repo.SaveSettings(settings); err != nil {
+			return UserSettings{}, fmt.Errorf("failed to store settings: %w", err)
+		}
+	}
+	c.putSettings(settings)
+	return settings.view(), nil
+}
+
+// putSettings сохраняет настройки в памяти. Вызывается под блокировкой записи.
+func (c *Calendar) putSettings(settings UserSettings) {
+	if c.settings == nil {
+		c.settings = make(map[string]UserSettings)
+	}
+	c.settings[settings.UserID] = settings
+}
+
+// requestWeekStart возвращает первый день недели из параметра week_start, а без него — из настроек пользователя
+func requestWeekStart(r *http.Request, calendar *Calendar, userID string) (time.Weekday, error) {
+	if value := r.URL.Query().Get("week_start"); value != "" {
+		return parseWeekday(value)
+	}
+	return calendar.WeekStart(userID), nil
+}
+
+func getSettingsHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Settings", Data: calendar.Settings(userID)})
+	}
+}
+
+func updateSettingsHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		var settings UserSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			writeBodyError(w, err, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		// Пользователь берется из пути, а не из тела
+		settings.UserID = userID
+		updated, err := calendar.UpdateSettings(settings)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Settings updated", Data: updated})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUserSettings(t *testing.T) {
+	tests := []struct {
+		settings UserSettings
+		want     time.Weekday
+		wantErr  bool
+	}{
+		{settings: UserSettings{}, want: time.Monday},
+		{settings: UserSettings{WeekStart: "Sunday"}, want: time.Sunday},
+		{settings: UserSettings{Locale: "en-US"}, want: time.Sunday},
+		{settings: UserSettings{Locale: "ru_RU"}, want: time.Monday},
+		{settings: UserSettings{Locale: "ar-Arab-EG"}, want: time.Saturday},
+		{settings: UserSettings{Locale: "en"}, want: time.Monday},
+		{settings: UserSettings{Locale: "en-US", WeekStart: "monday"}, want: time.Monday},
+		{settings: UserSettings{WeekStart: "someday"}, wantErr: true},
+		{settings: UserSettings{Locale: "en US"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeSettings(tt.settings)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSettings) {
+				t.Errorf("normalizeSettings(%+v): expected ErrInvalidSettings, got %v", tt.settings, err)
+			}
+			continue
+		}
+		if err != nil || got.weekStart() != tt.want {
+			t.Errorf("normalizeSettings(%+v) = %+v, %v; want week from %s", tt.settings, got, err, tt.want)
+		}
+	}
+
+	for kind, open := range openTestRepositories(t) {
+		t.Run(kind, func(t *testing.T) {
+			calendar, _ := NewCalendar(open())
+			if got := calendar.Settings("user1"); got != (UserSettings{UserID: "user1", WeekStart: "monday"}) {
+				t.Errorf("Unexpected default settings: %+v", got)
+			}
+			updated, err := calendar.UpdateSettings(UserSettings{UserID: "user1", Locale: "en_US"})
+			if err != nil || updated.WeekStart != "sunday" || updated.Locale != "en-US" {
+				t.Fatalf("UpdateSettings: %+v, %v", updated, err)
+			}
+			calendar.Close()
+
+			reopened, _ := NewCalendar(open())
+			defer reopened.Close()
+			if reopened.WeekStart("user1") != time.Sunday || reopened.WeekStart("user2") != time.Monday {
+				t.Errorf("Expected settings to survive a restart, got %+v", reopened.Settings("user1"))
+			}
+		})
+	}
+}
@@ -0,0 +1,689 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// Права доступа к календарю
+const (
+	PermissionOwner = "owner"
+	PermissionWrite = "write"
+	PermissionRead  = "read"
+)
+
+// Ответы участников на приглашение (PARTSTAT из RFC 5545 в нижнем регистре)
+const (
+	RSVPNeedsAction = "needs-action"
+	RSVPAccepted    = "accepted"
+	RSVPDeclined    = "declined"
+	RSVPTentative   = "tentative"
+)
+
+const (
+	maxCalendarNameLength = 100
+	maxAttendees          = 100
+	maxShares             = 100
+)
+
+var (
+	// ErrCalendarNotFound — календаря нет или он недоступен пользователю
+	ErrCalendarNotFound = errors.New("calendar not found")
+	// ErrNotAttendee — пользователь не приглашен на событие
+	ErrNotAttendee = errors.New("user is not an attendee of the event")
+	// ErrCrossOwnerMove — событие переносится в календарь другого владельца
+	ErrCrossOwnerMove = errors.New("event can only be moved between calendars of the same owner")
+	// ErrReadOnly — календарем поделились только на чтение
+	ErrReadOnly = errors.New("calendar is shared read-only")
+	// ErrNotCalendarOwner — настройки и доступ к календарю меняет только владелец
+	ErrNotCalendarOwner = errors.New("only the owner can manage the calendar")
+	// ErrInvalidShare — недопустимые параметры доступа к календарю
+	ErrInvalidShare = errors.New("invalid share")
+	// ErrCalendarNotShared — календарь не открыт указанному пользователю
+	ErrCalendarNotShared = errors.New("calendar is not shared with this user")
+)
+
+// calendarColor — цвет календаря в формате #RRGGBB
+var calendarColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// UserCalendar — именованный календарь пользователя. События без calendar_id лежат в календаре
+// пользователя по умолчанию, который нельзя открыть другим; остальными календарями можно поделиться.
+type UserCalendar struct {
+	ID      int     `json:"id"`
+	OwnerID string  `json:"owner_id"`
+	Name    string  `json:"name"`
+	Color   string  `json:"color,omitempty"`
+	Shares  []Share `json:"shares,omitempty"` // видны только владельцу
+	// Permission — права пользователя, запросившего календарь; не хранится
+	Permission string `json:"permission,omitempty"`
+}
+
+// Share — доступ другого пользователя к календарю
+type Share struct {
+	UserID     string `json:"user_id"`
+	Permission string `json:"permission"` // read или write
+}
+
+// Attendee — участник события
+type Attendee struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status"` // needs-action, accepted, declined или tentative
+}
+
+// validRSVP сообщает, является ли status допустимым ответом участника
+func validRSVP(status string) bool {
+	switch status {
+	case RSVPNeedsAction, RSVPAccepted, RSVPDeclined, RSVPTentative:
+		return true
+	}
+	return false
+}
+
+// normalizeAttendees проверяет участников и упорядочивает их по user_id; пустой статус — needs-action
+func normalizeAttendees(attendees []Attendee) ([]Attendee, error) {
+	if len(attendees) > maxAttendees {
+		return nil, fmt.Errorf("at most %d attendees are allowed", maxAttendees)
+	}
+	if len(attendees) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(attendees))
+	result := make([]Attendee, 0, len(attendees))
+	for _, attendee := range attendees {
+		attendee.UserID = strings.TrimSpace(attendee.UserID)
+		if attendee.UserID == "" {
+			return nil, errors.New("attendee user_id is required")
+		}
+		if seen[attendee.UserID] {
+			return nil, fmt.Errorf("duplicate attendee %q", attendee.UserID)
+		}
+		seen[attendee.UserID] = true
+		if attendee.Status == "" {
+			attendee.Status = RSVPNeedsAction
+		}
+		if !validRSVP(attendee.Status) {
+			return nil, fmt.Errorf("invalid attendee status %q", attendee.Status)
+		}
+		result = append(result, attendee)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result, nil
+}
+
+// keepResponses переносит ответы участников из previous: отвечать за участника может только он сам,
+// поэтому новые участники получают needs-action, а остальные сохраняют свой ответ
+func keepResponses(attendees, previous []Attendee) {
+	for i := range attendees {
+		attendees[i].Status = RSVPNeedsAction
+		for _, old := range previous {
+			if old.UserID == attendees[i].UserID {
+				attendees[i].Status = old.Status
+				break
+			}
+		}
+	}
+}
+
+// attendeeStatus возвращает ответ userID на приглашение или "", если он не участник
+func (e *Event) attendeeStatus(userID string) string {
+	for _, attendee := range e.Attendees {
+		if attendee.UserID == userID {
+			return attendee.Status
+		}
+	}
+	return ""
+}
+
+// ValidateCalendar проверяет имя и цвет календаря
+func ValidateCalendar(cal UserCalendar) error {
+	name := strings.TrimSpace(cal.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxCalendarNameLength {
+		return fmt.Errorf("name must be at most %d characters long", maxCalendarNameLength)
+	}
+	if cal.Color != "" && !calendarColor.MatchString(cal.Color) {
+		return errors.New("color must be in format #RRGGBB")
+	}
+	return nil
+}
+
+// permission возвращает права userID на календарь cal или "", если доступа нет
+func (cal *UserCalendar) permission(userID string) string {
+	if cal.OwnerID == userID {
+		return PermissionOwner
+	}
+	for _, share := range cal.Shares {
+		if share.UserID == userID {
+			return share.Permission
+		}
+	}
+	return ""
+}
+
+// view возвращает копию календаря такой, какой ее видит userID
+func (cal *UserCalendar) view(userID string) UserCalendar {
+	v := *cal
+	v.Permission = cal.permission(userID)
+	if v.Permission == PermissionOwner {
+		v.Shares = append([]Share(nil), cal.Shares...)
+	} else {
+		v.Shares = nil
+	}
+	return v
+}
+
+// canRead сообщает, видно ли событие пользователю: владельцу, участнику и тем, с кем поделились календарем.
+// Вызывается под блокировкой.
+func (c *Calendar) canRead(userID string, event *Event) bool {
+	if event.UserID == userID || event.attendeeStatus(userID) != "" {
+		return true
+	}
+	cal := c.calendars[event.CalendarID]
+	return event.CalendarID != 0 && cal != nil && cal.permission(userID) != ""
+}
+
+// canWrite сообщает, может ли пользователь менять и удалять событие. Вызывается под блокировкой.
+func (c *Calendar) canWrite(userID string, event *Event) bool {
+	if event.UserID == userID {
+		return true
+	}
+	cal := c.calendars[event.CalendarID]
+	return event.CalendarID != 0 && cal != nil && cal.permission(userID) == PermissionWrite
+}
+
+// checkWrite проверяет, что userID может менять и удалять событие. Вызывается под блокировкой.
+func (c *Calendar) checkWrite(userID string, event *Event) error {
+	switch {
+	case c.canWrite(userID, event):
+		return nil
+	case c.canRead(userID, event):
+		return ErrReadOnly
+	}
+	return ErrForbidden
+}
+
+// calendarOwner возвращает владельца календаря calendarID, в который userID сохраняет событие.
+// Календарь 0 — календарь пользователя по умолчанию. Вызывается под блокировкой.
+func (c *Calendar) calendarOwner(userID string, calendarID int) (string, error) {
+	if calendarID == 0 {
+		return userID, nil
+	}
+	cal := c.calendars[calendarID]
+	if cal == nil {
+		return "", ErrCalendarNotFound
+	}
+	switch cal.permission(userID) {
+	case PermissionOwner, PermissionWrite:
+		return cal.OwnerID, nil
+	case PermissionRead:
+		return "", ErrReadOnly
+	}
+	return "", ErrCalendarNotFound
+}
+
+// attendeePut добавляет событие в списки приглашений участников. Вызывается под блокировкой записи.
+func (c *Calendar) attendeePut(event *Event) {
+	for _, attendee := range event.Attendees {
+		if c.attending == nil {
+			c.attending = make(map[string]map[int]*Event)
+		}
+		if c.attending[attendee.UserID] == nil {
+			c.attending[attendee.UserID] = make(map[int]*Event)
+		}
+		c.attending[attendee.UserID][event.ID] = event
+	}
+}
+
+// attendeeRemove убирает событие из списков приглашений участников
+func (c *Calendar) attendeeRemove(event *Event) {
+	for _, attendee := range event.Attendees {
+		delete(c.attending[attendee.UserID], event.ID)
+		if len(c.attending[attendee.UserID]) == 0 {
+			delete(c.attending, attendee.UserID)
+		}
+	}
+}
+
+// sharedEventsInRange возвращает чужие события, видимые пользователю в [from, to): события календарей,
+// которыми с ним поделились, и события, на которые он приглашен и не отказался. Вызывается под блокировкой.
+func (c *Calendar) sharedEventsInRange(userID string, from, to time.Time) []Event {
+	var events []Event
+	seen := make(map[int]bool)
+	for _, cal := range c.calendars {
+		if perm := cal.permission(userID); perm == "" || perm == PermissionOwner {
+			continue
+		}
+		for _, event := range c.eventsInRange(cal.OwnerID, from, to) {
+			if event.CalendarID == cal.ID {
+				events = append(events, event)
+				seen[event.ID] = true
+			}
+		}
+	}
+	for id, event := range c.attending[userID] {
+		if event.UserID == userID || seen[id] || event.attendeeStatus(userID) == RSVPDeclined {
+			continue
+		}
+		if event.IsRecurring() {
+			events = append(events, event.occurrencesInRange(from, to)...)
+		} else if event.overlaps(from, to) {
+			events = append(events, *event)
+		}
+	}
+	return events
+}
+
+// CreateCalendar создает календарь владельца cal.OwnerID и возвращает его
+func (c *Calendar) CreateCalendar(cal UserCalendar) (UserCalendar, error) {
+	if cal.OwnerID == "" {
+		return UserCalendar{}, errors.New("user ID is required")
+	}
+	if err := ValidateCalendar(cal); err != nil {
+		return UserCalendar{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Нулевой ID зарезервирован за календарем по умолчанию
+	stored := UserCalendar{ID: max(c.nextCalendarID, 1), OwnerID: cal.OwnerID, Name: strings.TrimSpace(cal.Name), Color: cal.Color}
+	if c.repo != nil {
+		if err := c.repo.SaveCalendar(stored); err != nil {
+			return UserCalendar{}, fmt.Errorf("failed to store calendar: %w", err)
+		}
+	}
+	c.putCalendar(&stored)
+	return stored.view(cal.OwnerID), nil
+}
+
+// putCalendar сохраняет календарь в памяти. Вызывается под блокировкой записи.
+func (c *Calendar) putCalendar(cal *UserCalendar) {
+	if c.calendars == nil {
+		c.calendars = make(map[int]*UserCalendar)
+	}
+	c.calendars[cal.ID] = cal
+	if cal.ID >= c.nextCalendarID {
+		c.nextCalendarID = cal.ID + 1
+	}
+}
+
+// GetCalendar возвращает календарь, доступный пользователю
+func (c *Calendar) GetCalendar(userID string, id int) (UserCalendar, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cal := c.calendars[id]
+	if cal == nil || cal.permission(userID) == "" {
+		return UserCalendar{}, ErrCalendarNotFound
+	}
+	return cal.view(userID), nil
+}
+
+// UserCalendars возвращает календари пользователя и календари, которыми с ним поделились, по возрастанию ID
+func (c *Calendar) UserCalendars(userID string) []UserCalendar {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]UserCalendar, 0)
+	for _, cal := range c.calendars {
+		if cal.permission(userID) != "" {
+			result = append(result, cal.view(userID))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// ownCalendar возвращает календарь, которым владеет userID. Вызывается под блокировкой.
+func (c *Calendar) ownCalendar(userID string, id int) (*UserCalendar, error) {
+	cal := c.calendars[id]
+	if cal == nil || cal.permission(userID) == "" {
+		return nil, ErrCalendarNotFound
+	}
+	if cal.OwnerID != userID {
+		return nil, ErrNotCalendarOwner
+	}
+	return cal, nil
+}
+
+// changeCalendar применяет change к копии календаря владельца userID и сохраняет результат
+func (c *Calendar) changeCalendar(userID string, id int, change func(cal *UserCalendar) error) (UserCalendar, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing, err := c.ownCalendar(userID, id)
+	if err != nil {
+		return UserCalendar{}, err
+	}
+	cal := *existing
+	cal.Shares = append([]Share(nil), existing.Shares...)
+	if err := change(&cal); err != nil {
+		return UserCalendar{}, err
+	}
+	if c.repo != nil {
+		if err := c.repo.SaveCalendar(cal); err != nil {
+			return UserCalendar{}, fmt.Errorf("failed to store calendar: %w", err)
+		}
+	}
+	c.putCalendar(&cal)
+	// Пользователи, получившие или потерявшие доступ, узнают об этом из ленты изменений
+	for _, share := range cal.Shares {
+		if existing.permission(share.UserID) == "" {
+			c.publishAccess(&cal, share.UserID, ChangeCreated)
+		}
+	}
+	for _, share := range existing.Shares {
+		if cal.permission(share.UserID) == "" {
+			c.publishAccess(&cal, share.UserID, ChangeDeleted)
+		}
+	}
+	return cal.view(userID), nil
+}
+
+// UpdateCalendar меняет имя и цвет календаря; доступно только владельцу
+func (c *Calendar) UpdateCalendar(userID string, id int, name, color string) (UserCalendar, error) {
+	if err := ValidateCalendar(UserCalendar{Name: name, Color: color}); err != nil {
+		return UserCalendar{}, err
+	}
+	return c.changeCalendar(userID, id, func(cal *UserCalendar) error {
+		cal.Name, cal.Color = strings.TrimSpace(name), color
+		return nil
+	})
+}
+
+// ShareCalendar открывает календарь пользователю grantee с правами read или write; повторный вызов меняет права
+func (c *Calendar) ShareCalendar(userID string, id int, grantee, permission string) (UserCalendar, error) {
+	if permission != PermissionRead && permission != PermissionWrite {
+		return UserCalendar{}, fmt.Errorf("%w: permission must be read or write", ErrInvalidShare)
+	}
+	if grantee == "" || grantee == userID {
+		return UserCalendar{}, fmt.Errorf("%w: calendar can only be shared with another user", ErrInvalidShare)
+	}
+	return c.changeCalendar(userID, id, func(cal *UserCalendar) error {
+		for i := range cal.Shares {
+			if cal.Shares[i].UserID == grantee {
+				cal.Shares[i].Permission = permission
+				return nil
+			}
+		}
+		if len(cal.Shares) >= maxShares {
+			return fmt.Errorf("%w: calendar can be shared with at most %d users", ErrInvalidShare, maxShares)
+		}
+		cal.Shares = append(cal.Shares, Share{UserID: grantee, Permission: permission})
+		sort.Slice(cal.Shares, func(i, j int) bool { return cal.Shares[i].UserID < cal.Shares[j].UserID })
+		return nil
+	})
+}
+
+// UnshareCalendar закрывает доступ пользователя grantee к календарю
+func (c *Calendar) UnshareCalendar(userID string, id int, grantee string) (UserCalendar, error) {
+	return c.changeCalendar(userID, id, func(cal *UserCalendar) error {
+		for i := range cal.Shares {
+			if cal.Shares[i].UserID == grantee {
+				cal.Shares = append(cal.Shares[:i], cal.Shares[i+1:]...)
+				return nil
+			}
+		}
+		return ErrCalendarNotShared
+	})
+}
+
+// DeleteCalendar удаляет календарь вместе с его событиями; доступно только владельцу
+func (c *Calendar) DeleteCalendar(userID string, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cal, err := c.ownCalendar(userID, id)
+	if err != nil {
+		return err
+	}
+
+	// Сначала удаляем события: если удаление прервется, календарь останется и его можно удалить повторно.
+	// В корзину они не попадают — восстанавливать их некуда.
+	for _, event := range c.trash {
+		if event.UserID != cal.OwnerID || event.CalendarID != id {
+			continue
+		}
+		if c.repo != nil {
+			if err := c.repo.DeleteEvent(event.ID); err != nil {
+				return fmt.Errorf("failed to delete stored event: %w", err)
+			}
+		}
+		c.trashRemove(event)
+	}
+	for _, event := range c.userEvents(cal.OwnerID) {
+		if event.CalendarID != id {
+			continue
+		}
+		if c.repo != nil {
+			if err := c.repo.DeleteEvent(event.ID); err != nil {
+				return fmt.Errorf("failed to delete stored event: %w", err)
+			}
+		}
+		delete(c.events, event.ID)
+		c.indexPut(event, nil)
+		c.publishChange(event, nil)
+	}
+	if c.repo != nil {
+		if err := c.repo.DeleteCalendar(id); err != nil {
+			return fmt.Errorf("failed to delete stored calendar: %w", err)
+		}
+	}
+	delete(c.calendars, id)
+	return nil
+}
+
+// RespondToEvent сохраняет ответ участника userID на приглашение
+func (c *Calendar) RespondToEvent(userID string, id int, status string) (Event, error) {
+	if !validRSVP(status) {
+		return Event{}, fmt.Errorf("invalid status %q", status)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing, exists := c.events[id]
+	if !exists || !c.canRead(userID, existing) {
+		return Event{}, ErrEventNotFound
+	}
+	if existing.attendeeStatus(userID) == "" {
+		return Event{}, ErrNotAttendee
+	}
+
+	event := *existing
+	event.Version++
+	event.Attendees = append([]Attendee(nil), existing.Attendees...)
+	for i := range event.Attendees {
+		if event.Attendees[i].UserID == userID {
+			event.Attendees[i].Status = status
+		}
+	}
+	if c.repo != nil {
+		if err := c.repo.UpdateEvent(event); err != nil {
+			return Event{}, fmt.Errorf("failed to store event: %w", err)
+		}
+	}
+	c.events[id] = &event
+	c.indexPut(existing, &event)
+	c.publishChange(existing, &event)
+	return event, nil
+}
+
+// apiCalendarID возвращает ID календаря из пути
+func apiCalendarID(r *http.Request) int {
+	id, _ := strconv.Atoi(mux.Vars(r)["cid"])
+	return id
+}
+
+func calendarLocation(userID string, id int) string {
+	return fmt.Sprintf("%s/users/%s/calendars/%d", apiPrefix, userID, id)
+}
+
+// calendarBody — изменяемые поля календаря в теле запроса
+type calendarBody struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func decodeCalendarBody(w http.ResponseWriter, r *http.Request) (calendarBody, bool) {
+	var body calendarBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeBodyError(w, err, http.StatusBadRequest, "Invalid JSON format")
+		return body, false
+	}
+	if err := ValidateCalendar(UserCalendar{Name: body.Name, Color: body.Color}); err != nil {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, err.Error())
+		return body, false
+	}
+	return body, true
+}
+
+// listCalendarsHandler возвращает календари пользователя и календари, которыми с ним поделились
+func listCalendarsHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Calendars", Data: calendar.UserCalendars(userID)})
+	}
+}
+
+func createCalendarHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		body, ok := decodeCalendarBody(w, r)
+		if !ok {
+			return
+		}
+		created, err := calendar.CreateCalendar(UserCalendar{OwnerID: userID, Name: body.Name, Color: body.Color})
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.Header().Set("Location", calendarLocation(userID, created.ID))
+		writeResponse(w, http.StatusCreated, Response{Message: "Calendar created", Data: created})
+	}
+}
+
+func getCalendarHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		cal, err := calendar.GetCalendar(userID, apiCalendarID(r))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Calendar", Data: cal})
+	}
+}
+
+func updateCalendarHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		body, ok := decodeCalendarBody(w, r)
+		if !ok {
+			return
+		}
+		cal, err := calendar.UpdateCalendar(userID, apiCalendarID(r), body.Name, body.Color)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Calendar updated", Data: cal})
+	}
+}
+
+func deleteCalendarHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		if err := calendar.DeleteCalendar(userID, apiCalendarID(r)); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// shareCalendarHandler обрабатывает PUT .../calendars/{cid}/shares/{grantee} с телом {"permission": "read"}
+func shareCalendarHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		var body struct {
+			Permission string `json:"permission"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeBodyError(w, err, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		cal, err := calendar.ShareCalendar(userID, apiCalendarID(r), mux.Vars(r)["grantee"], body.Permission)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Calendar shared", Data: cal})
+	}
+}
+
+func unshareCalendarHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		cal, err := calendar.UnshareCalendar(userID, apiCalendarID(r), mux.Vars(r)["grantee"])
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Calendar unshared", Data: cal})
+	}
+}
+
+// rsvpHandler обрабатывает PUT .../events/{id}/rsvp с телом {"status": "accepted"}
+func rsvpHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		var body struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeBodyError(w, err, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		if !validRSVP(body.Status) {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "status must be needs-action, accepted, declined or tentative")
+			return
+		}
+		event, err := calendar.RespondToEvent(userID, apiEventID(r), body.Status)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Message: "Response saved", Data: event})
+	}
+}
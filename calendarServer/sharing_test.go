@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"WBTechL2/calendarServer/client"
+)
+
+func TestSharedCalendar(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	work, err := calendar.CreateCalendar(UserCalendar{OwnerID: "user1", Name: "Work", Color: "#3366ff"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Private", Date: "2024-03-10"})
+	sharedID, err := calendar.CreateEvent(Event{UserID: "user1", CalendarID: work.ID, Title: "Standup", Date: "2024-03-10"})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// Пока календарь не открыт, его события другим не видны
+	if _, err := calendar.CreateEvent(Event{UserID: "user2", CalendarID: work.ID, Title: "Intrusion", Date: "2024-03-10"}); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("Expected ErrCalendarNotFound, got %v", err)
+	}
+	if _, err := calendar.ShareCalendar("user1", work.ID, "user2", PermissionRead); err != nil {
+		t.Fatalf("Failed to share calendar: %v", err)
+	}
+	events, _ := calendar.GetEventsForDay("2024-03-10", "user2")
+	if got := orderedTitles(events); !equalStrings(got, []string{"Standup"}) {
+		t.Errorf("Expected only the shared event, got %v", got)
+	}
+	if _, err := calendar.GetEvent("user2", sharedID); err != nil {
+		t.Errorf("Expected the shared event to be visible, got %v", err)
+	}
+
+	// Чтение не дает права изменять
+	err = calendar.UpdateEvent(Event{ID: sharedID, UserID: "user2", Title: "Changed", Date: "2024-03-10"})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := calendar.DeleteUserEvent("user2", sharedID); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly on delete, got %v", err)
+	}
+
+	// С правом записи событие меняется, но остается у владельца
+	calendar.ShareCalendar("user1", work.ID, "user2", PermissionWrite)
+	if err := calendar.UpdateEvent(Event{ID: sharedID, UserID: "user2", Title: "Changed", Date: "2024-03-10"}); err != nil {
+		t.Fatalf("Failed to update shared event: %v", err)
+	}
+	updated, _ := calendar.GetEvent("user1", sharedID)
+	if updated.UserID != "user1" || updated.CalendarID != work.ID || updated.Title != "Changed" {
+		t.Errorf("Unexpected event after update: %+v", updated)
+	}
+	createdID, err := calendar.CreateEvent(Event{UserID: "user2", CalendarID: work.ID, Title: "Retro", Date: "2024-03-11"})
+	if err != nil {
+		t.Fatalf("Failed to create event in shared calendar: %v", err)
+	}
+	if created, _ := calendar.GetEvent("user1", createdID); created.UserID != "user1" {
+		t.Errorf("Expected event to belong to the calendar owner, got %q", created.UserID)
+	}
+
+	// Переносить события между владельцами нельзя
+	own, _ := calendar.CreateCalendar(UserCalendar{OwnerID: "user2", Name: "Own"})
+	err = calendar.UpdateEvent(Event{ID: sharedID, UserID: "user2", CalendarID: own.ID, Title: "Changed", Date: "2024-03-10"})
+	if !errors.Is(err, ErrCrossOwnerMove) {
+		t.Errorf("Expected ErrCrossOwnerMove, got %v", err)
+	}
+
+	// Настройками календаря управляет только владелец
+	if _, err := calendar.ShareCalendar("user2", work.ID, "user3", PermissionRead); !errors.Is(err, ErrNotCalendarOwner) {
+		t.Errorf("Expected ErrNotCalendarOwner, got %v", err)
+	}
+	if got := calendar.UserCalendars("user2"); len(got) != 2 || got[0].Permission != PermissionWrite || got[0].Shares != nil || got[1].Permission != PermissionOwner {
+		t.Errorf("Unexpected calendars of user2: %+v", got)
+	}
+
+	if _, err := calendar.UnshareCalendar("user1", work.ID, "user2"); err != nil {
+		t.Fatalf("Failed to unshare calendar: %v", err)
+	}
+	if events, _ := calendar.GetEventsForWeek("2024-03-10", "user2"); len(events) != 0 {
+		t.Errorf("Expected no events after unsharing, got %v", orderedTitles(events))
+	}
+	if _, err := calendar.GetEvent("user2", sharedID); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound after unsharing, got %v", err)
+	}
+
+	// Удаление календаря удаляет его события
+	if err := calendar.DeleteCalendar("user1", work.ID); err != nil {
+		t.Fatalf("Failed to delete calendar: %v", err)
+	}
+	events, _ = calendar.GetEventsForWeek("2024-03-10", "user1")
+	if got := orderedTitles(events); !equalStrings(got, []string{"Private"}) {
+		t.Errorf("Expected only the default calendar to remain, got %v", got)
+	}
+}
+
+func TestAttendees(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 11, 10, 0, 0, 0, time.UTC)
+	id, err := calendar.CreateEvent(Event{
+		UserID: "user1", Title: "Planning", Start: start, End: start.Add(time.Hour),
+		RRule:     "FREQ=DAILY;COUNT=3",
+		Attendees: []Attendee{{UserID: "user3", Status: RSVPAccepted}, {UserID: "user2"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// Ответить за участника может только он сам
+	event, _ := calendar.GetEvent("user1", id)
+	want := []Attendee{{UserID: "user2", Status: RSVPNeedsAction}, {UserID: "user3", Status: RSVPNeedsAction}}
+	if len(event.Attendees) != 2 || event.Attendees[0] != want[0] || event.Attendees[1] != want[1] {
+		t.Errorf("Expected attendees %v, got %v", want, event.Attendees)
+	}
+
+	events, _ := calendar.GetEventsForWeek("2024-03-11", "user2")
+	if len(events) != 3 || events[0].RecurrenceID == "" {
+		t.Errorf("Expected 3 expanded instances for the attendee, got %d", len(events))
+	}
+	if _, err := calendar.RespondToEvent("user2", id, RSVPAccepted); err != nil {
+		t.Fatalf("Failed to respond: %v", err)
+	}
+	if _, err := calendar.RespondToEvent("user3", id, RSVPDeclined); err != nil {
+		t.Fatalf("Failed to respond: %v", err)
+	}
+	if events, _ := calendar.GetEventsForWeek("2024-03-11", "user3"); len(events) != 0 {
+		t.Errorf("Expected declined event to be hidden, got %d events", len(events))
+	}
+	if _, err := calendar.RespondToEvent("user1", id, RSVPAccepted); !errors.Is(err, ErrNotAttendee) {
+		t.Errorf("Expected ErrNotAttendee for the organizer, got %v", err)
+	}
+	if _, err := calendar.RespondToEvent("user4", id, RSVPAccepted); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound for a stranger, got %v", err)
+	}
+
+	// Организатор меняет событие и список участников, ответы оставшихся сохраняются
+	err = calendar.UpdateEvent(Event{
+		ID: id, UserID: "user1", Title: "Planning", Start: start.Add(time.Hour),
+		Attendees: []Attendee{{UserID: "user2", Status: RSVPDeclined}, {UserID: "user4"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	event, _ = calendar.GetEvent("user4", id)
+	want = []Attendee{{UserID: "user2", Status: RSVPAccepted}, {UserID: "user4", Status: RSVPNeedsAction}}
+	if len(event.Attendees) != 2 || event.Attendees[0] != want[0] || event.Attendees[1] != want[1] {
+		t.Errorf("Expected attendees %v, got %v", want, event.Attendees)
+	}
+	if _, err := calendar.GetEvent("user3", id); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected removed attendee to lose access, got %v", err)
+	}
+	// Участник видит событие, но изменить его не может
+	if err := calendar.DeleteUserEvent("user2", id); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly for an attendee, got %v", err)
+	}
+
+	_, err = calendar.CreateEvent(Event{UserID: "user1", Title: "x", Date: "2024-03-11", Attendees: []Attendee{{UserID: "user2"}, {UserID: "user2"}}})
+	if err == nil || err.Error() != `duplicate attendee "user2"` {
+		t.Errorf("Expected duplicate attendee error, got %v", err)
+	}
+}
+
+func TestCalendarsAPI(t *testing.T) {
+	ctx := context.Background()
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(map[string]string{"user1-key": "user1", "user2-key": "user2"}, nil), nil)
+	validateResponses(t, router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	owner := client.New(server.URL, "user1-key", server.Client())
+	guest := client.New(server.URL, "user2-key", server.Client())
+
+	work, err := owner.CreateCalendar(ctx, "user1", client.Calendar{Name: "Work", Color: "#3366ff"})
+	if err != nil || work.ID == 0 || work.Permission != PermissionOwner {
+		t.Fatalf("CreateCalendar: %+v, %v", work, err)
+	}
+	event, err := owner.CreateEvent(ctx, "user1", client.Event{
+		Title: "Standup", Date: "2024-03-10", CalendarID: work.ID,
+		Attendees: []client.Attendee{{UserID: "user3"}},
+	}, false)
+	if err != nil || event.CalendarID != work.ID || event.Attendees[0].Status != RSVPNeedsAction {
+		t.Fatalf("CreateEvent: %+v, %v", event, err)
+	}
+
+	var apiErr *client.APIError
+	if _, err := guest.GetCalendar(ctx, "user2", work.ID); !client.IsNotFound(err) {
+		t.Errorf("Expected 404 before sharing, got %v", err)
+	}
+	shared, err := owner.ShareCalendar(ctx, "user1", work.ID, "user2", "read")
+	if err != nil || len(shared.Shares) != 1 {
+		t.Fatalf("ShareCalendar: %+v, %v", shared, err)
+	}
+	page, err := guest.ListEvents(ctx, "user2", client.ListOptions{From: "2024-03-10", To: "2024-03-10"})
+	if err != nil || len(page.Events) != 1 || page.Events[0].UserID != "user1" {
+		t.Errorf("Expected the shared event in the guest's range, got %+v, %v", page, err)
+	}
+	_, err = guest.PatchEvent(ctx, "user2", event.ID, map[string]any{"title": "Changed"}, 0, false)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a read-only share, got %v", err)
+	}
+
+	owner.ShareCalendar(ctx, "user1", work.ID, "user2", "write")
+	patched, err := guest.PatchEvent(ctx, "user2", event.ID, map[string]any{"title": "Changed"}, 0, false)
+	if err != nil || patched.Title != "Changed" || patched.UserID != "user1" {
+		t.Errorf("PatchEvent with write access: %+v, %v", patched, err)
+	}
+	calendars, err := guest.ListCalendars(ctx, "user2")
+	if err != nil || len(calendars) != 1 || calendars[0].Permission != PermissionWrite || calendars[0].Shares != nil {
+		t.Errorf("ListCalendars: %+v, %v", calendars, err)
+	}
+	if _, err := guest.UpdateCalendar(ctx, "user2", work.ID, client.Calendar{Name: "Mine"}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 when a guest renames the calendar, got %v", err)
+	}
+	if _, err := owner.ShareCalendar(ctx, "user1", work.ID, "user1", "read"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 when sharing with oneself, got %v", err)
+	}
+	renamed, err := owner.UpdateCalendar(ctx, "user1", work.ID, client.Calendar{Name: "Team", Color: "#000000"})
+	if err != nil || renamed.Name != "Team" {
+		t.Errorf("UpdateCalendar: %+v, %v", renamed, err)
+	}
+	if _, err := owner.UnshareCalendar(ctx, "user1", work.ID, "user2"); err != nil {
+		t.Errorf("UnshareCalendar: %v", err)
+	}
+	if _, err := owner.UnshareCalendar(ctx, "user1", work.ID, "user2"); !client.IsNotFound(err) {
+		t.Errorf("Expected 404 for a missing share, got %v", err)
+	}
+
+	// Приглашенный пользователь отвечает на приглашение
+	invited, err := guest.CreateEvent(ctx, "user2", client.Event{Title: "Lunch", Date: "2024-03-12", Attendees: []client.Attendee{{UserID: "user1"}}}, false)
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	answered, err := owner.RespondToEvent(ctx, "user1", invited.ID, "tentative")
+	if err != nil || answered.Attendees[0].Status != RSVPTentative {
+		t.Errorf("RespondToEvent: %+v, %v", answered, err)
+	}
+	if _, err := guest.RespondToEvent(ctx, "user2", invited.ID, "accepted"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for the organizer, got %v", err)
+	}
+	if _, err := owner.RespondToEvent(ctx, "user1", invited.ID, "maybe"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an unknown status, got %v", err)
+	}
+
+	if err := owner.DeleteCalendar(ctx, "user1", work.ID); err != nil {
+		t.Errorf("DeleteCalendar: %v", err)
+	}
+	if _, err := owner.GetEvent(ctx, "user1", event.ID); !client.IsNotFound(err) {
+		t.Errorf("Expected events of the deleted calendar to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Repository — постоянное хранилище событий.
+// Calendar держит все события в памяти и синхронно дублирует в хранилище каждое изменение,
+// поэтому методы хранилища повторяют изменяющие операции Calendar.
+type Repository interface {
+	// Load возвращает все сохраненные события и следующий свободный ID
+	Load() ([]Event, int, error)
+	// CreateEvent сохраняет новое событие вместе со следующим свободным ID
+	CreateEvent(event Event, nextID int) error
+	UpdateEvent(event Event) error
+	DeleteEvent(id int) error
+	// SaveEvents атомарно создает или заменяет события вместе со следующим свободным ID:
+	// после сбоя сохранены либо все события, либо ни одного
+	SaveEvents(events []Event, nextID int) error
+	// LoadCalendars возвращает все сохраненные календари пользователей
+	LoadCalendars() ([]UserCalendar, error)
+	// SaveCalendar создает или заменяет календарь
+	SaveCalendar(cal UserCalendar) error
+	DeleteCalendar(id int) error
+	// LoadSettings возвращает сохраненные настройки всех пользователей
+	LoadSettings() ([]UserSettings, error)
+	// SaveSettings создает или заменяет настройки пользователя settings.UserID
+	SaveSettings(settings UserSettings) error
+	// Ping проверяет, что хранилище открыто и доступно (для /readyz)
+	Ping() error
+	Close() error
+}
+
+// Типы хранилищ, которые можно выбрать через -storage или STORAGE
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+	StorageSQLite = "sqlite"
+)
+
+// OpenRepository открывает хранилище указанного типа.
+// Для StorageMemory возвращает nil: события живут только в памяти процесса.
+func OpenRepository(kind, path string) (Repository, error) {
+	switch kind {
+	case "", StorageMemory:
+		return nil, nil
+	case StorageFile:
+		if path == "" {
+			path = "calendar.log"
+		}
+		return OpenFileRepository(path)
+	case StorageSQLite:
+		if path == "" {
+			path = "calendar.db"
+		}
+		return OpenSQLRepository(path)
+	default:
+		return nil, fmt.Errorf("unknown storage %q (expected %s, %s or %s)", kind, StorageMemory, StorageFile, StorageSQLite)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Операции журнала файлового хранилища
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+	opNextID = "next_id"
+	opSave   = "save" // несколько событий одной записью, чтобы они сохранились атомарно
+
+	opSaveCalendar   = "save_calendar"
+	opDeleteCalendar = "delete_calendar"
+	opSaveSettings   = "save_settings"
+)
+
+// logRecord — одна запись журнала
+type logRecord struct {
+	Op     string  `json:"op"`
+	Event  *Event  `json:"event,omitempty"`
+	Events []Event `json:"events,omitempty"`
+	ID     int     `json:"id,omitempty"`
+	NextID int     `json:"next_id,omitempty"`
+
+	Calendar *UserCalendar `json:"calendar,omitempty"`
+	Settings *UserSettings `json:"settings,omitempty"`
+}
+
+// FileRepository хранит события в журнале (append-only log) в формате JSON Lines.
+// Каждая запись сбрасывается на диск через fsync до того, как изменение попадет в Calendar.
+// При открытии журнал проигрывается, а если мусорных записей стало слишком много — сжимается.
+// Копии событий в памяти не держатся: они уже есть в Calendar, а Load* заново читают журнал.
+type FileRepository struct {
+	path string
+	file *os.File
+	size int64 // длина журнала по конец последней целой записи
+	mu   sync.Mutex
+}
+
+// logState — состояние, восстановленное проигрыванием журнала
+type logState struct {
+	events    map[int]Event
+	nextID    int
+	calendars map[int]UserCalendar
+	settings  map[string]UserSettings
+}
+
+// OpenFileRepository открывает (или создает) журнал событий
+func OpenFileRepository(path string) (*FileRepository, error) {
+	r := &FileRepository{path: path}
+
+	state, records, err := r.replay(true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Сжимаем журнал, если в нем заметно больше записей, чем живых событий, календарей и настроек
+	if records > 2*(len(state.events)+len(state.calendars)+len(state.settings))+100 {
+		if err := r.compact(state); err != nil {
+			return nil, err
+		}
+	}
+
+	r.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage log: %w", err)
+	}
+	info, err := r.file.Stat()
+	if err != nil {
+		r.file.Close()
+		return nil, fmt.Errorf("failed to open storage log: %w", err)
+	}
+	r.size = info.Size()
+	return r, nil
+}
+
+// replay читает журнал и восстанавливает состояние, возвращает его и количество записей.
+// Недописанная последняя строка (обрыв при записи) отбрасывается, а если truncate — файл обрезается по ней.
+func (r *FileRepository) replay(truncate bool) (*logState, int, error) {
+	state := &logState{
+		events:    make(map[int]Event),
+		nextID:    1,
+		calendars: make(map[int]UserCalendar),
+		settings:  make(map[string]UserSettings),
+	}
+	flags := os.O_RDONLY
+	if truncate {
+		flags = os.O_RDWR
+	}
+	f, err := os.OpenFile(r.path, flags, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open storage log: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	records := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if truncate && len(bytes.TrimSpace(line)) > 0 {
+				// Хвост без перевода строки — запись не была дописана до конца
+				if err := f.Truncate(offset); err != nil {
+					return nil, 0, fmt.Errorf("failed to truncate storage log: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read storage log: %w", err)
+		}
+
+		var rec logRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, 0, fmt.Errorf("corrupted storage log at offset %d: %w", offset, err)
+		}
+		state.apply(rec)
+		offset += int64(len(line))
+		records++
+	}
+	return state, records, nil
+}
+
+// load проигрывает журнал для Load*. Вызывается под r.mu, поэтому видит только целые записи.
+func (r *FileRepository) load() (*logState, error) {
+	state, _, err := r.replay(false)
+	return state, err
+}
+
+// apply применяет запись журнала к состоянию
+func (s *logState) apply(rec logRecord) {
+	switch rec.Op {
+	case opCreate, opUpdate:
+		if rec.Event != nil {
+			s.events[rec.Event.ID] = *rec.Event
+		}
+	case opSave:
+		for _, event := range rec.Events {
+			s.events[event.ID] = event
+		}
+	case opDelete:
+		delete(s.events, rec.ID)
+	case opSaveCalendar:
+		if rec.Calendar != nil {
+			s.calendars[rec.Calendar.ID] = *rec.Calendar
+		}
+	case opDeleteCalendar:
+		delete(s.calendars, rec.ID)
+	case opSaveSettings:
+		if rec.Settings != nil {
+			s.settings[rec.Settings.UserID] = *rec.Settings
+		}
+	}
+	if rec.NextID > s.nextID {
+		s.nextID = rec.NextID
+	}
+}
+
+// compact переписывает журнал так, чтобы в нем остались только живые события, календари и настройки из state
+func (r *FileRepository) compact(state *logState) error {
+	tmpPath := r.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to compact storage log: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	ids := make([]int, 0, len(state.events))
+	for id := range state.events {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, id := range ids {
+		event := state.events[id]
+		if err := enc.Encode(logRecord{Op: opCreate, Event: &event, NextID: state.nextID}); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact storage log: %w", err)
+		}
+	}
+	calendarIDs := make([]int, 0, len(state.calendars))
+	for id := range state.calendars {
+		calendarIDs = append(calendarIDs, id)
+	}
+	sort.Ints(calendarIDs)
+	for _, id := range calendarIDs {
+		cal := state.calendars[id]
+		if err := enc.Encode(logRecord{Op: opSaveCalendar, Calendar: &cal}); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact storage log: %w", err)
+		}
+	}
+	users := make([]string, 0, len(state.settings))
+	for userID := range state.settings {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+	for _, userID := range users {
+		settings := state.settings[userID]
+		if err := enc.Encode(logRecord{Op: opSaveSettings, Settings: &settings}); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact storage log: %w", err)
+		}
+	}
+	// Следующий ID сохраняем отдельно: в журнале может не остаться ни одного события
+	if err := enc.Encode(logRecord{Op: opNextID, NextID: state.nextID}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact storage log: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact storage log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact storage log: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact storage log: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("failed to compact storage log: %w", err)
+	}
+	return syncDir(filepath.Dir(r.path))
+}
+
+// append дописывает запись в журнал и дожидается ее сброса на диск
+func (r *FileRepository) append(rec logRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode storage record: %w", err)
+	}
+	data = append(data, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(data); err != nil {
+		return r.rollback(fmt.Errorf("failed to write storage log: %w", err))
+	}
+	if err := r.file.Sync(); err != nil {
+		return r.rollback(fmt.Errorf("failed to sync storage log: %w", err))
+	}
+	r.size += int64(len(data))
+	return nil
+}
+
+// rollback обрезает журнал по последней целой записи после неудачной записи err: иначе следующие
+// записи легли бы после оборванной, и журнал перестал бы проигрываться. Вызывается под r.mu.
+func (r *FileRepository) rollback(err error) error {
+	if truncErr := os.Truncate(r.path, r.size); truncErr != nil {
+		return fmt.Errorf("%w; failed to truncate storage log: %w", err, truncErr)
+	}
+	return err
+}
+
+func (r *FileRepository) Load() ([]Event, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, err := r.load()
+	if err != nil {
+		return nil, 0, err
+	}
+	events := make([]Event, 0, len(state.events))
+	for _, event := range state.events {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, state.nextID, nil
+}
+
+func (r *FileRepository) CreateEvent(event Event, nextID int) error {
+	return r.append(logRecord{Op: opCreate, Event: &event, NextID: nextID})
+}
+
+func (r *FileRepository) UpdateEvent(event Event) error {
+	return r.append(logRecord{Op: opUpdate, Event: &event})
+}
+
+func (r *FileRepository) SaveEvents(events []Event, nextID int) error {
+	return r.append(logRecord{Op: opSave, Events: events, NextID: nextID})
+}
+
+func (r *FileRepository) DeleteEvent(id int) error {
+	return r.append(logRecord{Op: opDelete, ID: id})
+}
+
+func (r *FileRepository) LoadCalendars() ([]UserCalendar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	calendars := make([]UserCalendar, 0, len(state.calendars))
+	for _, cal := range state.calendars {
+		calendars = append(calendars, cal)
+	}
+	sort.Slice(calendars, func(i, j int) bool { return calendars[i].ID < calendars[j].ID })
+	return calendars, nil
+}
+
+func (r *FileRepository) SaveCalendar(cal UserCalendar) error {
+	return r.append(logRecord{Op: opSaveCalendar, Calendar: &cal})
+}
+
+func (r *FileRepository) DeleteCalendar(id int) error {
+	return r.append(logRecord{Op: opDeleteCalendar, ID: id})
+}
+
+func (r *FileRepository) LoadSettings() ([]UserSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	settings := make([]UserSettings, 0, len(state.settings))
+	for _, s := range state.settings {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].UserID < settings[j].UserID })
+	return settings, nil
+}
+
+func (r *FileRepository) SaveSettings(settings UserSettings) error {
+	return r.append(logRecord{Op: opSaveSettings, Settings: &settings})
+}
+
+// Ping проверяет, что журнал открыт и файл не удален с диска (иначе записи уходили бы в никуда)
+func (r *FileRepository) Ping() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Stat(); err != nil {
+		return fmt.Errorf("storage log unavailable: %w", err)
+	}
+	if _, err := os.Stat(r.path); err != nil {
+		return fmt.Errorf("storage log unavailable: %w", err)
+	}
+	return nil
+}
+
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.file.Sync(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// syncDir сбрасывает на диск запись каталога, чтобы переименование файла пережило сбой питания
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqlSchema создает таблицы хранилища.
+// Событие целиком хранится в JSON, отдельными колонками вынесены только ключи для выборок.
+const sqlSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	id      INTEGER PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	data    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_user_id ON events (user_id);
+CREATE TABLE IF NOT EXISTS calendars (
+	id       INTEGER PRIMARY KEY,
+	owner_id TEXT NOT NULL,
+	data     TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS settings (
+	user_id TEXT PRIMARY KEY,
+	data    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+`
+
+// SQLRepository хранит события во встроенной базе SQLite
+type SQLRepository struct {
+	db *sql.DB
+}
+
+// OpenSQLRepository открывает (или создает) базу SQLite по пути path
+func OpenSQLRepository(path string) (*SQLRepository, error) {
+	// Путь экранируется: иначе ? и # в имени файла читались бы как параметры подключения
+	dsn := (&url.URL{Scheme: "file", Path: path}).String() + "?_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite допускает одного писателя, Calendar и так сериализует изменения
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqlSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return &SQLRepository{db: db}, nil
+}
+
+func (r *SQLRepository) Load() ([]Event, int, error) {
+	rows, err := r.db.Query(`SELECT data FROM events ORDER BY id`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, 0, fmt.Errorf("failed to load events: %w", err)
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to load events: %w", err)
+	}
+
+	nextID := 1
+	err = r.db.QueryRow(`SELECT value FROM meta WHERE key = 'next_id'`).Scan(&nextID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, 0, fmt.Errorf("failed to load next ID: %w", err)
+	}
+	return events, nextID, nil
+}
+
+func (r *SQLRepository) CreateEvent(event Event, nextID int) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO events (id, user_id, data) VALUES (?, ?, ?)`, event.ID, event.UserID, string(data)); err != nil {
+		return fmt.Errorf("failed to insert event: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES ('next_id', ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, nextID); err != nil {
+		return fmt.Errorf("failed to store next ID: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *SQLRepository) UpdateEvent(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if _, err := r.db.Exec(`UPDATE events SET user_id = ?, data = ? WHERE id = ?`, event.UserID, string(data), event.ID); err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) SaveEvents(events []Event, nextID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO events (id, user_id, data) VALUES (?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET user_id = excluded.user_id, data = excluded.data`, event.ID, event.UserID, string(data)); err != nil {
+			return fmt.Errorf("failed to store event: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES ('next_id', ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, nextID); err != nil {
+		return fmt.Errorf("failed to store next ID: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *SQLRepository) DeleteEvent(id int) error {
+	if _, err := r.db.Exec(`DELETE FROM events WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) LoadCalendars() ([]UserCalendar, error) {
+	rows, err := r.db.Query(`SELECT data FROM calendars ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendars: %w", err)
+	}
+	defer rows.Close()
+
+	calendars := make([]UserCalendar, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to load calendars: %w", err)
+		}
+		var cal UserCalendar
+		if err := json.Unmarshal([]byte(data), &cal); err != nil {
+			return nil, fmt.Errorf("failed to decode calendar: %w", err)
+		}
+		calendars = append(calendars, cal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load calendars: %w", err)
+	}
+	return calendars, nil
+}
+
+func (r *SQLRepository) SaveCalendar(cal UserCalendar) error {
+	data, err := json.Marshal(cal)
+	if err != nil {
+		return fmt.Errorf("failed to encode calendar: %w", err)
+	}
+	if _, err := r.db.Exec(`INSERT INTO calendars (id, owner_id, data) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET owner_id = excluded.owner_id, data = excluded.data`, cal.ID, cal.OwnerID, string(data)); err != nil {
+		return fmt.Errorf("failed to store calendar: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) DeleteCalendar(id int) error {
+	if _, err := r.db.Exec(`DELETE FROM calendars WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete calendar: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) LoadSettings() ([]UserSettings, error) {
+	rows, err := r.db.Query(`SELECT data FROM settings ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make([]UserSettings, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to load settings: %w", err)
+		}
+		var s UserSettings
+		if err := json.Unmarshal([]byte(data), &s); err != nil {
+			return nil, fmt.Errorf("failed to decode settings: %w", err)
+		}
+		settings = append(settings, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (r *SQLRepository) SaveSettings(settings UserSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	if _, err := r.db.Exec(`INSERT INTO settings (user_id, data) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET data = excluded.data`, settings.UserID, string(data)); err != nil {
+		return fmt.Errorf("failed to store settings: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) Ping() error {
+	if err := r.db.Ping(); err != nil {
+		return fmt.Errorf("database unavailable: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openTestRepositories возвращает функции открытия каждого постоянного хранилища в одном и том же месте
+func openTestRepositories(t *testing.T) map[string]func() Repository {
+	dir := t.TempDir()
+	open := func(kind, name string) func() Repository {
+		return func() Repository {
+			repo, err := OpenRepository(kind, filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("Failed to open %s storage: %v", kind, err)
+			}
+			return repo
+		}
+	}
+	return map[string]func() Repository{
+		StorageFile:   open(StorageFile, "calendar.log"),
+		StorageSQLite: open(StorageSQLite, "calendar.db"),
+	}
+}
+
+func TestRepositoryPersistence(t *testing.T) {
+	for kind, open := range openTestRepositories(t) {
+		t.Run(kind, func(t *testing.T) {
+			calendar, err := NewCalendar(open())
+			if err != nil {
+				t.Fatalf("Failed to create calendar: %v", err)
+			}
+
+			id1, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Event 1", Date: "2023-12-31"})
+			id2, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Event 2", Date: "2023-12-31"})
+			id3, _ := calendar.CreateEvent(Event{UserID: "user2", Title: "Event 3", Date: "2024-01-01"})
+			work, _ := calendar.CreateCalendar(UserCalendar{OwnerID: "user1", Name: "Work"})
+			calendar.ShareCalendar("user1", work.ID, "user2", PermissionRead)
+			removed, _ := calendar.CreateCalendar(UserCalendar{OwnerID: "user1", Name: "Removed"})
+			calendar.DeleteCalendar("user1", removed.ID)
+
+			if err := calendar.UpdateEvent(Event{ID: id1, UserID: "user1", Title: "Updated", Date: "2023-12-31"}); err != nil {
+				t.Fatalf("Failed to update event: %v", err)
+			}
+			// Удаляем последнее событие: его ID не должен выдаваться повторно после перезапуска
+			if err := calendar.DeleteUserEvent("user2", id3); err != nil {
+				t.Fatalf("Failed to delete event: %v", err)
+			}
+			if err := calendar.Close(); err != nil {
+				t.Fatalf("Failed to close calendar: %v", err)
+			}
+
+			reopened, err := NewCalendar(open())
+			if err != nil {
+				t.Fatalf("Failed to reopen calendar: %v", err)
+			}
+			defer reopened.Close()
+
+			if len(reopened.events) != 2 {
+				t.Fatalf("Expected 2 events after restart, got %d", len(reopened.events))
+			}
+			if reopened.events[id1].Title != "Updated" {
+				t.Errorf("Expected updated title, got '%s'", reopened.events[id1].Title)
+			}
+			if _, exists := reopened.events[id2]; !exists {
+				t.Error("Event 2 was lost after restart")
+			}
+			if got := reopened.UserCalendars("user2"); len(got) != 1 || got[0].Name != "Work" || got[0].Permission != PermissionRead {
+				t.Errorf("Expected shared calendar after restart, got %+v", got)
+			}
+
+			id4, err := reopened.CreateEvent(Event{UserID: "user1", Title: "Event 4", Date: "2024-01-02"})
+			if err != nil {
+				t.Fatalf("Failed to create event: %v", err)
+			}
+			if id4 != id3+1 {
+				t.Errorf("Expected next ID %d after restart, got %d", id3+1, id4)
+			}
+		})
+	}
+}
+
+func TestFileRepositoryTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, err := OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	if err := repo.CreateEvent(Event{ID: 1, UserID: "user1", Title: "Event", Date: "2023-12-31"}, 2); err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+	repo.Close()
+
+	// Имитируем обрыв записи посреди строки
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"op":"create","event":{"id":2,`)
+	f.Close()
+
+	repo, err = OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("Expected truncated tail to be dropped, got %v", err)
+	}
+	defer repo.Close()
+
+	events, nextID, _ := repo.Load()
+	if len(events) != 1 || nextID != 2 {
+		t.Errorf("Expected 1 event and next ID 2, got %d events and next ID %d", len(events), nextID)
+	}
+	if err := repo.CreateEvent(Event{ID: 2, UserID: "user1", Title: "Event 2", Date: "2023-12-31"}, 3); err != nil {
+		t.Fatalf("Failed to append after recovery: %v", err)
+	}
+}
+
+func TestFileRepositoryFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, err := OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	if err := repo.CreateEvent(Event{ID: 1, UserID: "user1", Title: "Event", Date: "2023-12-31"}, 2); err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+
+	// Имитируем запись, оборвавшуюся посреди строки: часть попала в файл, а Write вернул ошибку
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"op":"create","event":{"id":2,`)
+	f.Close()
+	writable := repo.file
+	repo.file, _ = os.Open(path)
+	if err := repo.CreateEvent(Event{ID: 2, UserID: "user1", Title: "Lost", Date: "2023-12-31"}, 3); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	repo.file.Close()
+	repo.file = writable
+
+	// Оборванная запись срезана, и следующая ложится после последней целой
+	if err := repo.CreateEvent(Event{ID: 2, UserID: "user1", Title: "Event 2", Date: "2023-12-31"}, 3); err != nil {
+		t.Fatalf("Failed to append after a failed write: %v", err)
+	}
+	repo.Close()
+	repo, err = OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("Expected the log to replay after a failed write, got %v", err)
+	}
+	defer repo.Close()
+	if events, nextID, _ := repo.Load(); len(events) != 2 || nextID != 3 || events[1].Title != "Event 2" {
+		t.Errorf("Expected 2 events and next ID 3, got %+v and next ID %d", events, nextID)
+	}
+}
+
+func TestFileRepositoryCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, _ := OpenFileRepository(path)
+	for i := 1; i <= 200; i++ {
+		repo.CreateEvent(Event{ID: i, UserID: "user1", Title: "Event", Date: "2023-12-31"}, i+1)
+		repo.DeleteEvent(i)
+	}
+	repo.Close()
+
+	before, _ := os.Stat(path)
+	repo, err := OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer repo.Close()
+	after, _ := os.Stat(path)
+
+	if after.Size() >= before.Size() {
+		t.Errorf("Expected log to shrink after compaction, got %d -> %d bytes", before.Size(), after.Size())
+	}
+	events, nextID, _ := repo.Load()
+	if len(events) != 0 || nextID != 201 {
+		t.Errorf("Expected no events and next ID 201, got %d events and next ID %d", len(events), nextID)
+	}
+}
+
+func TestSQLRepositorySpecialPath(t *testing.T) {
+	// ? и # в пути — часть имени файла, а не параметры подключения
+	path := filepath.Join(t.TempDir(), "my calendar?mode=ro#1.db")
+	repo, err := OpenSQLRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer repo.Close()
+	if err := repo.CreateEvent(Event{ID: 1, UserID: "user1", Title: "Event", Date: "2023-12-31"}, 2); err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the database at %q: %v", path, err)
+	}
+}
+
+func TestOpenRepositoryUnknown(t *testing.T) {
+	if _, err := OpenRepository("redis", ""); err == nil {
+		t.Fatal("Expected error for unknown storage, got nil")
+	}
+	repo, err := OpenRepository(StorageMemory, "")
+	if err != nil || repo != nil {
+		t.Fatalf("Expected nil repository for memory storage, got %v, %v", repo, err)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trashRetention — сколько удаленное событие хранится в корзине, прежде чем удалиться окончательно
+const trashRetention = 30 * 24 * time.Hour
+
+// ErrVersionMismatch — событие изменилось с тех пор, как клиент его прочитал (If-Match не совпал)
+var ErrVersionMismatch = errors.New("event version does not match")
+
+// trashPut кладет удаленное событие в корзину. Вызывается под блокировкой записи.
+func (c *Calendar) trashPut(event *Event) {
+	if c.trash == nil {
+		c.trash = make(map[int]*Event)
+		c.userTrash = make(map[string]map[int]*Event)
+	}
+	c.trash[event.ID] = event
+	if c.userTrash[event.UserID] == nil {
+		c.userTrash[event.UserID] = make(map[int]*Event)
+	}
+	c.userTrash[event.UserID][event.ID] = event
+}
+
+// trashRemove убирает событие из корзины в памяти. Вызывается под блокировкой записи.
+func (c *Calendar) trashRemove(event *Event) {
+	delete(c.trash, event.ID)
+	delete(c.userTrash[event.UserID], event.ID)
+	if len(c.userTrash[event.UserID]) == 0 {
+		delete(c.userTrash, event.UserID)
+	}
+}
+
+// purgeTrash окончательно удаляет события, пролежавшие в корзине дольше trashRetention, а также
+// самые старые удаленные события пользователей, у которых в корзине больше quota.MaxEvents событий:
+// иначе удаление и создание по кругу обходили бы ограничение на число событий.
+// Вызывается под блокировкой записи.
+func (c *Calendar) purgeTrash(now time.Time) error {
+	for _, event := range c.trash {
+		if now.Sub(event.DeletedAt) < trashRetention {
+			continue
+		}
+		if err := c.purgeEvent(event); err != nil {
+			return err
+		}
+	}
+	limit := c.quota.MaxEvents
+	if limit <= 0 {
+		return nil
+	}
+	for _, trash := range c.userTrash {
+		if len(trash) <= limit {
+			continue
+		}
+		events := make([]*Event, 0, len(trash))
+		for _, event := range trash {
+			events = append(events, event)
+		}
+		sort.Slice(events, func(i, j int) bool {
+			if !events[i].DeletedAt.Equal(events[j].DeletedAt) {
+				return events[i].DeletedAt.Before(events[j].DeletedAt)
+			}
+			return events[i].ID < events[j].ID
+		})
+		for _, event := range events[:len(events)-limit] {
+			if err := c.purgeEvent(event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// purgeEvent окончательно удаляет событие из корзины. Вызывается под блокировкой записи.
+func (c *Calendar) purgeEvent(event *Event) error {
+	if c.repo != nil {
+		if err := c.repo.DeleteEvent(event.ID); err != nil {
+			return fmt.Errorf("failed to purge deleted event %d: %w", event.ID, err)
+		}
+	}
+	c.trashRemove(event)
+	return nil
+}
+
+// DeletedEvents возвращает события из корзины, которые пользователь может восстановить, по возрастанию ID
+func (c *Calendar) DeletedEvents(userID string) []Event {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	events := make([]Event, 0)
+	for _, event := range c.trash {
+		if c.canWrite(userID, event) {
+			events = append(events, *event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
+
+// RestoreEvent возвращает событие из корзины. Восстановить событие может тот, кто может его изменять.
+func (c *Calendar) RestoreEvent(userID string, id int) (Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deleted, exists := c.trash[id]
+	if !exists || !c.canRead(userID, deleted) {
+		return Event{}, ErrEventNotFound
+	}
+	if !c.canWrite(userID, deleted) {
+		return Event{}, ErrReadOnly
+	}
+	if c.uidTaken(deleted.UserID, deleted.UID, 0) {
+		return Event{}, ErrDuplicateUID
+	}
+	if err := c.checkQuota(deleted, true); err != nil {
+		return Event{}, err
+	}
+
+	event := *deleted
+	event.Version++
+	event.DeletedAt = time.Time{}
+	if c.repo != nil {
+		if err := c.repo.UpdateEvent(event); err != nil {
+			return Event{}, fmt.Errorf("failed to store event: %w", err)
+		}
+	}
+	c.putEvent(deleted, &event)
+	c.publishChange(nil, &event)
+	return event, nil
+}
+
+// versionETag возвращает ETag версии события
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch разбирает заголовок If-Match в список ожидаемых версий.
+// Пустой заголовок и "*" не ограничивают версию (nil); слабые и чужие ETag не совпадают ни с одной версией.
+func parseIfMatch(header string) []int {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+	versions := make([]int, 0)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// restoreEventHandler восстанавливает событие из корзины
+func restoreEventHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		event, err := calendar.RestoreEvent(userID, apiEventID(r))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.Header().Set("ETag", versionETag(event.Version))
+		writeResponse(w, http.StatusOK, Response{Message: "Event restored", Data: event})
+	}
+}
+
+// trashHandler возвращает страницами удаленные события, которые пользователь может восстановить
+func trashHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+		opts, err := parseListOptions(r, defaultPageLimit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeEventPage(w, r, "Deleted events", calendar.DeletedEvents(userID), opts, idKey)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL2/calendarServer/client"
+)
+
+func TestEventVersions(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Standup", Date: "2024-03-10", Attendees: []Attendee{{UserID: "user2"}}})
+	if event, _ := calendar.GetEvent("user1", id); event.Version != 1 {
+		t.Fatalf("Expected version 1 of a new event, got %d", event.Version)
+	}
+
+	update := Event{ID: id, UserID: "user1", Title: "Retro", Date: "2024-03-10", Attendees: []Attendee{{UserID: "user2"}}}
+	if err := calendar.UpdateEventWithOptions(update, WriteOptions{IfVersion: []int{1}}); err != nil {
+		t.Fatalf("Update of the current version: %v", err)
+	}
+	if err := calendar.UpdateEventWithOptions(update, WriteOptions{IfVersion: []int{1}}); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Expected ErrVersionMismatch for a stale version, got %v", err)
+	}
+	if err := calendar.UpdateEventWithOptions(update, WriteOptions{IfVersion: []int{}}); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Expected ErrVersionMismatch for an empty version list, got %v", err)
+	}
+	// Ответ участника меняет событие, отметка о напоминаниях — нет
+	calendar.RespondToEvent("user2", id, RSVPAccepted)
+	calendar.markReminded(id, time.Now(), true)
+	if event, _ := calendar.GetEvent("user1", id); event.Version != 3 {
+		t.Errorf("Expected version 3, got %d", event.Version)
+	}
+
+	if err := calendar.DeleteUserEventWithOptions("user1", id, WriteOptions{IfVersion: []int{2}}); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Expected ErrVersionMismatch on delete, got %v", err)
+	}
+	if err := calendar.DeleteUserEventWithOptions("user1", id, WriteOptions{IfVersion: []int{2, 3}}); err != nil {
+		t.Errorf("Delete of the current version: %v", err)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	for kind, open := range openTestRepositories(t) {
+		t.Run(kind, func(t *testing.T) {
+			calendar, _ := NewCalendar(open())
+			id, _ := calendar.CreateEvent(Event{UserID: "user1", UID: "standup", Title: "Standup", Date: "2024-03-10"})
+			other, _ := calendar.CreateEvent(Event{UserID: "user1", Title: "Old", Date: "2024-03-11"})
+			calendar.DeleteUserEvent("user1", id)
+			calendar.DeleteUserEvent("user1", other)
+
+			if _, err := calendar.GetEvent("user1", id); !errors.Is(err, ErrEventNotFound) {
+				t.Errorf("Expected deleted event to be hidden, got %v", err)
+			}
+			if _, err := calendar.RestoreEvent("user2", id); !errors.Is(err, ErrEventNotFound) {
+				t.Errorf("Expected ErrEventNotFound for another user, got %v", err)
+			}
+			// Окончательно удаляются только события старше trashRetention
+			expired := *calendar.trash[other]
+			expired.DeletedAt = time.Now().Add(-trashRetention - time.Hour)
+			calendar.repo.UpdateEvent(expired)
+			calendar.Close()
+
+			calendar, _ = NewCalendar(open())
+			defer calendar.Close()
+			deleted := calendar.DeletedEvents("user1")
+			if len(deleted) != 1 || deleted[0].ID != id || deleted[0].DeletedAt.IsZero() || deleted[0].Version != 2 {
+				t.Fatalf("Unexpected trash after reopening: %+v", deleted)
+			}
+
+			restored, err := calendar.RestoreEvent("user1", id)
+			if err != nil || restored.Version != 3 || !restored.DeletedAt.IsZero() {
+				t.Fatalf("RestoreEvent: %+v, %v", restored, err)
+			}
+			if events := calendar.GetUserEvents("user1", time.Time{}, time.Time{}); len(events) != 1 || events[0].ID != id {
+				t.Errorf("Expected the restored event in the calendar, got %+v", events)
+			}
+			if _, err := calendar.RestoreEvent("user1", other); !errors.Is(err, ErrEventNotFound) {
+				t.Errorf("Expected the purged event to be gone, got %v", err)
+			}
+
+			// Восстановление не должно дублировать UID, занятый после удаления
+			calendar.DeleteUserEvent("user1", id)
+			calendar.CreateEvent(Event{UserID: "user1", UID: "standup", Title: "New standup", Date: "2024-03-12"})
+			if _, err := calendar.RestoreEvent("user1", id); !errors.Is(err, ErrDuplicateUID) {
+				t.Errorf("Expected ErrDuplicateUID, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTrashSharing(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	work, _ := calendar.CreateCalendar(UserCalendar{OwnerID: "user1", Name: "Work"})
+	calendar.ShareCalendar("user1", work.ID, "user2", PermissionRead)
+	calendar.ShareCalendar("user1", work.ID, "user3", PermissionWrite)
+	id, _ := calendar.CreateEvent(Event{UserID: "user1", CalendarID: work.ID, Title: "Planning", Date: "2024-03-10"})
+	if err := calendar.DeleteUserEvent("user3", id); err != nil {
+		t.Fatalf("Delete with write access: %v", err)
+	}
+
+	if deleted := calendar.DeletedEvents("user2"); len(deleted) != 0 {
+		t.Errorf("Read-only users should not see the trash, got %+v", deleted)
+	}
+	if _, err := calendar.RestoreEvent("user2", id); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := calendar.RestoreEvent("user3", id); err != nil {
+		t.Errorf("Restore with write access: %v", err)
+	}
+
+	// Удаление календаря не оставляет его событий в корзине
+	calendar.DeleteUserEvent("user1", id)
+	calendar.DeleteCalendar("user1", work.ID)
+	if deleted := calendar.DeletedEvents("user1"); len(deleted) != 0 {
+		t.Errorf("Expected an empty trash after deleting the calendar, got %+v", deleted)
+	}
+}
+
+func TestVersionsAPI(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	router := newRouter(calendar, NewAuthenticator(map[string]string{"user1-key": "user1"}, nil), nil)
+	validateResponses(t, router)
+	const path = "/api/v1/users/user1/events"
+
+	rec := serve(router, http.MethodPost, path, `{"title":"Standup","date":"2024-03-10"}`, "user1-key")
+	if rec.Code != http.StatusCreated || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("Expected 201 with ETag \"1\", got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	withHeader := func(method, target, body, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer user1-key")
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := withHeader(http.MethodGet, path+"/1", "", "If-None-Match", `"1"`); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for the cached version, got %d", rec.Code)
+	}
+	if rec := withHeader(http.MethodPut, path+"/1", `{"title":"Retro","date":"2024-03-10"}`, "If-Match", `"1"`); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("Expected 200 with ETag \"2\", got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := withHeader(http.MethodPut, path+"/1", `{"title":"Lost","date":"2024-03-10"}`, "If-Match", `"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale PUT, got %d", rec.Code)
+	}
+	if rec := withHeader(http.MethodPatch, path+"/1", `{"title":"Lost"}`, "If-Match", `W/"2"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a weak ETag, got %d", rec.Code)
+	}
+	if rec := withHeader(http.MethodDelete, path+"/1", "", "If-Match", `"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale DELETE, got %d", rec.Code)
+	}
+	if rec := withHeader(http.MethodPost, "/update_event", `{"id":1,"user_id":"user1","title":"Lost","date":"2024-03-10"}`, "If-Match", `"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 from the legacy route, got %d", rec.Code)
+	}
+	if event, _ := calendar.GetEvent("user1", 1); event.Title != "Retro" {
+		t.Errorf("Stale writes must not change the event, got %q", event.Title)
+	}
+
+	if rec := withHeader(http.MethodDelete, path+"/1", "", "If-Match", `"2"`); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/api/v1/users/user1/trash", "", "user1-key"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted_at"`) {
+		t.Errorf("Expected the event in the trash, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodPost, path+"/1/restore", "", "user1-key"); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"4"` {
+		t.Errorf("Expected 200 with ETag \"4\" on restore, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := serve(router, http.MethodPost, path+"/1/restore", "", "user1-key"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an event not in the trash, got %d", rec.Code)
+	}
+}
+
+func TestClientVersions(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+	created, _ := c.CreateEvent(ctx, "user1", client.Event{Title: "Standup", Date: "2024-03-10"}, false)
+
+	stale := *created
+	if _, err := c.PatchEvent(ctx, "user1", created.ID, map[string]any{"title": "Retro"}, created.Version, false); err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+	stale.Title = "Lost"
+	if _, err := c.ReplaceEvent(ctx, "user1", created.ID, stale, false); !client.IsPreconditionFailed(err) {
+		t.Errorf("Expected precondition failure for a stale replace, got %v", err)
+	}
+	if err := c.DeleteEvent(ctx, "user1", created.ID, stale.Version); !client.IsPreconditionFailed(err) {
+		t.Errorf("Expected precondition failure for a stale delete, got %v", err)
+	}
+
+	if err := c.DeleteEvent(ctx, "user1", created.ID, 0); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	page, err := c.ListDeletedEvents(ctx, "user1", client.ListOptions{})
+	if err != nil || len(page.Events) != 1 || page.Events[0].DeletedAt.IsZero() {
+		t.Fatalf("ListDeletedEvents: %+v, %v", page, err)
+	}
+	restored, err := c.RestoreEvent(ctx, "user1", created.ID)
+	if err != nil || restored.Title != "Retro" || restored.Version != 4 {
+		t.Errorf("RestoreEvent: %+v, %v", restored, err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Представления календаря для /views/{view}
+const (
+	ViewDay   = "day"
+	ViewWeek  = "week"
+	ViewMonth = "month"
+	ViewYear  = "year"
+)
+
+const (
+	// defaultAgendaLimit — сколько событий повестки возвращается, если limit не задан
+	defaultAgendaLimit = 20
+	// agendaWindow — с какого окна начинается поиск событий повестки; окно удваивается, пока их не хватит
+	agendaWindow = 7 * 24 * time.Hour
+	// agendaHorizonYears — дальше скольких лет от начала повестки события не ищутся:
+	// бесконечные повторения иначе искались бы вечно
+	agendaHorizonYears = 2
+)
+
+// ViewRange возвращает границы представления view (day, week, month или year), в которое попадает date
+func ViewRange(view, date string, loc *time.Location, weekStart time.Weekday) (time.Time, time.Time, error) {
+	switch view {
+	case ViewDay:
+		return DayRange(date, loc)
+	case ViewWeek:
+		return WeekRange(date, loc, weekStart)
+	case ViewMonth:
+		return MonthRange(date, loc)
+	case ViewYear:
+		return YearRange(date, loc)
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown view %q", view)
+}
+
+// Agenda возвращает повестку: не больше limit ближайших событий (экземпляров повторяющихся событий),
+// которые еще не закончились к моменту from, по возрастанию начала. Идущие в момент from события
+// тоже попадают в повестку. События дальше agendaHorizonYears лет от from не ищутся.
+func (c *Calendar) Agenda(userID string, from time.Time, limit int) ([]Event, error) {
+	if limit < 1 {
+		return nil, errors.New("limit must be positive")
+	}
+	opts := ListOptions{Limit: limit}
+	events, err := c.agendaEvents(userID, from, opts)
+	if err != nil {
+		return nil, err
+	}
+	page, _ := paginate(events, opts, startKey(from.Location()))
+	return page, nil
+}
+
+// agendaEvents возвращает события с момента from, которых хватает на страницу opts.
+// Окно поиска начинается с agendaWindow и удваивается, пока на странице есть место,
+// но не выходит за agendaHorizonYears лет.
+func (c *Calendar) agendaEvents(userID string, from time.Time, opts ListOptions) ([]Event, error) {
+	horizon := from.AddDate(agendaHorizonYears, 0, 0)
+	for window := agendaWindow; ; window *= 2 {
+		to := from.Add(window)
+		if to.After(horizon) {
+			to = horizon
+		}
+		events, err := c.GetEventsInRange(userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		// Следующая страница есть — значит, текущая заполнена целиком
+		if _, next := paginate(events, opts, startKey(from.Location())); next != "" || to.Equal(horizon) {
+			return events, nil
+		}
+	}
+}
+
+// viewHandler отвечает событиями дня, недели, месяца или года, в которые попадает date.
+// Неделя начинается с дня из параметра week_start или из настроек пользователя.
+func viewHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+
+		opts, err := parseListOptions(r, defaultPageLimit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		weekStart, err := requestWeekStart(r, calendar, userID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		from, to, err := ViewRange(mux.Vars(r)["view"], r.URL.Query().Get("date"), loc, weekStart)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD")
+			return
+		}
+
+		events, err := calendar.GetEventsInRange(userID, from, to)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeEventPage(w, r, "Events", events, opts, startKey(loc))
+	}
+}
+
+// agendaHandler отвечает повесткой: ближайшими limit событиями начиная с from (по умолчанию — с текущего момента).
+// Следующая страница повестки запрашивается курсором, как у остальных списков.
+func agendaHandler(calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := apiUser(w, r)
+		if !ok {
+			return
+		}
+
+		opts, err := parseListOptions(r, defaultAgendaLimit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		loc, err := requestLocation(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		from := time.Now().In(loc)
+		if value := r.URL.Query().Get("from"); value != "" {
+			if from, err = parseRangeBound(value, loc, false); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		events, err := calendar.agendaEvents(userID, from, opts)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeEventPage(w, r, "Agenda", events, opts, startKey(loc))
+	}
+}
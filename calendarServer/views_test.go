@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"WBTechL2/calendarServer/client"
+)
+
+func TestAgenda(t *testing.T) {
+	calendar, _ := NewCalendar(nil)
+	start := time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Standup", Start: start, End: start.Add(15 * time.Minute), RRule: "FREQ=WEEKLY"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Trip", Date: "2024-03-10", EndDate: "2024-03-12"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Far away", Date: "2025-01-01"})
+	calendar.CreateEvent(Event{UserID: "user1", Title: "Past", Date: "2024-03-01"})
+
+	// Идущая поездка попадает в повестку, прошедшие события — нет
+	events, err := calendar.Agenda("user1", time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := orderedTitles(events); !equalStrings(got, []string{"Trip", "Standup", "Standup"}) || events[1].RecurrenceID == "" {
+		t.Errorf("Unexpected agenda: %v", got)
+	}
+
+	// До далекого события надо пройти 42 повторения
+	events, _ = calendar.Agenda("user1", time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), 45)
+	if len(events) != 45 || events[42].Title != "Far away" {
+		t.Errorf("Expected the far event among 45, got %d events", len(events))
+	}
+
+	// Бесконечное повторение не ищется дальше горизонта
+	events, _ = calendar.Agenda("user1", time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), 1000)
+	if last := events[len(events)-1].Start; len(events) > 110 || last.After(time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected events within %d years, got %d ending at %v", agendaHorizonYears, len(events), last)
+	}
+	if _, err := calendar.Agenda("user1", start, 0); err == nil {
+		t.Error("Expected an error for a zero limit")
+	}
+}
+
+func TestViewsAPI(t *testing.T) {
+	ctx := context.Background()
+	c, calendar := newTestClient(t)
+	for _, date := range []string{"2024-03-09", "2024-03-10", "2024-03-11", "2024-03-17", "2024-12-31", "2025-01-01"} {
+		calendar.CreateEvent(Event{UserID: "user1", Title: date, Date: date})
+	}
+	titles := func(page *client.EventPage) []string {
+		result := make([]string, len(page.Events))
+		for i, event := range page.Events {
+			result[i] = event.Title
+		}
+		return result
+	}
+
+	settings, err := c.GetSettings(ctx, "user1")
+	if err != nil || settings.WeekStart != "monday" {
+		t.Fatalf("GetSettings: %+v, %v", settings, err)
+	}
+	page, err := c.GetView(ctx, "user1", client.ViewWeek, "2024-03-13", client.ListOptions{})
+	if err != nil || !equalStrings(titles(page), []string{"2024-03-11", "2024-03-17"}) {
+		t.Errorf("Expected the week from Monday, got %v, %v", page, err)
+	}
+
+	if settings, err = c.UpdateSettings(ctx, "user1", client.Settings{Locale: "en-US"}); err != nil || settings.WeekStart != "sunday" {
+		t.Fatalf("UpdateSettings: %+v, %v", settings, err)
+	}
+	page, _ = c.GetView(ctx, "user1", client.ViewWeek, "2024-03-13", client.ListOptions{})
+	if !equalStrings(titles(page), []string{"2024-03-10", "2024-03-11"}) {
+		t.Errorf("Expected the week from Sunday, got %v", titles(page))
+	}
+	page, _ = c.GetView(ctx, "user1", client.ViewWeek, "2024-03-13", client.ListOptions{WeekStart: "saturday"})
+	if !equalStrings(titles(page), []string{"2024-03-09", "2024-03-10", "2024-03-11"}) {
+		t.Errorf("Expected the week from Saturday, got %v", titles(page))
+	}
+	page, _ = c.GetView(ctx, "user1", client.ViewYear, "2024-06-01", client.ListOptions{Limit: 4})
+	if !equalStrings(titles(page), []string{"2024-03-09", "2024-03-10", "2024-03-11", "2024-03-17"}) || page.NextCursor == "" {
+		t.Errorf("Expected the first page of the year, got %v", titles(page))
+	}
+	page, _ = c.GetView(ctx, "user1", client.ViewYear, "2024-06-01", client.ListOptions{Limit: 4, Cursor: page.NextCursor})
+	if !equalStrings(titles(page), []string{"2024-12-31"}) {
+		t.Errorf("Expected the rest of the year, got %v", titles(page))
+	}
+
+	page, err = c.GetAgenda(ctx, "user1", client.ListOptions{From: "2024-03-11", Limit: 2})
+	if err != nil || !equalStrings(titles(page), []string{"2024-03-11", "2024-03-17"}) || page.NextCursor == "" {
+		t.Fatalf("GetAgenda: %v, %v", page, err)
+	}
+	page, _ = c.GetAgenda(ctx, "user1", client.ListOptions{From: "2024-03-11", Limit: 2, Cursor: page.NextCursor})
+	if !equalStrings(titles(page), []string{"2024-12-31", "2025-01-01"}) {
+		t.Errorf("Expected the next page of the agenda, got %v", titles(page))
+	}
+
+	var apiErr *client.APIError
+	if _, err := c.UpdateSettings(ctx, "user1", client.Settings{Locale: "not a locale"}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid locale, got %v", err)
+	}
+	if _, err := c.GetView(ctx, "user1", "decade", "2024-03-13", client.ListOptions{}); !client.IsNotFound(err) {
+		t.Errorf("Expected 404 for an unknown view, got %v", err)
+	}
+	if _, err := c.GetView(ctx, "user1", client.ViewDay, "13.03.2024", client.ListOptions{}); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+}
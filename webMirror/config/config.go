@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"WBTechL2/webMirror/mirror"
+	"WBTechL2/webMirror/urlutils"
+)
+
+// Profile описывает один сайт для зеркалирования.
+// Нулевые значения полей означают «не задано» и при слиянии не перекрывают другие источники;
+// числовые поля и флаги — указатели, чтобы явный 0 (например, rate_limit: 0) тоже перекрывал.
+type Profile struct {
+	Seeds       []string          `json:"seeds" yaml:"seeds" toml:"seeds"`
+	Output      string            `json:"output" yaml:"output" toml:"output"`
+	Depth       *int              `json:"depth" yaml:"depth" toml:"depth"`
+	Timeout     *int              `json:"timeout" yaml:"timeout" toml:"timeout"` // секунды
+	Concurrency *int              `json:"concurrency" yaml:"concurrency" toml:"concurrency"`
+	Include     []string          `json:"include" yaml:"include" toml:"include"`
+	Exclude     []string          `json:"exclude" yaml:"exclude" toml:"exclude"`
+	Headers     map[string]string `json:"headers" yaml:"headers" toml:"headers"`
+	UserAgent   string            `json:"user_agent" yaml:"user_agent" toml:"user_agent"`
+	RateLimit   *float64          `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"` // запросов в секунду
+	Layout      string            `json:"layout" yaml:"layout" toml:"layout"`
+	Sitemaps    *bool             `json:"sitemaps" yaml:"sitemaps" toml:"sitemaps"`
+	Feeds       *bool             `json:"feeds" yaml:"feeds" toml:"feeds"`
+}
+
+// File — содержимое конфигурационного файла: общие значения и именованные профили
+type File struct {
+	Defaults Profile            `json:"defaults" yaml:"defaults" toml:"defaults"`
+	Profiles map[string]Profile `json:"profiles" yaml:"profiles" toml:"profiles"`
+}
+
+// Load читает конфигурационный файл, формат определяется по расширению (.json, .yaml/.yml, .toml)
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var f File
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &f)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &f)
+	case ".toml":
+		err = toml.Unmarshal(data, &f)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q (expected .json, .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if len(f.Profiles) == 0 {
+		return nil, fmt.Errorf("config %s defines no profiles", path)
+	}
+	return &f, nil
+}
+
+// Names возвращает имена профилей в алфавитном порядке
+func (f *File) Names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve возвращает профиль с примененными общими значениями и переопределениями overrides
+func (f *File) Resolve(name string, overrides Profile) (Profile, error) {
+	p, ok := f.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(f.Names(), ", "))
+	}
+	return Merge(f.Defaults, p, overrides), nil
+}
+
+// Merge сливает профили: каждый следующий перекрывает заданные в нем поля предыдущих.
+// Списки заменяются целиком, заголовки объединяются по ключам.
+func Merge(profiles ...Profile) Profile {
+	var res Profile
+	for _, p := range profiles {
+		if len(p.Seeds) > 0 {
+			res.Seeds = p.Seeds
+		}
+		if p.Output != "" {
+			res.Output = p.Output
+		}
+		if p.Depth != nil {
+			depth := *p.Depth
+			res.Depth = &depth
+		}
+		if p.Timeout != nil {
+			timeout := *p.Timeout
+			res.Timeout = &timeout
+		}
+		if p.Concurrency != nil {
+			concurrency := *p.Concurrency
+			res.Concurrency = &concurrency
+		}
+		if len(p.Include) > 0 {
+			res.Include = p.Include
+		}
+		if len(p.Exclude) > 0 {
+			res.Exclude = p.Exclude
+		}
+		if len(p.Headers) > 0 {
+			if res.Headers == nil {
+				res.Headers = make(map[string]string, len(p.Headers))
+			}
+			for k, v := range p.Headers {
+				res.Headers[k] = v
+			}
+		}
+		if p.UserAgent != "" {
+			res.UserAgent = p.UserAgent
+		}
+		if p.RateLimit != nil {
+			rate := *p.RateLimit
+			res.RateLimit = &rate
+		}
+		if p.Layout != "" {
+			res.Layout = p.Layout
+		}
+		if p.Sitemaps != nil {
+			sitemaps := *p.Sitemaps
+			res.Sitemaps = &sitemaps
+		}
+		if p.Feeds != nil {
+			feeds := *p.Feeds
+			res.Feeds = &feeds
+		}
+	}
+	return res
+}
+
+// Options преобразует профиль в параметры зеркалирования, незаданные поля берутся по умолчанию
+func (p Profile) Options() (mirror.Options, error) {
+	opts := mirror.DefaultOptions()
+	opts.Seeds = p.Seeds
+	if p.Output != "" {
+		opts.Output = p.Output
+	}
+	if p.Depth != nil {
+		opts.Depth = *p.Depth
+	}
+	if p.Timeout != nil {
+		opts.Timeout = time.Duration(*p.Timeout) * time.Second
+	}
+	if p.Concurrency != nil {
+		opts.Concurrency = *p.Concurrency
+	}
+	opts.Include = p.Include
+	opts.Exclude = p.Exclude
+	opts.Headers = p.Headers
+	opts.UserAgent = p.UserAgent
+	if p.RateLimit != nil {
+		opts.RateLimit = *p.RateLimit
+	}
+	opts.Sitemaps = p.Sitemaps != nil && *p.Sitemaps
+	opts.Feeds = p.Feeds != nil && *p.Feeds
+
+	layout, err := urlutils.ParseLayout(p.Layout)
+	if err != nil {
+		return mirror.Options{}, err
+	}
+	opts.Layout = layout
+
+	return opts, opts.Validate()
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"WBTechL2/webMirror/urlutils"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadFormats(t *testing.T) {
+	files := map[string]string{
+		"sites.json": `{
+  "defaults": {"depth": 1, "headers": {"X-Token": "abc"}},
+  "profiles": {"docs": {"seeds": ["https://example.com/docs"], "rate_limit": 2, "layout": "flat"}}
+}`,
+		"sites.yaml": `
+defaults:
+  depth: 1
+  headers:
+    X-Token: abc
+profiles:
+  docs:
+    seeds: [https://example.com/docs]
+    rate_limit: 2
+    layout: flat
+`,
+		"sites.toml": `
+[defaults]
+depth = 1
+[defaults.headers]
+X-Token = "abc"
+
+[profiles.docs]
+seeds = ["https://example.com/docs"]
+rate_limit = 2
+layout = "flat"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			f, err := Load(writeFile(t, name, content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p, err := f.Resolve("docs", Profile{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			opts, err := p.Options()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if opts.Depth != 1 || opts.RateLimit != 2 || opts.Layout != urlutils.LayoutFlat {
+				t.Errorf("unexpected options: %+v", opts)
+			}
+			if opts.Headers["X-Token"] != "abc" {
+				t.Errorf("expected header from defaults, got %v", opts.Headers)
+			}
+			// Незаданные поля берутся по умолчанию
+			if opts.Concurrency != 5 || opts.Timeout != 30*time.Second {
+				t.Errorf("expected default concurrency and timeout, got %d and %s", opts.Concurrency, opts.Timeout)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(writeFile(t, "sites.ini", "")); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+	if _, err := Load(writeFile(t, "sites.json", `{"profiles": {}}`)); err == nil {
+		t.Error("expected error for config without profiles")
+	}
+
+	f, err := Load(writeFile(t, "sites.json", `{"profiles": {"a": {"seeds": ["http://a"]}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.Resolve("b", Profile{}); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestMergeOverrides(t *testing.T) {
+	zero, two, three := 0, 2, 3
+	rate, noRate := 5.0, 0.0
+	defaults := Profile{Depth: &two, Concurrency: &three, RateLimit: &rate, Headers: map[string]string{"A": "1"}}
+	profile := Profile{Seeds: []string{"http://a"}, Headers: map[string]string{"B": "2"}}
+	overrides := Profile{Depth: &zero, RateLimit: &noRate, Headers: map[string]string{"A": "cli"}}
+
+	got := Merge(defaults, profile, overrides)
+
+	if got.Depth == nil || *got.Depth != 0 {
+		t.Errorf("expected explicit depth 0 to override, got %v", got.Depth)
+	}
+	if got.Concurrency == nil || *got.Concurrency != 3 {
+		t.Errorf("expected concurrency from defaults, got %v", got.Concurrency)
+	}
+	// Явный 0 (например, -rate 0) снимает ограничение частоты, заданное в общих значениях
+	if got.RateLimit == nil || *got.RateLimit != 0 {
+		t.Errorf("expected explicit rate limit 0 to override, got %v", got.RateLimit)
+	}
+	if opts, err := got.Options(); err != nil || opts.RateLimit != 0 {
+		t.Errorf("expected no rate limit in options, got %v, %v", opts.RateLimit, err)
+	}
+	if _, err := Merge(defaults, profile, Profile{Concurrency: &zero}).Options(); err == nil {
+		t.Error("expected explicit concurrency 0 to be rejected rather than ignored")
+	}
+	if got.Headers["A"] != "cli" || got.Headers["B"] != "2" {
+		t.Errorf("unexpected merged headers: %v", got.Headers)
+	}
+	// Слияние не должно менять исходные профили
+	if defaults.Headers["A"] != "1" {
+		t.Errorf("defaults were modified: %v", defaults.Headers)
+	}
+}
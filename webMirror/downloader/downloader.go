@@ -8,7 +8,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"WBTechL2/webMirror/progress"
 )
 
 // Downloader управляет загрузкой ресурсов
@@ -18,6 +21,11 @@ type Downloader struct {
 	timeout     time.Duration
 	concurrency int
 	semaphore   chan struct{}
+	stats       *progress.Stats
+	headers     map[string]string
+	interval    time.Duration // минимальный интервал между запросами, 0 — без ограничения
+	rateMu      sync.Mutex
+	nextRequest time.Time
 }
 
 // NewDownloader создает новый загрузчик
@@ -41,6 +49,55 @@ func NewDownloader(timeout time.Duration, concurrency int) *Downloader {
 	return d
 }
 
+// SetStats подключает счетчики прогресса: загрузка считается начатой,
+// когда она получает слот семафора
+func (d *Downloader) SetStats(stats *progress.Stats) {
+	d.stats = stats
+}
+
+// SetUserAgent задает заголовок User-Agent для всех запросов
+func (d *Downloader) SetUserAgent(userAgent string) {
+	d.userAgent = userAgent
+}
+
+// SetHeaders задает дополнительные заголовки, отправляемые с каждым запросом
+func (d *Downloader) SetHeaders(headers map[string]string) {
+	d.headers = headers
+}
+
+// SetRateLimit ограничивает частоту запросов (запросов в секунду), 0 снимает ограничение
+func (d *Downloader) SetRateLimit(perSecond float64) {
+	if perSecond <= 0 {
+		d.interval = 0
+		return
+	}
+	d.interval = time.Duration(float64(time.Second) / perSecond)
+}
+
+// waitRate блокируется, пока ограничение частоты не разрешит следующий запрос
+func (d *Downloader) waitRate() {
+	if d.interval == 0 {
+		return
+	}
+	d.rateMu.Lock()
+	now := time.Now()
+	wait := d.nextRequest.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	d.nextRequest = now.Add(wait + d.interval)
+	d.rateMu.Unlock()
+	time.Sleep(wait)
+}
+
+// setHeaders проставляет в запрос общие заголовки загрузчика
+func (d *Downloader) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", d.userAgent)
+	for k, v := range d.headers {
+		req.Header.Set(k, v)
+	}
+}
+
 // DownloadFile загружает файл по URL и сохраняет его локально
 func (d *Downloader) DownloadFile(targetURL *url.URL, localPath string) error {
 	// Получаем семафор для ограничения параллельности
@@ -59,8 +116,9 @@ func (d *Downloader) DownloadFile(targetURL *url.URL, localPath string) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("User-Agent", d.userAgent)
 	req.Header.Set("Accept", "*/*")
+	d.setHeaders(req)
+	d.waitRate()
 
 	// Выполняем запрос
 	resp, err := d.client.Do(req)
@@ -97,7 +155,7 @@ func (d *Downloader) GetContentType(targetURL *url.URL) (string, error) {
 		return "", err
 	}
 
-	req.Header.Set("User-Agent", d.userAgent)
+	d.setHeaders(req)
 
 	resp, err := d.client.Do(req)
 	if err != nil {
@@ -118,18 +176,35 @@ func (d *Downloader) GetContentType(targetURL *url.URL) (string, error) {
 	return strings.TrimSpace(contentType), nil
 }
 
-// FetchContent загружает содержимое ресурса в память
+// FetchContent загружает содержимое ресурса в память и отмечает начало загрузки в счетчиках
 func (d *Downloader) FetchContent(targetURL *url.URL) ([]byte, string, error) {
+	return d.fetch(targetURL, true)
+}
+
+// FetchAux загружает служебный ресурс (robots.txt, sitemap) в память, не трогая счетчики:
+// такие загрузки не ставятся в очередь и не должны менять число загружаемых URL
+func (d *Downloader) FetchAux(targetURL *url.URL) ([]byte, error) {
+	content, _, err := d.fetch(targetURL, false)
+	return content, err
+}
+
+// fetch загружает ресурс с учетом ограничения параллельности и частоты запросов;
+// counted — переводить ли URL из очереди в загружаемые
+func (d *Downloader) fetch(targetURL *url.URL, counted bool) ([]byte, string, error) {
 	d.semaphore <- struct{}{}
 	defer func() { <-d.semaphore }()
+	if counted && d.stats != nil {
+		d.stats.Start()
+	}
 
 	req, err := http.NewRequest("GET", targetURL.String(), nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("User-Agent", d.userAgent)
 	req.Header.Set("Accept", "*/*")
+	d.setHeaders(req)
+	d.waitRate()
 
 	resp, err := d.client.Do(req)
 	if err != nil {
@@ -165,7 +240,7 @@ func (d *Downloader) CheckRobotsTxt(baseURL *url.URL) (bool, error) {
 		return true, nil // Если не можем проверить, разрешаем
 	}
 
-	req.Header.Set("User-Agent", d.userAgent)
+	d.setHeaders(req)
 
 	resp, err := d.client.Do(req)
 	if err != nil {
@@ -2,4 +2,8 @@ module WBTechL2/webMirror
 
 go 1.24.1
 
-require golang.org/x/net v0.32.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/net v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
+)
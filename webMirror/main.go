@@ -3,11 +3,27 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 
+	"WBTechL2/webMirror/config"
 	"WBTechL2/webMirror/mirror"
+	"WBTechL2/webMirror/progress"
 )
 
+// listFlag — флаг, который можно указывать несколько раз
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func main() {
 	// Парсим флаги
 	var (
@@ -17,7 +33,23 @@ func main() {
 		timeoutFlag = flag.Int("timeout", 30, "Таймаут для HTTP запросов в секундах")
 		concFlag    = flag.Int("concurrency", 5, "Количество одновременных загрузок")
 		_           = flag.Bool("robots", false, "Проверять robots.txt (опционально) - функциональность пока не реализована")
+		quietFlag   = flag.Bool("quiet", false, "Не выводить прогресс и лог загрузок, только итог и ошибки")
+		verboseFlag = flag.Bool("verbose", false, "Выводить строку лога для каждой загрузки")
+		metricsFlag = flag.String("metrics-addr", "", "Адрес для HTTP эндпоинта /metrics в формате Prometheus (например, :9100)")
+		configFlag  = flag.String("config", "", "Конфигурационный файл с профилями сайтов (.json, .yaml, .yml, .toml)")
+		profileFlag = flag.String("profile", "", "Профили из конфигурации через запятую (по умолчанию все)")
+		uaFlag      = flag.String("user-agent", "", "Заголовок User-Agent")
+		rateFlag    = flag.Float64("rate", 0, "Ограничение частоты запросов в секунду (0 — без ограничения)")
+		layoutFlag  = flag.String("layout", "host", "Раскладка файлов: host (<output>/<host>/<path>) или flat (<output>/<path>)")
+		sitemapFlag = flag.Bool("sitemaps", false, "Искать страницы в sitemap (директивы Sitemap: в robots.txt или /sitemap.xml)")
+		feedsFlag   = flag.Bool("feeds", false, "Загружать записи RSS/Atom лент, найденных при обходе")
+		includeFlag listFlag
+		excludeFlag listFlag
+		headerFlag  listFlag
 	)
+	flag.Var(&includeFlag, "include", "Загружать только URL, совпадающие с регулярным выражением (можно указать несколько раз)")
+	flag.Var(&excludeFlag, "exclude", "Не загружать URL, совпадающие с регулярным выражением (можно указать несколько раз)")
+	flag.Var(&headerFlag, "header", "Дополнительный заголовок запроса в формате 'Name: value' (можно указать несколько раз)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Использование: %s [OPTIONS]\n\n", os.Args[0])
@@ -27,13 +59,16 @@ func main() {
 		fmt.Fprintf(os.Stderr, "\nПримеры:\n")
 		fmt.Fprintf(os.Stderr, "  %s -url https://example.com -output ./example_mirror -depth 2\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -url http://localhost:8080 -depth 5 -concurrency 10\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -url https://example.com -quiet -metrics-addr :9100\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -config sites.yaml -profile docs,blog -depth 1\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -url https://example.com -sitemaps -feeds -depth 1\n", os.Args[0])
 	}
 
 	flag.Parse()
 
 	// Проверяем обязательный параметр URL
-	if *urlFlag == "" {
-		fmt.Fprintf(os.Stderr, "Ошибка: необходимо указать URL с помощью флага -url\n\n")
+	if *urlFlag == "" && *configFlag == "" {
+		fmt.Fprintf(os.Stderr, "Ошибка: необходимо указать URL с помощью флага -url или конфигурацию с помощью флага -config\n\n")
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -54,19 +89,149 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *quietFlag && *verboseFlag {
+		fmt.Fprintf(os.Stderr, "Ошибка: флаги -quiet и -verbose несовместимы\n")
+		os.Exit(1)
+	}
+
+	// Явно заданные флаги перекрывают значения из конфигурации
+	var overrides config.Profile
+	var flagErr error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			overrides.Seeds = []string{*urlFlag}
+		case "output":
+			overrides.Output = *outputFlag
+		case "depth":
+			overrides.Depth = depthFlag
+		case "timeout":
+			overrides.Timeout = timeoutFlag
+		case "concurrency":
+			overrides.Concurrency = concFlag
+		case "user-agent":
+			overrides.UserAgent = *uaFlag
+		case "rate":
+			overrides.RateLimit = rateFlag
+		case "layout":
+			overrides.Layout = *layoutFlag
+		case "sitemaps":
+			overrides.Sitemaps = sitemapFlag
+		case "feeds":
+			overrides.Feeds = feedsFlag
+		case "include":
+			overrides.Include = includeFlag
+		case "exclude":
+			overrides.Exclude = excludeFlag
+		case "header":
+			overrides.Headers, flagErr = parseHeaders(headerFlag)
+		}
+	})
+	if flagErr != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", flagErr)
+		os.Exit(1)
+	}
+
+	// Собираем профили для запуска
+	type run struct {
+		name string
+		opts mirror.Options
+	}
+	var runs []run
+	if *configFlag == "" {
+		opts, err := config.Merge(overrides).Options()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			os.Exit(1)
+		}
+		runs = append(runs, run{opts: opts})
+	} else {
+		file, err := config.Load(*configFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка при загрузке конфигурации: %v\n", err)
+			os.Exit(1)
+		}
+		names := file.Names()
+		if *profileFlag != "" {
+			names = strings.Split(*profileFlag, ",")
+		}
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			p, err := file.Resolve(name, overrides)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+				os.Exit(1)
+			}
+			opts, err := p.Options()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Ошибка в профиле %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			runs = append(runs, run{name: name, opts: opts})
+		}
+	}
+
+	// Счетчики общие для всех профилей, чтобы метрики не сбрасывались между запусками;
+	// итоговая сводка каждого профиля считается по приросту счетчиков за его запуск
+	stats := progress.NewStats()
+
+	// Поднимаем эндпоинт метрик, если он запрошен
+	if *metricsFlag != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", stats.Handler())
+		go func() {
+			if err := http.ListenAndServe(*metricsFlag, mux); err != nil {
+				fmt.Fprintf(os.Stderr, "Ошибка эндпоинта метрик: %v\n", err)
+			}
+		}()
+	}
+
+	for _, r := range runs {
+		if r.name != "" && !*quietFlag {
+			fmt.Printf("\n=== Profile %s ===\n", r.name)
+		}
+		r.opts.Stats = stats
+		mirrorSite(r.opts, *quietFlag, *verboseFlag)
+	}
+}
+
+// mirrorSite выполняет одно зеркалирование и завершает программу при ошибке
+func mirrorSite(opts mirror.Options, quiet, verbose bool) {
 	// Создаем экземпляр зеркалирования
-	m, err := mirror.NewMirror(*urlFlag, *outputFlag, *depthFlag, *timeoutFlag, *concFlag)
+	m, err := mirror.NewMirrorWithOptions(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Ошибка при создании зеркала: %v\n", err)
 		os.Exit(1)
 	}
 
+	switch {
+	case quiet:
+		m.SetLogLevel(mirror.LogQuiet)
+	case verbose:
+		m.SetLogLevel(mirror.LogVerbose)
+	}
+
 	// Запускаем зеркалирование
 	if err := m.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Ошибка при зеркалировании: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("\nЗеркалирование завершено успешно!")
-	fmt.Printf("Результаты сохранены в: %s\n", *outputFlag)
+	if !quiet {
+		fmt.Println("\nЗеркалирование завершено успешно!")
+		fmt.Printf("Результаты сохранены в: %s\n", opts.Output)
+	}
+}
+
+// parseHeaders разбирает заголовки вида "Name: value"
+func parseHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected 'Name: value'", v)
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
 }
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -12,13 +13,36 @@ import (
 	"WBTechL2/webMirror/cssparser"
 	"WBTechL2/webMirror/downloader"
 	"WBTechL2/webMirror/htmlparser"
+	"WBTechL2/webMirror/progress"
+	"WBTechL2/webMirror/sitemap"
 	"WBTechL2/webMirror/urlutils"
 )
 
+// maxSitemapNesting ограничивает глубину вложенности индексов sitemap
+const maxSitemapNesting = 3
+
+// LogLevel задает подробность вывода в процессе зеркалирования
+type LogLevel int
+
+const (
+	// LogQuiet выводит только итоговую сводку и ошибки
+	LogQuiet LogLevel = iota
+	// LogNormal рисует строку прогресса в терминале, а вне терминала пишет построчный лог загрузок
+	LogNormal
+	// LogVerbose всегда пишет построчный лог загрузок
+	LogVerbose
+)
+
 // Mirror управляет процессом зеркалирования сайта
 type Mirror struct {
 	baseURL        *url.URL
+	seeds          []*url.URL
 	basePath       string
+	layout         urlutils.Layout
+	include        []*regexp.Regexp
+	exclude        []*regexp.Regexp
+	sitemaps       bool
+	feeds          bool
 	maxDepth       int
 	downloader     *downloader.Downloader
 	visitedURLs    map[string]bool
@@ -29,59 +53,154 @@ type Mirror struct {
 	wg             sync.WaitGroup
 	errors         []error
 	errMu          sync.Mutex
+	stats          *progress.Stats
+	reporter       *progress.Reporter
+	logLevel       LogLevel
 }
 
 // NewMirror создает новый экземпляр зеркалирования
 func NewMirror(startURL string, outputPath string, maxDepth int, timeout int, concurrency int) (*Mirror, error) {
-	baseURL, err := url.Parse(startURL)
-	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
-	}
+	opts := DefaultOptions()
+	opts.Seeds = []string{startURL}
+	opts.Output = outputPath
+	opts.Depth = maxDepth
+	opts.Timeout = time.Duration(timeout) * time.Second
+	opts.Concurrency = concurrency
+	return NewMirrorWithOptions(opts)
+}
 
-	// Нормализуем URL
-	if baseURL.Scheme == "" {
-		baseURL.Scheme = "http"
+// NewMirrorWithOptions создает экземпляр зеркалирования по набору параметров
+func NewMirrorWithOptions(opts Options) (*Mirror, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
 	}
-	if baseURL.Path == "" {
-		baseURL.Path = "/"
+
+	seeds := make([]*url.URL, 0, len(opts.Seeds))
+	for _, seed := range opts.Seeds {
+		seedURL, err := url.Parse(seed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL: %w", err)
+		}
+
+		// Нормализуем URL
+		if seedURL.Scheme == "" {
+			seedURL.Scheme = "http"
+		}
+		if seedURL.Path == "" {
+			seedURL.Path = "/"
+		}
+		seeds = append(seeds, seedURL)
 	}
 
 	// Создаем директорию для вывода
-	if err := os.MkdirAll(outputPath, 0755); err != nil {
+	if err := os.MkdirAll(opts.Output, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	layout, _ := urlutils.ParseLayout(string(opts.Layout))
+	include, _ := compilePatterns(opts.Include)
+	exclude, _ := compilePatterns(opts.Exclude)
+
+	stats := opts.Stats
+	if stats == nil {
+		stats = progress.NewStats()
+	}
+	d := downloader.NewDownloader(opts.Timeout, opts.Concurrency)
+	d.SetStats(stats)
+	d.SetHeaders(opts.Headers)
+	d.SetRateLimit(opts.RateLimit)
+	if opts.UserAgent != "" {
+		d.SetUserAgent(opts.UserAgent)
+	}
+
 	m := &Mirror{
-		baseURL:        baseURL,
-		basePath:       outputPath,
-		maxDepth:       maxDepth,
-		downloader:     downloader.NewDownloader(time.Duration(timeout)*time.Second, concurrency),
+		baseURL:        seeds[0],
+		seeds:          seeds,
+		basePath:       opts.Output,
+		layout:         layout,
+		include:        include,
+		exclude:        exclude,
+		sitemaps:       opts.Sitemaps,
+		feeds:          opts.Feeds,
+		maxDepth:       opts.Depth,
+		downloader:     d,
 		visitedURLs:    make(map[string]bool),
 		urlToLocalPath: make(map[string]string),
 		htmlFiles:      make(map[string]string),
 		cssFiles:       make(map[string]string),
 		errors:         make([]error, 0),
+		stats:          stats,
+		reporter:       progress.NewReporter(stats, os.Stdout, 500*time.Millisecond),
+		logLevel:       LogNormal,
 	}
 
 	return m, nil
 }
 
+// SetLogLevel задает подробность вывода
+func (m *Mirror) SetLogLevel(level LogLevel) {
+	m.logLevel = level
+}
+
+// Stats возвращает счетчики прогресса, например для публикации метрик
+func (m *Mirror) Stats() *progress.Stats {
+	return m.stats
+}
+
+// logf выводит служебное сообщение, если вывод не подавлен
+func (m *Mirror) logf(format string, args ...any) {
+	if m.logLevel == LogQuiet {
+		return
+	}
+	m.reporter.Printf(format, args...)
+}
+
+// logURLf выводит построчный лог загрузок: всегда в подробном режиме,
+// а в обычном — только если живая строка прогресса недоступна
+func (m *Mirror) logURLf(format string, args ...any) {
+	if m.logLevel == LogVerbose || (m.logLevel == LogNormal && !m.reporter.Live()) {
+		m.reporter.Printf(format, args...)
+	}
+}
+
 // Start начинает процесс зеркалирования
 func (m *Mirror) Start() error {
-	fmt.Printf("Starting mirror of %s\n", m.baseURL.String())
-	fmt.Printf("Output directory: %s\n", m.basePath)
-	fmt.Printf("Max depth: %d\n", m.maxDepth)
+	for _, seed := range m.seeds {
+		m.logf("Starting mirror of %s\n", seed.String())
+	}
+	m.logf("Output directory: %s\n", m.basePath)
+	m.logf("Max depth: %d\n", m.maxDepth)
+
+	// Счетчики могут быть общими для нескольких запусков, сводка показывает только этот
+	before := m.stats.Snapshot()
+	if m.logLevel != LogQuiet {
+		m.reporter.Start()
+	}
 
-	// Начинаем с корневого URL
-	m.wg.Add(1)
-	go m.processURL(m.baseURL, 0, "")
+	// Начинаем со стартовых URL
+	for _, seed := range m.seeds {
+		m.wg.Add(1)
+		go m.processURL(seed, 0, "")
+	}
+
+	// Дополнительно ищем страницы в sitemap
+	if m.sitemaps {
+		m.wg.Add(1)
+		go m.discoverSitemaps()
+	}
 
 	m.wg.Wait()
+	m.reporter.Stop()
 
 	// Финальный проход: обновляем все HTML и CSS файлы с правильными ссылками
-	fmt.Println("\nUpdating links in HTML and CSS files...")
+	m.logf("\nUpdating links in HTML and CSS files...\n")
 	m.updateAllLinks()
 
+	// Итоговая сводка выводится и в тихом режиме
+	sn := m.stats.Snapshot().Sub(before)
+	m.reporter.Printf("Downloaded %d URLs (%s), failed %d, in %s\n",
+		sn.Done, progress.FormatBytes(sn.Bytes), sn.Failed, sn.Elapsed.Round(time.Millisecond))
+
 	// Выводим ошибки если есть
 	if len(m.errors) > 0 {
 		fmt.Printf("\nCompleted with %d errors:\n", len(m.errors))
@@ -119,7 +238,12 @@ func (m *Mirror) processURL(targetURL *url.URL, depth int, referrer string) {
 	m.mu.RUnlock()
 
 	// Проверяем, тот же ли это домен
-	if !urlutils.IsSameDomain(normalizedURL, m.baseURL) {
+	if !m.inScope(normalizedURL) {
+		return
+	}
+
+	// Стартовые URL загружаются всегда, остальные — с учетом фильтров
+	if referrer != "" && !m.allowed(normalizedURL) {
 		return
 	}
 
@@ -127,12 +251,14 @@ func (m *Mirror) processURL(targetURL *url.URL, depth int, referrer string) {
 	m.mu.Lock()
 	m.visitedURLs[normalizedStr] = true
 	m.mu.Unlock()
+	m.stats.Enqueue()
 
-	fmt.Printf("[%d] Downloading: %s\n", depth, normalizedURL.String())
+	m.logURLf("[%d] Downloading: %s\n", depth, normalizedURL.String())
 
 	// Загружаем содержимое
 	content, contentType, err := m.downloader.FetchContent(normalizedURL)
 	if err != nil {
+		m.stats.Fail()
 		m.addError(fmt.Errorf("failed to download %s: %w", normalizedURL.String(), err))
 		return
 	}
@@ -141,22 +267,25 @@ func (m *Mirror) processURL(targetURL *url.URL, depth int, referrer string) {
 	var localPath string
 	if urlutils.IsResourceURL(normalizedURL) || !isHTMLContent(contentType) {
 		// Это ресурс (CSS, JS, изображение)
-		localPath = urlutils.URLToResourcePath(normalizedURL, m.basePath)
+		localPath = urlutils.URLToResourcePath(normalizedURL, m.basePath, m.layout)
 	} else {
 		// Это HTML страница
-		localPath = urlutils.URLToLocalPath(normalizedURL, m.basePath)
+		localPath = urlutils.URLToLocalPath(normalizedURL, m.basePath, m.layout)
 	}
 
 	// Сохраняем файл
 	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		m.stats.Fail()
 		m.addError(fmt.Errorf("failed to create directory for %s: %w", localPath, err))
 		return
 	}
 
 	if err := os.WriteFile(localPath, content, 0644); err != nil {
+		m.stats.Fail()
 		m.addError(fmt.Errorf("failed to save file %s: %w", localPath, err))
 		return
 	}
+	m.stats.Done(len(content))
 
 	// Сохраняем маппинг URL -> локальный путь
 	relativePath := urlutils.LocalPathToURL(localPath, m.basePath, m.baseURL)
@@ -182,7 +311,7 @@ func (m *Mirror) processURL(targetURL *url.URL, depth int, referrer string) {
 			}
 
 			// Проверяем, тот же ли домен
-			if !urlutils.IsSameDomain(linkURL, m.baseURL) {
+			if !m.inScope(linkURL) {
 				continue
 			}
 
@@ -211,7 +340,7 @@ func (m *Mirror) processURL(targetURL *url.URL, depth int, referrer string) {
 		fullURLMap := make(map[string]string)
 		for _, link := range links {
 			linkURL, err := urlutils.NormalizeURL(link.URL, normalizedURL)
-			if err == nil && urlutils.IsSameDomain(linkURL, m.baseURL) {
+			if err == nil && m.inScope(linkURL) {
 				linkStr := linkURL.String()
 				if localPath, ok := m.urlToLocalPath[linkStr]; ok {
 					// Используем оригинальный URL из ссылки для маппинга
@@ -253,7 +382,7 @@ func (m *Mirror) processURL(targetURL *url.URL, depth int, referrer string) {
 				continue
 			}
 
-			if !urlutils.IsSameDomain(linkURL, m.baseURL) {
+			if !m.inScope(linkURL) {
 				continue
 			}
 
@@ -287,7 +416,134 @@ func (m *Mirror) processURL(targetURL *url.URL, depth int, referrer string) {
 		m.mu.Lock()
 		m.cssFiles[normalizedStr] = localPath
 		m.mu.Unlock()
+	} else if m.feeds && sitemap.IsFeedContentType(contentType) {
+		// Если это RSS/Atom лента, ставим в очередь ее записи
+		entries, err := sitemap.ParseFeed(content)
+		if err != nil {
+			m.addError(fmt.Errorf("failed to parse feed from %s: %w", normalizedURL.String(), err))
+			return
+		}
+		for _, entry := range entries {
+			entryURL, err := urlutils.NormalizeURL(entry, normalizedURL)
+			if err != nil || !m.inScope(entryURL) {
+				continue
+			}
+			m.wg.Add(1)
+			go m.processURL(entryURL, depth+1, normalizedStr)
+		}
+	}
+}
+
+// discoverSitemaps находит sitemap через директивы Sitemap: в robots.txt
+// (или берет /sitemap.xml, если их нет) и ставит страницы из них в очередь с глубиной 0
+func (m *Mirror) discoverSitemaps() {
+	defer m.wg.Done()
+
+	type pending struct {
+		u        *url.URL
+		explicit bool // адрес указан явно, ошибка его загрузки попадет в отчет
+	}
+
+	var queue []pending
+	hosts := make(map[string]bool)
+	for _, seed := range m.seeds {
+		root := &url.URL{Scheme: seed.Scheme, Host: seed.Host, Path: "/"}
+		if hosts[root.String()] {
+			continue
+		}
+		hosts[root.String()] = true
+
+		var found []string
+		if content, err := m.fetchAux(root.ResolveReference(&url.URL{Path: "/robots.txt"})); err == nil {
+			found, _ = sitemap.ParseRobots(strings.NewReader(string(content)))
+		}
+		for _, s := range found {
+			if u, err := url.Parse(s); err == nil {
+				queue = append(queue, pending{u: root.ResolveReference(u), explicit: true})
+			}
+		}
+		if len(found) == 0 {
+			queue = append(queue, pending{u: root.ResolveReference(&url.URL{Path: "/sitemap.xml"})})
+		}
+	}
+
+	seen := make(map[string]bool)
+	for level := 0; len(queue) > 0 && level <= maxSitemapNesting; level++ {
+		var next []pending
+		for _, p := range queue {
+			smStr := p.u.String()
+			if seen[smStr] {
+				continue
+			}
+			seen[smStr] = true
+
+			content, err := m.fetchAux(p.u)
+			if err != nil {
+				if p.explicit {
+					m.addError(fmt.Errorf("failed to download sitemap %s: %w", smStr, err))
+				}
+				continue
+			}
+			sm, err := sitemap.Parse(content)
+			if err != nil {
+				if p.explicit {
+					m.addError(fmt.Errorf("failed to parse sitemap %s: %w", smStr, err))
+				}
+				continue
+			}
+			m.logURLf("[sitemap] %s: %d pages, %d sitemaps\n", smStr, len(sm.Pages), len(sm.Sitemaps))
+
+			for _, s := range sm.Sitemaps {
+				if u, err := urlutils.NormalizeURL(s, p.u); err == nil {
+					next = append(next, pending{u: u, explicit: true})
+				}
+			}
+			for _, page := range sm.Pages {
+				pageURL, err := urlutils.NormalizeURL(page, p.u)
+				if err != nil || !m.inScope(pageURL) {
+					continue
+				}
+				m.wg.Add(1)
+				go m.processURL(pageURL, 0, smStr)
+			}
+		}
+		queue = next
+	}
+}
+
+// fetchAux загружает служебный ресурс (robots.txt, sitemap), не сохраняя его в зеркало.
+// В статистику страниц такие загрузки не попадают: отсутствующий sitemap.xml — не ошибка зеркалирования.
+func (m *Mirror) fetchAux(u *url.URL) ([]byte, error) {
+	return m.downloader.FetchAux(u)
+}
+
+// inScope проверяет, относится ли URL к домену одного из стартовых URL
+func (m *Mirror) inScope(u *url.URL) bool {
+	for _, seed := range m.seeds {
+		if urlutils.IsSameDomain(u, seed) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed применяет к URL фильтры include/exclude
+func (m *Mirror) allowed(u *url.URL) bool {
+	s := u.String()
+	for _, re := range m.exclude {
+		if re.MatchString(s) {
+			return false
+		}
+	}
+	if len(m.include) == 0 {
+		return true
+	}
+	for _, re := range m.include {
+		if re.MatchString(s) {
+			return true
+		}
 	}
+	return false
 }
 
 // addError добавляет ошибку в список
@@ -351,7 +607,7 @@ func (m *Mirror) updateAllLinks() {
 				continue
 			}
 
-			if !urlutils.IsSameDomain(linkURL, m.baseURL) {
+			if !m.inScope(linkURL) {
 				continue
 			}
 
@@ -397,7 +653,7 @@ func (m *Mirror) updateAllLinks() {
 				continue
 			}
 
-			if !urlutils.IsSameDomain(linkURL, m.baseURL) {
+			if !m.inScope(linkURL) {
 				continue
 			}
 
@@ -0,0 +1,52 @@
+package mirror
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSitemapFetchesKeepStatsBalanced(t *testing.T) {
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "User-agent: *\nSitemap: %s/sitemap.xml\nSitemap: %s/missing.xml\n", server.URL, server.URL)
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>%s/about.html</loc></url></urlset>`, server.URL)
+	})
+	mux.HandleFunc("/about.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>About</body></html>")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>Home</body></html>")
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	opts := DefaultOptions()
+	opts.Seeds = []string{server.URL + "/"}
+	opts.Output = t.TempDir()
+	opts.Sitemaps = true
+	m, err := NewMirrorWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetLogLevel(LogQuiet)
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// robots.txt и sitemap не попадают в счетчики страниц, и очередь после обхода пуста
+	sn := m.Stats().Snapshot()
+	if sn.Queued != 0 || sn.InFlight != 0 || sn.Done != 2 || sn.Failed != 0 {
+		t.Errorf("unexpected snapshot after the crawl: %+v", sn)
+	}
+}
@@ -0,0 +1,82 @@
+package mirror
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"WBTechL2/webMirror/progress"
+	"WBTechL2/webMirror/urlutils"
+)
+
+// Options описывает параметры одного зеркалирования
+type Options struct {
+	Seeds       []string          // стартовые URL, первый из них считается базовым
+	Output      string            // директория для сохранения зеркала
+	Depth       int               // максимальная глубина рекурсии
+	Timeout     time.Duration     // таймаут HTTP запросов
+	Concurrency int               // количество одновременных загрузок
+	Include     []string          // регулярные выражения: если заданы, загружаются только совпавшие URL
+	Exclude     []string          // регулярные выражения: совпавшие URL не загружаются
+	Headers     map[string]string // дополнительные заголовки запросов
+	UserAgent   string            // заголовок User-Agent, пустая строка — значение по умолчанию
+	RateLimit   float64           // запросов в секунду, 0 — без ограничения
+	Layout      urlutils.Layout   // раскладка файлов в директории вывода
+	Sitemaps    bool              // искать страницы в sitemap из robots.txt (или /sitemap.xml)
+	Feeds       bool              // ставить в очередь записи найденных RSS/Atom лент
+	Stats       *progress.Stats   // общие счетчики прогресса, nil — создать новые
+}
+
+// DefaultOptions возвращает параметры по умолчанию, совпадающие со значениями флагов CLI
+func DefaultOptions() Options {
+	return Options{
+		Output:      "./mirror",
+		Depth:       3,
+		Timeout:     30 * time.Second,
+		Concurrency: 5,
+		Layout:      urlutils.LayoutHost,
+	}
+}
+
+// Validate проверяет корректность параметров
+func (o Options) Validate() error {
+	if len(o.Seeds) == 0 {
+		return errors.New("at least one seed URL is required")
+	}
+	if o.Depth < 0 {
+		return errors.New("depth must be >= 0")
+	}
+	if o.Timeout <= 0 {
+		return errors.New("timeout must be > 0")
+	}
+	if o.Concurrency <= 0 {
+		return errors.New("concurrency must be > 0")
+	}
+	if o.RateLimit < 0 {
+		return errors.New("rate limit must be >= 0")
+	}
+	if _, err := urlutils.ParseLayout(string(o.Layout)); err != nil {
+		return err
+	}
+	if _, err := compilePatterns(o.Include); err != nil {
+		return fmt.Errorf("invalid include pattern: %w", err)
+	}
+	if _, err := compilePatterns(o.Exclude); err != nil {
+		return fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	return nil
+}
+
+// compilePatterns компилирует список регулярных выражений
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
@@ -0,0 +1,276 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats хранит счетчики процесса зеркалирования.
+// Все методы безопасны для конкурентного использования.
+type Stats struct {
+	queued   atomic.Int64
+	inFlight atomic.Int64
+	done     atomic.Int64
+	failed   atomic.Int64
+	bytes    atomic.Int64
+	started  time.Time
+}
+
+// Snapshot представляет согласованный срез счетчиков на момент вызова
+type Snapshot struct {
+	Queued   int64
+	InFlight int64
+	Done     int64
+	Failed   int64
+	Bytes    int64
+	Elapsed  time.Duration
+}
+
+// NewStats создает новый набор счетчиков, отсчет времени начинается с момента создания
+func NewStats() *Stats {
+	return &Stats{started: time.Now()}
+}
+
+// Enqueue отмечает URL, поставленный в очередь на загрузку
+func (s *Stats) Enqueue() {
+	s.queued.Add(1)
+}
+
+// Start переводит URL из очереди в число загружаемых
+func (s *Stats) Start() {
+	s.queued.Add(-1)
+	s.inFlight.Add(1)
+}
+
+// Done отмечает успешно обработанный URL и количество загруженных байт
+func (s *Stats) Done(n int) {
+	s.inFlight.Add(-1)
+	s.done.Add(1)
+	s.bytes.Add(int64(n))
+}
+
+// Fail отмечает URL, обработка которого завершилась ошибкой
+func (s *Stats) Fail() {
+	s.inFlight.Add(-1)
+	s.failed.Add(1)
+}
+
+// Snapshot возвращает текущие значения счетчиков
+func (s *Stats) Snapshot() Snapshot {
+	return Snapshot{
+		Queued:   s.queued.Load(),
+		InFlight: s.inFlight.Load(),
+		Done:     s.done.Load(),
+		Failed:   s.failed.Load(),
+		Bytes:    s.bytes.Load(),
+		Elapsed:  time.Since(s.started),
+	}
+}
+
+// Sub возвращает прирост счетчиков и времени с момента снимка before, например за один запуск
+// при счетчиках, общих для нескольких запусков. Queued и InFlight берутся текущие.
+func (sn Snapshot) Sub(before Snapshot) Snapshot {
+	sn.Done -= before.Done
+	sn.Failed -= before.Failed
+	sn.Bytes -= before.Bytes
+	sn.Elapsed -= before.Elapsed
+	return sn
+}
+
+// Throughput возвращает среднюю скорость загрузки в байтах в секунду
+func (sn Snapshot) Throughput() float64 {
+	secs := sn.Elapsed.Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(sn.Bytes) / secs
+}
+
+// ETA оценивает оставшееся время по средней скорости обработки URL.
+// Полный объем обхода заранее неизвестен, поэтому оценка учитывает
+// только уже найденные, но еще не обработанные URL.
+// Возвращает false, если оценку дать пока нельзя.
+func (sn Snapshot) ETA() (time.Duration, bool) {
+	finished := sn.Done + sn.Failed
+	remaining := sn.Queued + sn.InFlight
+	if finished == 0 || sn.Elapsed <= 0 {
+		return 0, false
+	}
+	perURL := sn.Elapsed / time.Duration(finished)
+	return perURL * time.Duration(remaining), true
+}
+
+// String форматирует срез счетчиков в одну строку для вывода в терминал
+func (sn Snapshot) String() string {
+	eta := "--"
+	if d, ok := sn.ETA(); ok {
+		eta = d.Round(time.Second).String()
+	}
+	return fmt.Sprintf("queued: %d  in-flight: %d  done: %d  failed: %d  %s  %s/s  elapsed: %s  ETA: %s",
+		sn.Queued, sn.InFlight, sn.Done, sn.Failed,
+		FormatBytes(sn.Bytes), FormatBytes(int64(sn.Throughput())),
+		sn.Elapsed.Round(time.Second), eta)
+}
+
+// FormatBytes форматирует размер в человекочитаемом виде
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// WritePrometheus выводит счетчики в текстовом формате Prometheus
+func (s *Stats) WritePrometheus(w io.Writer) error {
+	sn := s.Snapshot()
+	metrics := []struct {
+		name, help, kind string
+		value            float64
+	}{
+		{"webmirror_urls_queued", "URLs waiting to be downloaded.", "gauge", float64(sn.Queued)},
+		{"webmirror_urls_in_flight", "URLs currently being downloaded.", "gauge", float64(sn.InFlight)},
+		{"webmirror_urls_done_total", "URLs downloaded and saved successfully.", "counter", float64(sn.Done)},
+		{"webmirror_urls_failed_total", "URLs that failed to download or save.", "counter", float64(sn.Failed)},
+		{"webmirror_downloaded_bytes_total", "Bytes downloaded.", "counter", float64(sn.Bytes)},
+		{"webmirror_elapsed_seconds", "Seconds since the crawl started.", "gauge", sn.Elapsed.Seconds()},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler возвращает HTTP обработчик, отдающий метрики в формате Prometheus
+func (s *Stats) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.WritePrometheus(w)
+	})
+}
+
+// Reporter периодически перерисовывает строку прогресса в терминале
+type Reporter struct {
+	stats    *Stats
+	out      io.Writer
+	interval time.Duration
+	live     bool
+	mu       sync.Mutex
+	lastLen  int
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewReporter создает отображение прогресса.
+// Живая строка прогресса рисуется только если out — терминал.
+func NewReporter(stats *Stats, out io.Writer, interval time.Duration) *Reporter {
+	return &Reporter{
+		stats:    stats,
+		out:      out,
+		interval: interval,
+		live:     IsTerminal(out),
+	}
+}
+
+// Live сообщает, рисует ли Reporter живую строку прогресса
+func (r *Reporter) Live() bool {
+	return r.live
+}
+
+// Start запускает фоновую перерисовку строки прогресса
+func (r *Reporter) Start() {
+	if !r.live || r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.stopped = make(chan struct{})
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.redraw()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает перерисовку и оставляет в терминале финальную строку прогресса
+func (r *Reporter) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.stopped
+	r.stop = nil
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drawLocked()
+	fmt.Fprintln(r.out)
+	r.lastLen = 0
+}
+
+// Printf выводит строку лога, не ломая строку прогресса:
+// строка прогресса стирается, печатается сообщение, затем строка рисуется заново
+func (r *Reporter) Printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.live && r.lastLen > 0 {
+		r.clearLocked()
+	}
+	fmt.Fprintf(r.out, format, args...)
+	if r.live && r.stop != nil {
+		r.drawLocked()
+	}
+}
+
+func (r *Reporter) redraw() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drawLocked()
+}
+
+func (r *Reporter) drawLocked() {
+	line := r.stats.Snapshot().String()
+	fmt.Fprintf(r.out, "\r%s", line)
+	// Затираем хвост предыдущей, более длинной строки
+	if pad := r.lastLen - len(line); pad > 0 {
+		fmt.Fprintf(r.out, "%*s", pad, "")
+	}
+	r.lastLen = len(line)
+}
+
+func (r *Reporter) clearLocked() {
+	fmt.Fprintf(r.out, "\r%*s\r", r.lastLen, "")
+	r.lastLen = 0
+}
+
+// IsTerminal проверяет, является ли w терминалом
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,84 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsTransitions(t *testing.T) {
+	s := NewStats()
+	for i := 0; i < 3; i++ {
+		s.Enqueue()
+	}
+	s.Start()
+	s.Start()
+	s.Done(100)
+	s.Fail()
+
+	sn := s.Snapshot()
+	if sn.Queued != 1 || sn.InFlight != 0 || sn.Done != 1 || sn.Failed != 1 || sn.Bytes != 100 {
+		t.Fatalf("unexpected snapshot: %+v", sn)
+	}
+}
+
+func TestSnapshotSub(t *testing.T) {
+	before := Snapshot{Done: 5, Failed: 1, Bytes: 500, Elapsed: 10 * time.Second}
+	after := Snapshot{Queued: 2, Done: 8, Failed: 1, Bytes: 800, Elapsed: 15 * time.Second}
+	if d := after.Sub(before); d != (Snapshot{Queued: 2, Done: 3, Bytes: 300, Elapsed: 5 * time.Second}) {
+		t.Errorf("unexpected delta: %+v", d)
+	}
+}
+
+func TestSnapshotETA(t *testing.T) {
+	sn := Snapshot{Queued: 3, InFlight: 1, Done: 2, Elapsed: 10 * time.Second}
+	eta, ok := sn.ETA()
+	if !ok {
+		t.Fatal("expected ETA to be available")
+	}
+	if eta != 20*time.Second {
+		t.Errorf("expected ETA 20s, got %s", eta)
+	}
+
+	if _, ok := (Snapshot{Queued: 5}).ETA(); ok {
+		t.Error("expected no ETA before any URL finished")
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	s := NewStats()
+	s.Enqueue()
+	s.Start()
+	s.Done(2048)
+
+	var buf bytes.Buffer
+	if err := s.WritePrometheus(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE webmirror_urls_done_total counter\nwebmirror_urls_done_total 1\n",
+		"webmirror_downloaded_bytes_total 2048\n",
+		"webmirror_urls_queued 0\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:           "0 B",
+		1023:        "1023 B",
+		1536:        "1.5 KiB",
+		5 << 20:     "5.0 MiB",
+		3 << 30 / 2: "1.5 GiB",
+	}
+	for in, want := range tests {
+		if got := FormatBytes(in); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
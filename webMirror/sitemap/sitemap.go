@@ -0,0 +1,159 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxDecompressed ограничивает размер распакованного sitemap (по протоколу — не более 50 МБ)
+const maxDecompressed = 50 << 20
+
+// Sitemap — результат разбора sitemap файла
+type Sitemap struct {
+	Pages    []string // URL страниц из <urlset>
+	Sitemaps []string // URL вложенных sitemap из <sitemapindex>
+}
+
+// ParseRobots извлекает адреса sitemap из директив Sitemap: файла robots.txt
+func ParseRobots(r io.Reader) ([]string, error) {
+	var sitemaps []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Отбрасываем комментарии
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			sitemaps = append(sitemaps, value)
+		}
+	}
+	return sitemaps, scanner.Err()
+}
+
+// Parse разбирает sitemap (<urlset>) или индекс sitemap (<sitemapindex>).
+// Сжатые gzip файлы распознаются по сигнатуре и распаковываются автоматически.
+func Parse(data []byte) (*Sitemap, error) {
+	data, err := gunzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		XMLName  xml.Name
+		URLs     []string `xml:"url>loc"`
+		Sitemaps []string `xml:"sitemap>loc"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+
+	sm := &Sitemap{}
+	switch doc.XMLName.Local {
+	case "urlset":
+		sm.Pages = trimAll(doc.URLs)
+	case "sitemapindex":
+		sm.Sitemaps = trimAll(doc.Sitemaps)
+	default:
+		return nil, fmt.Errorf("unexpected sitemap root element <%s>", doc.XMLName.Local)
+	}
+	return sm, nil
+}
+
+// ParseFeed извлекает ссылки на записи из RSS 2.0, RSS 1.0 (RDF) или Atom ленты.
+// Для документов, которые не являются лентой, возвращает пустой список без ошибки.
+func ParseFeed(data []byte) ([]string, error) {
+	data, err := gunzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		XMLName xml.Name
+		// RSS 2.0: <rss><channel><item><link>
+		ChannelItems []string `xml:"channel>item>link"`
+		// RSS 1.0: <rdf:RDF><item><link>
+		Items []string `xml:"item>link"`
+		// Atom: <feed><entry><link href="..." rel="...">
+		Entries []struct {
+			Links []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	switch doc.XMLName.Local {
+	case "rss":
+		return trimAll(doc.ChannelItems), nil
+	case "RDF":
+		return trimAll(doc.Items), nil
+	case "feed":
+		var links []string
+		for _, entry := range doc.Entries {
+			for _, link := range entry.Links {
+				// Ссылка на саму запись имеет rel="alternate" или не имеет rel вовсе
+				if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+					links = append(links, strings.TrimSpace(link.Href))
+				}
+			}
+		}
+		return links, nil
+	default:
+		return nil, nil
+	}
+}
+
+// IsFeedContentType проверяет, может ли ресурс с данным Content-Type быть RSS/Atom лентой
+func IsFeedContentType(contentType string) bool {
+	switch contentType {
+	case "application/rss+xml", "application/atom+xml", "application/rdf+xml",
+		"application/xml", "text/xml":
+		return true
+	}
+	return false
+}
+
+// gunzip распаковывает данные, если они сжаты gzip, иначе возвращает их без изменений
+func gunzip(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(zr, maxDecompressed+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+	}
+	if len(out) > maxDecompressed {
+		return nil, fmt.Errorf("decompressed sitemap exceeds %d bytes", maxDecompressed)
+	}
+	return out, nil
+}
+
+// trimAll убирает пробелы вокруг URL и пропускает пустые значения
+func trimAll(values []string) []string {
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
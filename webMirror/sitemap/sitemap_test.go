@@ -0,0 +1,121 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `User-agent: *
+Disallow: /private
+# Sitemap: https://example.com/commented.xml
+Sitemap: https://example.com/sitemap.xml
+sitemap:https://example.com/news.xml.gz # раздел новостей
+`
+	got, err := ParseRobots(strings.NewReader(robots))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"https://example.com/sitemap.xml", "https://example.com/news.xml.gz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseURLSet(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://example.com/ </loc><lastmod>2024-01-01</lastmod></url>
+  <url><loc>https://example.com/about</loc></url>
+</urlset>`
+	sm, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"https://example.com/", "https://example.com/about"}
+	if !reflect.DeepEqual(sm.Pages, want) || len(sm.Sitemaps) != 0 {
+		t.Errorf("unexpected sitemap: %+v", sm)
+	}
+}
+
+func TestParseGzippedIndex(t *testing.T) {
+	data := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/pages.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/posts.xml.gz</loc></sitemap>
+</sitemapindex>`
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(data))
+	zw.Close()
+
+	sm, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"https://example.com/pages.xml", "https://example.com/posts.xml.gz"}
+	if !reflect.DeepEqual(sm.Sitemaps, want) || len(sm.Pages) != 0 {
+		t.Errorf("unexpected sitemap: %+v", sm)
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	if _, err := Parse([]byte(`<html><body></body></html>`)); err == nil {
+		t.Error("expected error for non-sitemap document")
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "rss 2.0",
+			data: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+  <link>https://example.com/</link>
+  <atom:link href="https://example.com/feed" rel="self"/>
+  <item><title>Первая</title><link>https://example.com/posts/1</link></item>
+  <item><link>/posts/2</link></item>
+</channel></rss>`,
+			want: []string{"https://example.com/posts/1", "/posts/2"},
+		},
+		{
+			name: "rss 1.0",
+			data: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+  <channel><link>https://example.com/</link></channel>
+  <item><link>https://example.com/a</link></item>
+</rdf:RDF>`,
+			want: []string{"https://example.com/a"},
+		},
+		{
+			name: "atom",
+			data: `<feed xmlns="http://www.w3.org/2005/Atom">
+  <link href="https://example.com/" rel="alternate"/>
+  <entry><link href="https://example.com/e1"/><link rel="edit" href="https://example.com/edit/1"/></entry>
+  <entry><link rel="alternate" href="https://example.com/e2"/></entry>
+</feed>`,
+			want: []string{"https://example.com/e1", "https://example.com/e2"},
+		},
+		{
+			name: "not a feed",
+			data: `<note><link>https://example.com/x</link></note>`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFeed([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
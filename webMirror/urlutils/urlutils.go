@@ -1,11 +1,42 @@
 package urlutils
 
 import (
+	"fmt"
 	"net/url"
 	"path/filepath"
 	"strings"
 )
 
+// Layout задает раскладку файлов зеркала в директории вывода
+type Layout string
+
+const (
+	// LayoutHost раскладывает файлы по поддиректориям хостов: <output>/<host>/<path>
+	LayoutHost Layout = "host"
+	// LayoutFlat кладет файлы прямо в директорию вывода: <output>/<path>
+	LayoutFlat Layout = "flat"
+)
+
+// ParseLayout разбирает название раскладки, пустая строка означает LayoutHost
+func ParseLayout(s string) (Layout, error) {
+	switch Layout(s) {
+	case "", LayoutHost:
+		return LayoutHost, nil
+	case LayoutFlat:
+		return LayoutFlat, nil
+	default:
+		return "", fmt.Errorf("unknown output layout %q (expected %q or %q)", s, LayoutHost, LayoutFlat)
+	}
+}
+
+// root возвращает корневую директорию для файлов хоста с учетом раскладки
+func (l Layout) root(u *url.URL, basePath string) string {
+	if l == LayoutFlat {
+		return basePath
+	}
+	return filepath.Join(basePath, u.Host)
+}
+
 // NormalizeURL нормализует URL, убирая фрагменты и параметры при необходимости
 func NormalizeURL(rawURL string, baseURL *url.URL) (*url.URL, error) {
 	u, err := url.Parse(rawURL)
@@ -36,7 +67,7 @@ func IsSameDomain(targetURL, baseURL *url.URL) bool {
 }
 
 // URLToLocalPath преобразует URL в локальный путь файла
-func URLToLocalPath(u *url.URL, basePath string) string {
+func URLToLocalPath(u *url.URL, basePath string, layout Layout) string {
 	// Создаем путь из домена и пути URL
 	path := u.Path
 	if path == "/" || path == "" {
@@ -57,7 +88,7 @@ func URLToLocalPath(u *url.URL, basePath string) string {
 	}
 
 	// Создаем полный путь
-	fullPath := filepath.Join(basePath, u.Host, path)
+	fullPath := filepath.Join(layout.root(u, basePath), path)
 
 	// Очищаем путь от недопустимых символов
 	fullPath = filepath.Clean(fullPath)
@@ -66,7 +97,7 @@ func URLToLocalPath(u *url.URL, basePath string) string {
 }
 
 // URLToResourcePath преобразует URL ресурса в локальный путь
-func URLToResourcePath(u *url.URL, basePath string) string {
+func URLToResourcePath(u *url.URL, basePath string, layout Layout) string {
 	path := strings.TrimPrefix(u.Path, "/")
 
 	// Если путь пустой, используем имя файла из пути
@@ -74,7 +105,7 @@ func URLToResourcePath(u *url.URL, basePath string) string {
 		path = "resource"
 	}
 
-	fullPath := filepath.Join(basePath, u.Host, path)
+	fullPath := filepath.Join(layout.root(u, basePath), path)
 	return filepath.Clean(fullPath)
 }
 